@@ -4,11 +4,31 @@ export interface FocusInfo {
   since_last_change: number;
   focus_time_left: number;
   num_focuses: number;
+  committed: boolean;
+  paused: boolean;
+  paused_for: number;
+  phase: "" | "focus" | "short_break" | "long_break";
+  cycle_index: number;
+  phase_time_left: number;
+  upcoming_meetings: Meeting[];
+}
+
+export interface Meeting {
+  title: string;
+  start: string;
+  end: string;
 }
 
 export interface FocusRecord {
   timestamp: string;
   duration: number;
+  label: string;
+  project: string;
+  task: string;
+  actual_duration: number;
+  end_reason: "" | "expired" | "stopped" | "replaced";
+  stopped_by: string;
+  committed: boolean;
 }
 
 export function connectWebSocket(onMessage: (data: FocusInfo) => void): WebSocket {
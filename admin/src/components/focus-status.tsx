@@ -27,6 +27,7 @@ export default function FocusStatus() {
     });
 
     timer = setInterval(() => {
+      if (focus()?.paused) return;
       setTimeLeft((t) => Math.max(0, t - 1));
     }, 1000);
   });
@@ -45,8 +46,8 @@ export default function FocusStatus() {
         <div class="status-grid">
           <div class="status-item">
             <span class="label">State</span>
-            <span class={`value ${timeLeft() > 0 ? "focusing" : "idle"}`}>
-              {timeLeft() > 0 ? "Focusing" : "Idle"}
+            <span class={`value ${timeLeft() > 0 && !focus()!.paused ? "focusing" : "idle"}`}>
+              {focus()!.paused ? "Paused" : timeLeft() > 0 ? "Focusing" : "Idle"}
             </span>
           </div>
           <div class="status-item">
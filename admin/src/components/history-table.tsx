@@ -37,7 +37,11 @@ export default function HistoryTable() {
                 {(record) => (
                   <tr>
                     <td>{formatTime(record.timestamp)}</td>
-                    <td>{formatDuration(record.duration)}</td>
+                    <td>
+                      {record.end_reason === "" || record.actual_duration === record.duration
+                        ? formatDuration(record.duration)
+                        : `${formatDuration(record.actual_duration)} of ${formatDuration(record.duration)}`}
+                    </td>
                   </tr>
                 )}
               </For>
@@ -40,10 +40,10 @@ func collections() []db.Collection {
 		{
 			Name: "coach",
 			Type: "base",
-			Fields: []db.Field{
+			Fields: append([]db.Field{
 				{Name: "timestamp", Type: "date", Required: true},
 				{Name: "duration", Type: "number", Required: true},
-			},
+			}, db.FocusRecordFields()...),
 			Indexes: []string{"CREATE UNIQUE INDEX `ts_index` ON `coach` (`timestamp`)"},
 		},
 		{
@@ -1,11 +1,16 @@
 package coach
 
 import (
+	"encoding/json"
+	"errors"
 	"net/http"
 	"net/http/httptest"
 	"strings"
 	"testing"
 	"time"
+
+	"coach/internal/db"
+	"coach/internal/dimaist"
 )
 
 func TestAgentLockDefaultEngaged(t *testing.T) {
@@ -113,7 +118,7 @@ func TestRestoreAgentLockFromFutureTime(t *testing.T) {
 	state := &State{}
 
 	until := time.Now().Add(2 * time.Second)
-	state.RestoreAgentLock(&until)
+	state.RestoreAgentLock(&until, nil)
 
 	if agentLocked(state) {
 		t.Error("Restored future release should leave the lock unlocked")
@@ -131,7 +136,7 @@ func TestRestoreAgentLockFromPastTimeIsNoOp(t *testing.T) {
 	state := &State{}
 
 	past := time.Now().Add(-1 * time.Second)
-	state.RestoreAgentLock(&past)
+	state.RestoreAgentLock(&past, nil)
 
 	if !agentLocked(state) {
 		t.Error("Restoring a past release should leave the lock engaged")
@@ -199,3 +204,369 @@ func TestAgentLockGetEndpoint(t *testing.T) {
 		t.Errorf("Locked GET should expose null time_left_seconds, got %s", rr.Body.String())
 	}
 }
+
+func TestScopedReleaseOpensOnlyItsTargets(t *testing.T) {
+	state := &State{}
+
+	state.ReleaseAgentLockFor(100*time.Millisecond, []string{"docs.python.org"})
+	info := state.GetAgentLockInfo()
+	if info.TimeLeftSeconds != nil {
+		t.Error("A scoped release should leave the full lock engaged")
+	}
+	if len(info.Targets) != 1 || info.Targets[0].Target != "docs.python.org" {
+		t.Fatalf("Expected docs.python.org released, got %+v", info.Targets)
+	}
+	if fi := state.GetCurrentFocusInfo(); len(fi.AgentReleaseTargets) != 1 {
+		t.Errorf("Expected the scope in the broadcast focus info, got %+v", fi.AgentReleaseTargets)
+	}
+
+	time.Sleep(200 * time.Millisecond)
+	if targets := state.GetAgentLockInfo().Targets; len(targets) != 0 {
+		t.Errorf("Expected the target release to expire, got %+v", targets)
+	}
+}
+
+func TestScopedReleasesExtendPerTarget(t *testing.T) {
+	state := &State{}
+
+	state.ReleaseAgentLockFor(5*time.Second, []string{"a.com"})
+	state.ReleaseAgentLockFor(time.Minute, []string{"a.com", "b.com"})
+	state.ReleaseAgentLockFor(time.Second, []string{"b.com"})
+
+	targets := state.GetAgentLockInfo().Targets
+	if len(targets) != 2 || targets[0].TimeLeftSeconds < 58 || targets[1].TimeLeftSeconds < 58 {
+		t.Errorf("Expected both targets released for a minute, got %+v", targets)
+	}
+
+	state.EngageAgentLock()
+	if targets := state.GetAgentLockInfo().Targets; len(targets) != 0 {
+		t.Errorf("Engage should cancel target releases too, got %+v", targets)
+	}
+}
+
+func TestRestoreAgentLockWithScope(t *testing.T) {
+	state := &State{}
+
+	now := time.Now()
+	state.RestoreAgentLock(nil, map[string]time.Time{
+		"docs.python.org": now.Add(time.Minute),
+		"reddit.com":      now.Add(-time.Minute),
+	})
+
+	targets := state.GetAgentLockInfo().Targets
+	if len(targets) != 1 || targets[0].Target != "docs.python.org" {
+		t.Errorf("Expected only the live target restored, got %+v", targets)
+	}
+	if !agentLocked(state) {
+		t.Error("Restoring a scope should leave the full lock engaged")
+	}
+}
+
+func TestAgentLockReleaseEndpointWithTargets(t *testing.T) {
+	server := &Server{State: &State{}}
+
+	body := "duration=600&targets=Docs.Python.org,https://pypi.org/project/x&targets=com.example.app"
+	req := httptest.NewRequest(http.MethodPost, "/agent-lock/release", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	rr := httptest.NewRecorder()
+	server.AgentLockHandler(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if !agentLocked(server.State) {
+		t.Error("A scoped release should not open everything")
+	}
+	want := `"targets":[{"target":"com.example.app","time_left_seconds":5`
+	if !strings.Contains(rr.Body.String(), want) || !strings.Contains(rr.Body.String(), `"target":"pypi.org"`) {
+		t.Errorf("Expected normalized targets in the response, got %s", rr.Body.String())
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/agent-lock/release", strings.NewReader("duration=600&targets=docs.python.org/3"))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	rr = httptest.NewRecorder()
+	server.AgentLockHandler(rr, req)
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("A path in a target should be 400, got %d", rr.Code)
+	}
+}
+
+func TestFocusHoldsAgentLockUntilFocusEnds(t *testing.T) {
+	state := &State{}
+	state.SetLockDuringFocus(true)
+	engaged := make(chan LockEngaged, 1)
+	released := make(chan LockReleased, 1)
+	On(state.Events(), func(e LockEngaged) { engaged <- e })
+
+	state.ReleaseAgentLock(5 * time.Second)
+	On(state.Events(), func(e LockReleased) { released <- e })
+
+	state.SetFocusing(100 * time.Millisecond)
+	if !agentLocked(state) || !state.GetAgentLockInfo().HeldByFocus {
+		t.Fatal("Focus should hold the agent lock over the release")
+	}
+	select {
+	case e := <-engaged:
+		if e.Reason != "focus" {
+			t.Errorf("Expected reason focus, got %q", e.Reason)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected LockEngaged when focus took the lock")
+	}
+
+	// A release granted meanwhile waits for focus to end
+	state.ReleaseAgentLock(10 * time.Second)
+	if !agentLocked(state) {
+		t.Error("A release during focus shouldn't open the lock")
+	}
+
+	select {
+	case e := <-released:
+		if e.Reason != "focus_ended" || time.Until(e.Until) < 9*time.Second {
+			t.Errorf("Expected the 10s release back after focus, got %+v", e)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected LockReleased when focus ended")
+	}
+	if agentLocked(state) || state.GetAgentLockInfo().HeldByFocus {
+		t.Error("After focus the lock should follow its release window")
+	}
+}
+
+func TestFocusHoldStaysLockedWithoutRelease(t *testing.T) {
+	state := &State{}
+	state.SetLockDuringFocus(true)
+	state.SetFocusing(50 * time.Millisecond)
+	time.Sleep(150 * time.Millisecond)
+	if !agentLocked(state) || state.GetAgentLockInfo().HeldByFocus {
+		t.Error("With nothing released the lock stays engaged once focus ends")
+	}
+}
+
+func TestAgentLockReleaseDuringFocusNeedsOverride(t *testing.T) {
+	server := &Server{State: &State{}}
+	server.State.SetLockDuringFocus(true)
+	server.State.SetFocusing(time.Minute)
+
+	if rr := postRelease(server, "duration=60"); rr.Code != http.StatusConflict {
+		t.Fatalf("Expected 409 for a grant during focus, got %d", rr.Code)
+	}
+	if !agentLocked(server.State) {
+		t.Fatal("A refused grant must not open the lock")
+	}
+
+	if rr := postRelease(server, "duration=60&is_override=true&user_message=urgent"); rr.Code != http.StatusOK {
+		t.Fatalf("Expected 200 for an override, got %d", rr.Code)
+	}
+	if agentLocked(server.State) || server.State.FocusHoldsAgentLock() {
+		t.Error("An override should open the lock despite focus")
+	}
+
+	// The override lasts for the session; later grants go through
+	if rr := postRelease(server, "duration=120"); rr.Code != http.StatusOK {
+		t.Errorf("Expected 200 for a grant after the override, got %d", rr.Code)
+	}
+}
+
+func TestGrantCooldownAfterReleaseEnds(t *testing.T) {
+	server := &Server{State: &State{}}
+	server.State.SetAgentGrantCooldown(time.Minute)
+
+	// No release has ended yet: nothing to cool down from
+	if rr := postRelease(server, "duration=60"); rr.Code != http.StatusOK {
+		t.Fatalf("Expected the first grant to pass, got %d", rr.Code)
+	}
+	// Extending a running release isn't held back either
+	if rr := postRelease(server, "duration=120"); rr.Code != http.StatusOK {
+		t.Fatalf("Expected the extension to pass, got %d", rr.Code)
+	}
+
+	server.State.EngageAgentLock()
+	rr := postRelease(server, "duration=60")
+	if rr.Code != http.StatusTooManyRequests {
+		t.Fatalf("Expected 429 within the cooldown, got %d", rr.Code)
+	}
+	var refusal GrantRefusal
+	if err := json.Unmarshal(rr.Body.Bytes(), &refusal); err != nil {
+		t.Fatalf("Expected a JSON refusal: %v", err)
+	}
+	if refusal.CooldownSeconds < 59 || refusal.CooldownSeconds > 60 {
+		t.Errorf("Expected ~60s of cooldown left, got %d", refusal.CooldownSeconds)
+	}
+	if rr.Header().Get("Retry-After") == "" {
+		t.Error("Expected a Retry-After header")
+	}
+	if !agentLocked(server.State) {
+		t.Error("A refused grant must not open the lock")
+	}
+	if info := server.State.GetAgentLockInfo(); info.CooldownSeconds != refusal.CooldownSeconds {
+		t.Errorf("Expected GET /agent-lock to show the cooldown, got %d", info.CooldownSeconds)
+	}
+	if fi := server.State.GetCurrentFocusInfo(); fi.AgentCooldownLeft <= 0 {
+		t.Error("Expected FocusInfo to show the cooldown")
+	}
+
+	if rr := postRelease(server, "duration=60&is_override=true&user_message=deploy"); rr.Code != http.StatusOK {
+		t.Errorf("Overrides should bypass the cooldown, got %d", rr.Code)
+	}
+}
+
+func TestGrantCooldownRunsOut(t *testing.T) {
+	server := &Server{State: &State{}}
+	server.State.SetAgentGrantCooldown(100 * time.Millisecond)
+	server.State.ReleaseAgentLock(50 * time.Millisecond)
+
+	time.Sleep(100 * time.Millisecond)
+	if rr := postRelease(server, "duration=60"); rr.Code != http.StatusTooManyRequests {
+		t.Fatalf("Expected 429 right after the release expired, got %d", rr.Code)
+	}
+	time.Sleep(150 * time.Millisecond)
+	if rr := postRelease(server, "duration=60"); rr.Code != http.StatusOK {
+		t.Errorf("Expected the grant to pass after the cooldown, got %d", rr.Code)
+	}
+}
+
+func TestGrantCooldownOnlyAfterAGrantsRelease(t *testing.T) {
+	state := &State{}
+	state.SetAgentGrantCooldown(time.Minute)
+
+	// A lock policy window ending is the user's own schedule, not a grant
+	state.releaseAgentLockUntil(time.Now().Add(time.Hour), nil, db.ReleaseGrant{ID: "window", Scheduled: true})
+	state.engageAgentLock("schedule")
+	if left := state.AgentGrantCooldownLeft(); left != 0 {
+		t.Errorf("Expected no cooldown after a policy window, got %v", left)
+	}
+
+	// Nor does a grant that focus held back the whole time
+	state.SetLockDuringFocus(true)
+	state.SetFocusing(time.Minute)
+	state.ReleaseAgentLockGrant(time.Hour, nil, "grant1")
+	state.EngageAgentLock()
+	if left := state.AgentGrantCooldownLeft(); left != 0 {
+		t.Errorf("Expected no cooldown after a release focus held back, got %v", left)
+	}
+
+	state.SetLockDuringFocus(false)
+	state.ReleaseAgentLockGrant(time.Hour, nil, "grant2")
+	state.EngageAgentLock()
+	if left := state.AgentGrantCooldownLeft(); left <= 0 {
+		t.Error("Expected the cooldown once a grant's release ended")
+	}
+}
+
+func TestReleaseEndLinksToTheGrantBehindIt(t *testing.T) {
+	state := &State{}
+	state.ReleaseAgentLockGrant(time.Hour, nil, "grant1")
+
+	// Pretend the release opened 90s ago
+	state.mu.Lock()
+	g := state.agentReleaseGrants[db.FullReleaseKey]
+	g.Since = g.Since.Add(-90 * time.Second)
+	state.agentReleaseGrants[db.FullReleaseKey] = g
+	state.mu.Unlock()
+
+	// An extension becomes the grant in effect, the first one running up to
+	// it; a shorter grant changes nothing
+	state.ReleaseAgentLockGrant(2*time.Hour, nil, "grant2")
+	state.ReleaseAgentLockGrant(time.Minute, nil, "grant3")
+	if secs := state.RunningReleaseSeconds(); secs < 90 || secs > 91 {
+		t.Errorf("Expected ~90s released so far, got %d", secs)
+	}
+
+	state.mu.Lock()
+	entries := state.releaseEndsLocked(map[string]time.Time{db.FullReleaseKey: *state.agentReleaseUntil}, "engage", "agent", time.Now())
+	state.mu.Unlock()
+	if len(entries) != 2 {
+		t.Fatalf("Expected an end entry per grant, got %+v", entries)
+	}
+	if e := entries[0]; e.Kind != "engage" || e.GrantID != "grant1" || e.ActualSeconds < 90 || e.ActualSeconds > 91 {
+		t.Errorf("Unexpected end entry for the superseded grant %+v", e)
+	}
+	if e := entries[1]; e.Kind != "engage" || e.GrantID != "grant2" || e.ActualSeconds != 0 {
+		t.Errorf("Unexpected end entry for the extension %+v", e)
+	}
+}
+
+func TestScopedReleasesSharingAGrantEndOnce(t *testing.T) {
+	state := &State{}
+	state.ReleaseAgentLockGrant(time.Minute, []string{"a.com", "b.com"}, "grant1")
+	state.ReleaseAgentLockGrant(time.Minute, []string{"c.com"}, "grant2")
+
+	now := time.Now()
+	state.mu.Lock()
+	// c.com was due 30s before now: it only ran until then
+	state.agentReleaseGrants["c.com"] = db.ReleaseGrant{ID: "grant2", Since: now.Add(-time.Minute)}
+	entries := state.releaseEndsLocked(map[string]time.Time{
+		"a.com": now.Add(time.Minute),
+		"b.com": now.Add(time.Minute),
+		"c.com": now.Add(-30 * time.Second),
+	}, "expired", "timer", now)
+	state.mu.Unlock()
+
+	byGrant := map[string]db.LockDecision{}
+	for _, e := range entries {
+		byGrant[e.GrantID] = e
+	}
+	if len(entries) != 2 || len(byGrant) != 2 {
+		t.Fatalf("Expected one entry per grant, got %+v", entries)
+	}
+	if e := byGrant["grant2"]; e.ActualSeconds != 30 {
+		t.Errorf("Expected grant2 to have run 30s, got %d", e.ActualSeconds)
+	}
+	if state.RunningReleaseSeconds() != 0 {
+		t.Error("Ended releases should leave no grants behind")
+	}
+}
+
+func TestRunningReleaseSecondsCountsOnlyToday(t *testing.T) {
+	state := &State{}
+	state.ReleaseAgentLockGrant(time.Hour, nil, "grant1")
+
+	// Pretend the release opened two days ago
+	state.mu.Lock()
+	g := state.agentReleaseGrants[db.FullReleaseKey]
+	g.Since = g.Since.AddDate(0, 0, -2)
+	state.agentReleaseGrants[db.FullReleaseKey] = g
+	state.mu.Unlock()
+
+	now := time.Now()
+	today := int(now.Sub(time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())) / time.Second)
+	if secs := state.RunningReleaseSeconds(); secs < today-1 || secs > today+1 {
+		t.Errorf("Expected only today's %ds counted, got %d", today, secs)
+	}
+}
+
+func TestLockStateCountsRunningRelease(t *testing.T) {
+	server := &Server{State: &State{}}
+	server.State.ReleaseAgentLockGrant(time.Hour, nil, "grant1")
+	server.State.mu.Lock()
+	server.State.agentReleaseGrants[db.FullReleaseKey] = db.ReleaseGrant{ID: "grant1", Since: time.Now().Add(-2 * time.Minute)}
+	server.State.mu.Unlock()
+
+	req := httptest.NewRequest(http.MethodGet, "/agent-lock/state", nil)
+	rr := httptest.NewRecorder()
+	server.AgentLockHandler(rr, req)
+	if !strings.Contains(rr.Body.String(), `"released_seconds_today":120`) {
+		t.Errorf("Expected the running release counted, got %s", rr.Body.String())
+	}
+}
+
+func TestLockStateIncludesTodaysTasks(t *testing.T) {
+	server := &Server{State: &State{}, Tasks: &fakeTaskSource{tasks: []dimaist.Task{{ID: 7, Title: "Ship it"}}}}
+
+	req := httptest.NewRequest(http.MethodGet, "/agent-lock/state", nil)
+	rr := httptest.NewRecorder()
+	server.AgentLockHandler(rr, req)
+	if !strings.Contains(rr.Body.String(), `"title":"Ship it"`) {
+		t.Errorf("Expected today's tasks in the state, got %s", rr.Body.String())
+	}
+
+	for _, src := range []taskSource{nil, &fakeTaskSource{err: errors.New("dimaist down")}} {
+		server.Tasks = src
+		rr := httptest.NewRecorder()
+		server.AgentLockHandler(rr, httptest.NewRequest(http.MethodGet, "/agent-lock/state", nil))
+		if rr.Code != http.StatusOK || !strings.Contains(rr.Body.String(), `"tasks":[]`) {
+			t.Errorf("Expected an empty task list when tasks can't be read, got %d %s", rr.Code, rr.Body.String())
+		}
+	}
+}
@@ -0,0 +1,113 @@
+// Package ai is a small client for OpenAI-compatible chat-completion APIs.
+package ai
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// Client sends chat completions to an OpenAI-compatible server.
+type Client struct {
+	BaseURL string // API root including the version, e.g. "https://api.openai.com/v1"
+	APIKey  string
+	HTTP    *http.Client
+}
+
+func NewClient(baseURL, apiKey string) *Client {
+	return &Client{
+		BaseURL: strings.TrimSuffix(baseURL, "/"),
+		APIKey:  apiKey,
+		HTTP:    &http.Client{Timeout: 60 * time.Second},
+	}
+}
+
+// NewClientFromEnv builds a client from AI_URL and AI_API_KEY. Returns nil if
+// AI_URL is unset, so callers can treat AI as optional.
+func NewClientFromEnv() *Client {
+	baseURL := os.Getenv("AI_URL")
+	if baseURL == "" {
+		return nil
+	}
+	return NewClient(baseURL, os.Getenv("AI_API_KEY"))
+}
+
+type message struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+type completionRequest struct {
+	Model    string    `json:"model"`
+	Messages []message `json:"messages"`
+}
+
+type completionResponse struct {
+	Choices []struct {
+		Message message `json:"message"`
+	} `json:"choices"`
+}
+
+type errorResponse struct {
+	Error struct {
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// Complete sends a system prompt and one user message to model and returns
+// the assistant's reply.
+func (c *Client) Complete(ctx context.Context, model, systemPrompt, userMessage string) (string, error) {
+	body, err := json.Marshal(completionRequest{
+		Model: model,
+		Messages: []message{
+			{Role: "system", Content: systemPrompt},
+			{Role: "user", Content: userMessage},
+		},
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", c.BaseURL+"/chat/completions", bytes.NewReader(body))
+	if err != nil {
+		return "", fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if c.APIKey != "" {
+		req.Header.Set("Authorization", "Bearer "+c.APIKey)
+	}
+
+	resp, err := c.HTTP.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", fmt.Errorf("failed to read response body: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		var errResp errorResponse
+		if err := json.Unmarshal(respBody, &errResp); err == nil && errResp.Error.Message != "" {
+			return "", fmt.Errorf("completion failed with status %d: %s", resp.StatusCode, errResp.Error.Message)
+		}
+		return "", fmt.Errorf("completion failed with status %d: %s", resp.StatusCode, string(respBody))
+	}
+
+	var result completionResponse
+	if err := json.Unmarshal(respBody, &result); err != nil {
+		return "", fmt.Errorf("failed to parse response: %w", err)
+	}
+	if len(result.Choices) == 0 {
+		return "", fmt.Errorf("completion returned no choices")
+	}
+	return strings.TrimSpace(result.Choices[0].Message.Content), nil
+}
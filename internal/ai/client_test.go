@@ -0,0 +1,46 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestCompleteSpeaksChatCompletions(t *testing.T) {
+	var got completionRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/chat/completions" || r.Header.Get("Authorization") != "Bearer key" {
+			t.Errorf("Unexpected request %s with auth %q", r.URL.Path, r.Header.Get("Authorization"))
+		}
+		json.NewDecoder(r.Body).Decode(&got)
+		w.Write([]byte(`{"choices":[{"message":{"role":"assistant","content":" Start a session. "}}]}`))
+	}))
+	defer server.Close()
+
+	reply, err := NewClient(server.URL+"/v1/", "key").Complete(context.Background(), "m", "be brief", "hi")
+	if err != nil {
+		t.Fatalf("Complete: %v", err)
+	}
+	if reply != "Start a session." {
+		t.Errorf("Expected trimmed reply, got %q", reply)
+	}
+	if got.Model != "m" || len(got.Messages) != 2 || got.Messages[0].Role != "system" || got.Messages[1].Content != "hi" {
+		t.Errorf("Unexpected request body: %+v", got)
+	}
+}
+
+func TestCompleteReportsAPIError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte(`{"error":{"message":"bad key"}}`))
+	}))
+	defer server.Close()
+
+	_, err := NewClient(server.URL, "").Complete(context.Background(), "m", "", "hi")
+	if err == nil || !strings.Contains(err.Error(), "bad key") {
+		t.Errorf("Expected the API's error message, got %v", err)
+	}
+}
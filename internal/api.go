@@ -2,10 +2,15 @@ package coach
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
+	"net/url"
 	"strconv"
+	"strings"
 	"time"
 
+	"coach/internal/db"
 	"coach/internal/stats"
 
 	"github.com/charmbracelet/log"
@@ -37,15 +42,29 @@ func (s *Server) HealthHandler(w http.ResponseWriter, r *http.Request) {
 }
 
 // @Summary Get or set focus state
-// @Description Get the current focus state or set a new focus state with duration
+// @Description Get the current focus state or set a new focus state with duration.
+// @Description action=pause freezes the remaining time; action=resume continues it.
+// @Description Stopping, replacing or pausing a committed session needs is_override=true
+// @Description with a user_message.
 // @Tags focus
 // @Accept x-www-form-urlencoded
 // @Produce json
+// @Param action formData string false "pause or resume the current session instead of setting focus"
 // @Param focusing formData bool false "Set focus state to true/false"
 // @Param duration formData int false "Duration in seconds for focus period (default 30)"
+// @Param label formData string false "What the session is for"
+// @Param project formData string false "Project the session counts towards"
+// @Param task formData string false "Task the session works on"
+// @Param replace formData bool false "Drop queued focus periods instead of appending to them"
+// @Param source formData string false "Who is changing focus; recorded when a session is stopped (default http)"
+// @Param commit formData bool false "Start a committed session that can only be cancelled with an override"
+// @Param is_override formData bool false "Cancel a committed session anyway; journaled as a broken commitment"
+// @Param user_message formData string false "Why the commitment is being broken (required with is_override)"
+// @Param agent_message formData string false "The coach's reply, if any"
 // @Success 200 {object} map[string]interface{} "Returns focus state"
 // @Failure 400 {string} string "Bad request"
 // @Failure 405 {string} string "Method not allowed"
+// @Failure 409 {string} string "Session is committed"
 // @Failure 500 {string} string "Internal server error"
 // @Router /focusing [get]
 // @Router /focusing [post]
@@ -69,38 +88,925 @@ func (s *Server) FocusHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	by := r.FormValue("source")
+	if by == "" {
+		by = "http"
+	}
+
+	switch action := r.FormValue("action"); action {
+	case "":
+	case "pause":
+		// Pausing freezes a committed session as surely as stopping it
+		override, ok := s.allowFocusCancel(w, r, by)
+		if !ok {
+			return
+		}
+		if s.State.PauseFocus() {
+			s.journalFocusOverride(override)
+		}
+		writeJSON(w, s.State.GetCurrentFocusInfo())
+		return
+	case "resume":
+		s.State.ResumeFocus()
+		writeJSON(w, s.State.GetCurrentFocusInfo())
+		return
+	default:
+		http.Error(w, "action must be pause or resume", http.StatusBadRequest)
+		return
+	}
+
 	focusing := r.FormValue("focusing") == "true"
 	log.Info("Focus change requested", "focusing", focusing)
 
-	// Get duration parameter with default of 30 seconds
-	duration := r.FormValue("duration")
-	if duration == "" {
-		duration = "30"
+	// Get duration parameter with default of 30 seconds
+	duration := r.FormValue("duration")
+	if duration == "" {
+		duration = "30"
+	}
+
+	durationInt, err := strconv.Atoi(duration)
+	if err != nil {
+		http.Error(w, "Failed to parse duration", http.StatusBadRequest)
+		return
+	}
+	log.Info("Focus parameters", "duration", durationInt)
+
+	label := FocusLabel{
+		Label:   r.FormValue("label"),
+		Project: r.FormValue("project"),
+		Task:    r.FormValue("task"),
+	}
+	commit := r.FormValue("commit") == "true"
+	replace := focusing && r.FormValue("replace") == "true"
+	var override *focusOverride
+	if !focusing || replace {
+		var ok bool
+		if override, ok = s.allowFocusCancel(w, r, by); !ok {
+			return
+		}
+	}
+	switch {
+	case replace:
+		s.State.ReplaceFocusing(time.Duration(durationInt)*time.Second, label, commit, by)
+		go s.State.NotifyAllClients(s.State.GetCurrentFocusInfo())
+	case focusing && commit:
+		s.State.SetCommittedFocusing(time.Duration(durationInt)*time.Second, label)
+		go s.State.NotifyAllClients(s.State.GetCurrentFocusInfo())
+	default:
+		s.State.HandleFocusChange(focusing, durationInt, label, by)
+	}
+	s.journalFocusOverride(override)
+	writeJSON(w, s.State.GetCurrentFocusInfo())
+}
+
+// focusOverride is a granted request to break a committed session, waiting to
+// be journaled once the session has actually been cancelled.
+type focusOverride struct {
+	by, userMessage, agentMessage string
+	left                          int
+}
+
+// allowFocusCancel guards stopping, replacing or pausing a committed session.
+// Without is_override=true it answers 409; an override must say why in
+// user_message. It returns the override to journal (nil if the session isn't
+// committed), or false once it has written an error.
+func (s *Server) allowFocusCancel(w http.ResponseWriter, r *http.Request, by string) (*focusOverride, bool) {
+	if !s.State.IsCommitted() {
+		return nil, true
+	}
+	if r.FormValue("is_override") != "true" {
+		http.Error(w, "Focus session is committed; cancelling it needs is_override=true", http.StatusConflict)
+		return nil, false
+	}
+	userMessage := r.FormValue("user_message")
+	if strings.TrimSpace(userMessage) == "" {
+		http.Error(w, "user_message is required to break a commitment", http.StatusBadRequest)
+		return nil, false
+	}
+	return &focusOverride{
+		by: by, userMessage: userMessage, agentMessage: r.FormValue("agent_message"),
+		left: int(s.State.GetCurrentFocusInfo().FocusTimeLeft),
+	}, true
+}
+
+// journalFocusOverride records o as a broken commitment. Call it only once the
+// committed session has been cancelled; a nil o is a no-op.
+func (s *Server) journalFocusOverride(o *focusOverride) {
+	if o == nil {
+		return
+	}
+	log.Info("Committed focus overridden", "by", o.by, "left", o.left)
+	s.logLockDecision("broken_commitment", o.by, o.userMessage, o.agentMessage, o.left)
+}
+
+// @Summary Start or stop a pomodoro cycle
+// @Description POST /focus-cycle replaces any current focus with a cycle of focus
+// @Description phases separated by short and long breaks. POST /focus-cycle/stop ends it.
+// @Description Both return the focus state, whose phase and cycle_index track the cycle.
+// @Tags focus
+// @Accept x-www-form-urlencoded
+// @Produce json
+// @Param focus formData int false "Focus phase length in seconds (default 1500)"
+// @Param short_break formData int false "Short break length in seconds (default 300)"
+// @Param long_break formData int false "Long break length in seconds (default 900)"
+// @Param long_every formData int false "A long break follows every Nth focus phase (default 4)"
+// @Param cycles formData int false "Number of focus phases (default 4)"
+// @Param label formData string false "What the focus phases are for"
+// @Param project formData string false "Project the focus phases count towards"
+// @Param task formData string false "Task the focus phases work on"
+// @Param source formData string false "Who is starting or stopping the cycle (default http)"
+// @Param is_override formData bool false "Replace or stop a committed session anyway"
+// @Param user_message formData string false "Why the commitment is being broken (required with is_override)"
+// @Success 200 {object} FocusInfo
+// @Failure 400 {string} string "Bad request"
+// @Failure 405 {string} string "Method not allowed"
+// @Failure 409 {string} string "Session is committed"
+// @Router /focus-cycle [post]
+// @Router /focus-cycle/stop [post]
+func (s *Server) FocusCycleHandler(w http.ResponseWriter, r *http.Request) {
+	log.Info("Called /focus-cycle", "method", r.Method, "path", r.URL.Path)
+
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Failed to parse form", http.StatusBadRequest)
+		return
+	}
+	by := r.FormValue("source")
+	if by == "" {
+		by = "http"
+	}
+
+	switch r.URL.Path {
+	case "/focus-cycle":
+		config := DefaultCycleConfig()
+		for _, p := range []struct {
+			name string
+			dst  *time.Duration
+		}{
+			{"focus", &config.Focus},
+			{"short_break", &config.ShortBreak},
+			{"long_break", &config.LongBreak},
+		} {
+			if v := r.FormValue(p.name); v != "" {
+				secs, err := strconv.Atoi(v)
+				if err != nil {
+					http.Error(w, p.name+" must be an integer (seconds)", http.StatusBadRequest)
+					return
+				}
+				*p.dst = time.Duration(secs) * time.Second
+			}
+		}
+		for _, p := range []struct {
+			name string
+			dst  *int
+		}{
+			{"long_every", &config.LongEvery},
+			{"cycles", &config.Cycles},
+		} {
+			if v := r.FormValue(p.name); v != "" {
+				n, err := strconv.Atoi(v)
+				if err != nil {
+					http.Error(w, p.name+" must be an integer", http.StatusBadRequest)
+					return
+				}
+				*p.dst = n
+			}
+		}
+
+		label := FocusLabel{
+			Label:   r.FormValue("label"),
+			Project: r.FormValue("project"),
+			Task:    r.FormValue("task"),
+		}
+		if err := config.Validate(); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		override, ok := s.allowFocusCancel(w, r, by)
+		if !ok {
+			return
+		}
+		if err := s.State.StartCycle(config, label, by); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		s.journalFocusOverride(override)
+
+	case "/focus-cycle/stop":
+		override, ok := s.allowFocusCancel(w, r, by)
+		if !ok {
+			return
+		}
+		if s.State.StopCycle(by) {
+			s.journalFocusOverride(override)
+		}
+
+	default:
+		http.NotFound(w, r)
+		return
+	}
+
+	writeJSON(w, s.State.GetCurrentFocusInfo())
+}
+
+// @Summary List, book or cancel scheduled focus blocks
+// @Description GET /focus-schedule lists pending blocks. POST /focus-schedule books one:
+// @Description start and end are RFC3339 timestamps or HH:MM today (duration in seconds
+// @Description may replace end). POST /focus-schedule/cancel with id drops a pending block.
+// @Description A block starts at its time with the usual events and broadcasts.
+// @Tags focus
+// @Accept x-www-form-urlencoded
+// @Produce json
+// @Param start formData string false "When the block starts (RFC3339 or HH:MM)"
+// @Param end formData string false "When the block ends (RFC3339 or HH:MM)"
+// @Param duration formData int false "Block length in seconds, instead of end"
+// @Param label formData string false "What the block is for"
+// @Param project formData string false "Project the block counts towards"
+// @Param task formData string false "Task the block works on"
+// @Param id formData string false "Block to cancel"
+// @Success 200 {array} FocusBlock
+// @Failure 400 {string} string "Bad request"
+// @Failure 404 {string} string "No pending block with that id"
+// @Failure 405 {string} string "Method not allowed"
+// @Failure 500 {string} string "Internal server error"
+// @Router /focus-schedule [get]
+// @Router /focus-schedule [post]
+// @Router /focus-schedule/cancel [post]
+func (s *Server) FocusScheduleHandler(w http.ResponseWriter, r *http.Request) {
+	log.Info("Called /focus-schedule", "method", r.Method, "path", r.URL.Path)
+
+	switch r.URL.Path {
+	case "/focus-schedule":
+		if r.Method == http.MethodGet {
+			writeJSON(w, s.FocusScheduler.List())
+			return
+		}
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		if err := r.ParseForm(); err != nil {
+			http.Error(w, "Failed to parse form", http.StatusBadRequest)
+			return
+		}
+
+		now := time.Now()
+		start, err := parseScheduleTime(r.FormValue("start"), now)
+		if err != nil {
+			http.Error(w, "start must be RFC3339 or HH:MM", http.StatusBadRequest)
+			return
+		}
+		var end time.Time
+		if v := r.FormValue("duration"); v != "" {
+			secs, err := strconv.Atoi(v)
+			if err != nil || secs <= 0 {
+				http.Error(w, "duration must be a positive integer (seconds)", http.StatusBadRequest)
+				return
+			}
+			end = start.Add(time.Duration(secs) * time.Second)
+		} else if end, err = parseScheduleTime(r.FormValue("end"), now); err != nil {
+			http.Error(w, "end must be RFC3339 or HH:MM", http.StatusBadRequest)
+			return
+		}
+
+		label := FocusLabel{
+			Label:   r.FormValue("label"),
+			Project: r.FormValue("project"),
+			Task:    r.FormValue("task"),
+		}
+		block, err := s.FocusScheduler.Book(start, end, label)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		writeJSON(w, block)
+
+	case "/focus-schedule/cancel":
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		if err := r.ParseForm(); err != nil {
+			http.Error(w, "Failed to parse form", http.StatusBadRequest)
+			return
+		}
+		found, err := s.FocusScheduler.Cancel(r.FormValue("id"))
+		if !found {
+			http.Error(w, "No pending block with that id", http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			log.Error("Failed to persist focus block cancellation", "err", err)
+			http.Error(w, "Failed to cancel focus block", http.StatusInternalServerError)
+			return
+		}
+		writeJSON(w, s.FocusScheduler.List())
+
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// parseScheduleTime reads an RFC3339 timestamp, or a bare HH:MM meaning that
+// time today in the server's zone.
+func parseScheduleTime(v string, now time.Time) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, v); err == nil {
+		return t, nil
+	}
+	clock, err := time.ParseInLocation("15:04", v, now.Location())
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.Date(now.Year(), now.Month(), now.Day(), clock.Hour(), clock.Minute(), 0, 0, now.Location()), nil
+}
+
+// @Summary List, configure or trigger hooks
+// @Description GET /hooks lists registered hooks with their params, config and next run.
+// @Description POST /hooks with a JSON config updates that hook and reschedules it at once.
+// @Description POST /hooks/trigger with {"hook_id": ...} runs a hook now, skipping the
+// @Description "a client is connected" and "not focusing" checks scheduled runs make.
+// @Tags hooks
+// @Accept json
+// @Produce json
+// @Success 200 {array} HookInfo
+// @Failure 400 {string} string "Bad request"
+// @Failure 404 {string} string "No hook with that id"
+// @Failure 405 {string} string "Method not allowed"
+// @Failure 500 {string} string "Internal server error"
+// @Router /hooks [get]
+// @Router /hooks [post]
+// @Router /hooks/trigger [post]
+func (s *Server) HooksHandler(w http.ResponseWriter, r *http.Request) {
+	log.Info("Called /hooks", "method", r.Method, "path", r.URL.Path)
+
+	switch r.URL.Path {
+	case "/hooks":
+		if r.Method == http.MethodGet {
+			writeJSON(w, s.Hooks.Hooks())
+			return
+		}
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		var config HookConfig
+		if err := json.NewDecoder(r.Body).Decode(&config); err != nil {
+			http.Error(w, "Invalid JSON body", http.StatusBadRequest)
+			return
+		}
+		if !s.Hooks.Has(config.HookID) {
+			http.Error(w, "No hook with that id", http.StatusNotFound)
+			return
+		}
+		if config.Trigger == "" {
+			config.Trigger = "scheduled"
+		}
+		if err := config.Validate(); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if _, err := s.Hooks.UpdateConfig(config); err != nil {
+			log.Error("Failed to save hook config", "err", err)
+			http.Error(w, "Failed to save hook config", http.StatusInternalServerError)
+			return
+		}
+		writeJSON(w, s.Hooks.Hooks())
+
+	case "/hooks/trigger":
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		var body struct {
+			HookID string `json:"hook_id"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			http.Error(w, "Invalid JSON body", http.StatusBadRequest)
+			return
+		}
+		if !s.Hooks.Has(body.HookID) {
+			http.Error(w, "No hook with that id", http.StatusNotFound)
+			return
+		}
+		if err := s.Hooks.RunHook(body.HookID); err != nil {
+			log.Error("Hook run failed", "hook", body.HookID, "err", err)
+			http.Error(w, "Hook run failed: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		writeJSON(w, map[string]bool{"ok": true})
+
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// @Summary List hook results and mark them seen
+// @Description GET /hook-results lists the latest results, newest first. With unseen=true
+// @Description and client=ID it lists the newest limit results that client hasn't marked
+// @Description seen, however far back they go, so each device gets every result once. POST /hook-results/seen with
+// @Description {"client": ..., "ids": [...]} marks results seen for that client.
+// @Tags hooks
+// @Accept json
+// @Produce json
+// @Param unseen query bool false "Only results the client hasn't seen"
+// @Param client query string false "Client ID, required with unseen"
+// @Param limit query int false "Number of results to return (default 20, max 100)"
+// @Success 200 {array} db.HookResult
+// @Failure 400 {string} string "Bad request"
+// @Failure 405 {string} string "Method not allowed"
+// @Failure 500 {string} string "Internal server error"
+// @Router /hook-results [get]
+// @Router /hook-results/seen [post]
+func (s *Server) HookResultsHandler(w http.ResponseWriter, r *http.Request) {
+	log.Info("Called /hook-results", "method", r.Method, "path", r.URL.Path)
+
+	switch r.URL.Path {
+	case "/hook-results":
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		q := r.URL.Query()
+		unseen := q.Get("unseen") == "true"
+		client := q.Get("client")
+		if unseen && client == "" {
+			http.Error(w, "client is required with unseen=true", http.StatusBadRequest)
+			return
+		}
+		limit := 20
+		if v := q.Get("limit"); v != "" {
+			var err error
+			limit, err = strconv.Atoi(v)
+			if err != nil || limit < 1 || limit > 100 {
+				http.Error(w, "limit must be between 1 and 100", http.StatusBadRequest)
+				return
+			}
+		}
+		if s.DBManager == nil {
+			writeJSON(w, []db.HookResult{})
+			return
+		}
+
+		var results []db.HookResult
+		var err error
+		if unseen {
+			results, err = unseenHookResults(s.DBManager, client, limit)
+		} else {
+			results, err = s.DBManager.GetRecentHookResults(limit)
+		}
+		if err != nil {
+			log.Error("Failed to read hook results", "err", err)
+			http.Error(w, "Failed to read hook results", http.StatusInternalServerError)
+			return
+		}
+		writeJSON(w, results)
+
+	case "/hook-results/seen":
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		var body struct {
+			Client string   `json:"client"`
+			IDs    []string `json:"ids"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			http.Error(w, "Invalid JSON body", http.StatusBadRequest)
+			return
+		}
+		if body.Client == "" || len(body.IDs) == 0 {
+			http.Error(w, "client and ids are required", http.StatusBadRequest)
+			return
+		}
+		if s.DBManager == nil {
+			writeJSON(w, map[string]bool{"ok": true})
+			return
+		}
+
+		// Marking twice is a no-op; the unique index would reject the second row.
+		seen, err := s.DBManager.GetSeenHookResults(body.Client, body.IDs)
+		if err != nil {
+			log.Error("Failed to read seen hook results", "err", err)
+			http.Error(w, "Failed to mark hook results seen", http.StatusInternalServerError)
+			return
+		}
+		for _, id := range body.IDs {
+			if seen[id] {
+				continue
+			}
+			if err := s.DBManager.MarkHookResultSeen(id, body.Client); err != nil {
+				log.Error("Failed to mark hook result seen", "id", id, "client", body.Client, "err", err)
+				http.Error(w, "Failed to mark hook results seen", http.StatusInternalServerError)
+				return
+			}
+			seen[id] = true
+		}
+		writeJSON(w, map[string]bool{"ok": true})
+
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// @Summary Manage outbound webhooks and read their delivery log
+// @Description GET /webhooks lists subscriptions (secrets withheld). POST /webhooks with a
+// @Description JSON webhook creates it, or replaces the one with the same id; an empty
+// @Description secret keeps the stored one. POST /webhooks/delete with {"id": ...} removes
+// @Description one. GET /webhooks/deliveries lists recent attempts, newest first.
+// @Description Events are POSTed as {"event", "data"} with X-Coach-Event and, when a secret
+// @Description is set, X-Coach-Signature: sha256=<hex HMAC-SHA256 of the body>.
+// @Tags webhooks
+// @Accept json
+// @Produce json
+// @Param status query string false "Only deliveries with this status: pending, delivered or failed"
+// @Param limit query int false "Number of deliveries to list (default 50, max 200)"
+// @Success 200 {array} Webhook
+// @Success 200 {array} db.WebhookDelivery "Delivery log for /deliveries"
+// @Failure 400 {string} string "Bad request"
+// @Failure 404 {string} string "No webhook with that id"
+// @Failure 405 {string} string "Method not allowed"
+// @Failure 500 {string} string "Internal server error"
+// @Router /webhooks [get]
+// @Router /webhooks [post]
+// @Router /webhooks/delete [post]
+// @Router /webhooks/deliveries [get]
+func (s *Server) WebhooksHandler(w http.ResponseWriter, r *http.Request) {
+	log.Info("Called /webhooks", "method", r.Method, "path", r.URL.Path)
+
+	switch r.URL.Path {
+	case "/webhooks":
+		if r.Method == http.MethodGet {
+			writeJSON(w, s.Webhooks.Webhooks())
+			return
+		}
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		var hook Webhook
+		if err := json.NewDecoder(r.Body).Decode(&hook); err != nil {
+			http.Error(w, "Invalid JSON body", http.StatusBadRequest)
+			return
+		}
+		if err := hook.Validate(); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		saved, err := s.Webhooks.Save(hook)
+		if err != nil {
+			log.Error("Failed to save webhook", "err", err)
+			http.Error(w, "Failed to save webhook", http.StatusInternalServerError)
+			return
+		}
+		writeJSON(w, saved)
+
+	case "/webhooks/delete":
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		var body struct {
+			ID string `json:"id"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			http.Error(w, "Invalid JSON body", http.StatusBadRequest)
+			return
+		}
+		found, err := s.Webhooks.Delete(body.ID)
+		if !found {
+			http.Error(w, "No webhook with that id", http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			log.Error("Failed to delete webhook", "err", err)
+			http.Error(w, "Failed to delete webhook", http.StatusInternalServerError)
+			return
+		}
+		writeJSON(w, s.Webhooks.Webhooks())
+
+	case "/webhooks/deliveries":
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		q := r.URL.Query()
+		status := q.Get("status")
+		switch status {
+		case "", "pending", "delivered", "failed":
+		default:
+			http.Error(w, "status must be pending, delivered or failed", http.StatusBadRequest)
+			return
+		}
+		limit := 50
+		if v := q.Get("limit"); v != "" {
+			var err error
+			limit, err = strconv.Atoi(v)
+			if err != nil || limit < 1 || limit > 200 {
+				http.Error(w, "limit must be between 1 and 200", http.StatusBadRequest)
+				return
+			}
+		}
+		if s.DBManager == nil {
+			writeJSON(w, []db.WebhookDelivery{})
+			return
+		}
+		deliveries, err := s.DBManager.GetRecentWebhookDeliveries(limit, status)
+		if err != nil {
+			log.Error("Failed to read webhook deliveries", "err", err)
+			http.Error(w, "Failed to read webhook deliveries", http.StatusInternalServerError)
+			return
+		}
+		writeJSON(w, deliveries)
+
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// @Summary List or toggle local command hooks
+// @Description GET /command-hooks lists the commands from COMMAND_HOOKS_FILE. POST
+// @Description /command-hooks/toggle with {"name": ..., "enabled": bool} switches one on
+// @Description or off. Commands themselves can only be changed in the file.
+// @Tags hooks
+// @Accept json
+// @Produce json
+// @Success 200 {array} CommandHook
+// @Failure 400 {string} string "Bad request"
+// @Failure 404 {string} string "No command with that name"
+// @Failure 405 {string} string "Method not allowed"
+// @Failure 500 {string} string "Internal server error"
+// @Router /command-hooks [get]
+// @Router /command-hooks/toggle [post]
+func (s *Server) CommandHooksHandler(w http.ResponseWriter, r *http.Request) {
+	log.Info("Called /command-hooks", "method", r.Method, "path", r.URL.Path)
+
+	switch r.URL.Path {
+	case "/command-hooks":
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		if s.CommandHooks == nil {
+			writeJSON(w, []CommandHook{})
+			return
+		}
+		writeJSON(w, s.CommandHooks.Commands())
+
+	case "/command-hooks/toggle":
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		var body struct {
+			Name    string `json:"name"`
+			Enabled *bool  `json:"enabled"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			http.Error(w, "Invalid JSON body", http.StatusBadRequest)
+			return
+		}
+		if body.Enabled == nil {
+			http.Error(w, "enabled is required", http.StatusBadRequest)
+			return
+		}
+		if s.CommandHooks == nil {
+			http.Error(w, "No command with that name", http.StatusNotFound)
+			return
+		}
+		found, err := s.CommandHooks.SetEnabled(body.Name, *body.Enabled)
+		if !found {
+			http.Error(w, "No command with that name", http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			log.Error("Failed to save command hooks", "err", err)
+			http.Error(w, "Failed to save command hooks", http.StatusInternalServerError)
+			return
+		}
+		writeJSON(w, s.CommandHooks.Commands())
+
+	default:
+		http.NotFound(w, r)
 	}
+}
 
-	durationInt, err := strconv.Atoi(duration)
-	if err != nil {
-		http.Error(w, "Failed to parse duration", http.StatusBadRequest)
-		return
+// @Summary Manage recurring weekly focus windows
+// @Description GET /focus-rules lists rules. POST /focus-rules with a JSON rule creates it,
+// @Description or replaces the rule with the same id. POST /focus-rules/delete with
+// @Description {"id": ...} removes one. GET /focus-rules/preview?n=N lists the next N
+// @Description windows across all enabled rules.
+// @Tags focus
+// @Accept json
+// @Produce json
+// @Param n query int false "Number of windows to preview (default 10)"
+// @Success 200 {array} FocusRule
+// @Success 200 {array} FocusOccurrence "Upcoming windows for /preview"
+// @Failure 400 {string} string "Bad request"
+// @Failure 404 {string} string "No rule with that id"
+// @Failure 405 {string} string "Method not allowed"
+// @Failure 500 {string} string "Internal server error"
+// @Router /focus-rules [get]
+// @Router /focus-rules [post]
+// @Router /focus-rules/delete [post]
+// @Router /focus-rules/preview [get]
+func (s *Server) FocusRulesHandler(w http.ResponseWriter, r *http.Request) {
+	log.Info("Called /focus-rules", "method", r.Method, "path", r.URL.Path)
+
+	switch r.URL.Path {
+	case "/focus-rules":
+		if r.Method == http.MethodGet {
+			writeJSON(w, s.FocusRules.Rules())
+			return
+		}
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		var rule FocusRule
+		if err := json.NewDecoder(r.Body).Decode(&rule); err != nil {
+			http.Error(w, "Invalid JSON body", http.StatusBadRequest)
+			return
+		}
+		if err := rule.Validate(); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		saved, err := s.FocusRules.Save(rule)
+		if err != nil {
+			log.Error("Failed to save focus rule", "err", err)
+			http.Error(w, "Failed to save focus rule", http.StatusInternalServerError)
+			return
+		}
+		writeJSON(w, saved)
+
+	case "/focus-rules/delete":
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		var body struct {
+			ID string `json:"id"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			http.Error(w, "Invalid JSON body", http.StatusBadRequest)
+			return
+		}
+		found, err := s.FocusRules.Delete(body.ID)
+		if !found {
+			http.Error(w, "No rule with that id", http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			log.Error("Failed to delete focus rule", "err", err)
+			http.Error(w, "Failed to delete focus rule", http.StatusInternalServerError)
+			return
+		}
+		writeJSON(w, s.FocusRules.Rules())
+
+	case "/focus-rules/preview":
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		n := 10
+		if v := r.URL.Query().Get("n"); v != "" {
+			var err error
+			n, err = strconv.Atoi(v)
+			if err != nil || n < 1 || n > 100 {
+				http.Error(w, "n must be between 1 and 100", http.StatusBadRequest)
+				return
+			}
+		}
+		writeJSON(w, s.FocusRules.Preview(n))
+
+	default:
+		http.NotFound(w, r)
 	}
-	log.Info("Focus parameters", "duration", durationInt)
+}
 
-	s.State.HandleFocusChange(focusing, durationInt)
-	writeJSON(w, s.State.GetCurrentFocusInfo())
+// @Summary Manage time-of-day agent-lock policies
+// @Description GET /lock-policies lists policies. POST /lock-policies with a JSON policy
+// @Description creates it, or replaces the policy with the same id. POST /lock-policies/delete
+// @Description with {"id": ...} removes one. GET /lock-policies/windows lists the open and
+// @Description upcoming windows of the next week. While a window is open the agent lock is
+// @Description released; the releases are journaled with source "schedule".
+// @Tags agent-lock
+// @Accept json
+// @Produce json
+// @Success 200 {array} LockPolicy
+// @Success 200 {array} LockWindow "Open and upcoming windows for /windows"
+// @Failure 400 {string} string "Bad request"
+// @Failure 404 {string} string "No policy with that id"
+// @Failure 405 {string} string "Method not allowed"
+// @Failure 500 {string} string "Internal server error"
+// @Router /lock-policies [get]
+// @Router /lock-policies [post]
+// @Router /lock-policies/delete [post]
+// @Router /lock-policies/windows [get]
+func (s *Server) LockPoliciesHandler(w http.ResponseWriter, r *http.Request) {
+	log.Info("Called /lock-policies", "method", r.Method, "path", r.URL.Path)
+
+	switch r.URL.Path {
+	case "/lock-policies":
+		if r.Method == http.MethodGet {
+			writeJSON(w, s.LockPolicies.Policies())
+			return
+		}
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		var policy LockPolicy
+		if err := json.NewDecoder(r.Body).Decode(&policy); err != nil {
+			http.Error(w, "Invalid JSON body", http.StatusBadRequest)
+			return
+		}
+		if err := policy.Validate(); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		saved, err := s.LockPolicies.Save(policy)
+		if err != nil {
+			log.Error("Failed to save lock policy", "err", err)
+			http.Error(w, "Failed to save lock policy", http.StatusInternalServerError)
+			return
+		}
+		writeJSON(w, saved)
+
+	case "/lock-policies/delete":
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		var body struct {
+			ID string `json:"id"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			http.Error(w, "Invalid JSON body", http.StatusBadRequest)
+			return
+		}
+		found, err := s.LockPolicies.Delete(body.ID)
+		if !found {
+			http.Error(w, "No policy with that id", http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			log.Error("Failed to delete lock policy", "err", err)
+			http.Error(w, "Failed to delete lock policy", http.StatusInternalServerError)
+			return
+		}
+		writeJSON(w, s.LockPolicies.Policies())
+
+	case "/lock-policies/windows":
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		writeJSON(w, s.LockPolicies.Windows())
+
+	default:
+		http.NotFound(w, r)
+	}
 }
 
 // @Summary Get or release/engage the agent lock
 // @Description GET returns current agent-lock state. POST /agent-lock/release with form
 // @Description duration=N (seconds) releases the lock for N seconds (extends if longer
-// @Description than current release). POST /agent-lock/engage cancels any active release.
+// @Description than current release). Optional targets (repeated or comma-separated
+// @Description hostnames or app packages) release only those; the rest stays locked.
+// @Description Optional thread_id files the grant under a plea conversation.
+// @Description Under a daily release budget a grant is clipped to what is left and
+// @Description refused with 409 once it is spent; is_override=true bypasses the budget.
+// @Description While a focus session holds the lock, grants are refused with 409 and
+// @Description only an override releases it (for the rest of that session).
+// @Description Within the grant cooldown after a release ends, grants are refused with
+// @Description 429 and a GrantRefusal saying how long is left; overrides bypass it and
+// @Description are journaled with cooldown_bypassed.
+// @Description POST /agent-lock/engage cancels any active release.
+// @Description POST /agent-lock/request-delayed starts a countdown (the server's unlock
+// @Description delay) after which the lock is released for the server's unlock duration,
+// @Description without asking the judge; 409 if one is already counting down.
+// @Description POST /agent-lock/cancel-delayed stops the countdown.
+// @Description GET /agent-lock/trends?days=N (default 7, at most 90) returns LockTrends:
+// @Description per local day the grant, override and denial counts, released seconds,
+// @Description temptations and the share of denials that ended in an override.
 // @Tags agent-lock
 // @Produce json
 // @Success 200 {object} AgentLockInfo
+// @Success 200 {object} LockTrends "For /trends"
 // @Failure 400 {string} string "Bad request"
 // @Failure 405 {string} string "Method not allowed"
+// @Failure 409 {string} string "Release budget spent or focus holds the lock"
+// @Failure 429 {object} GrantRefusal
 // @Router /agent-lock [get]
 // @Router /agent-lock/release [post]
 // @Router /agent-lock/engage [post]
+// @Router /agent-lock/request-delayed [post]
+// @Router /agent-lock/cancel-delayed [post]
+// @Router /agent-lock/trends [get]
 func (s *Server) AgentLockHandler(w http.ResponseWriter, r *http.Request) {
 	log.Info("Called /agent-lock", "method", r.Method, "path", r.URL.Path)
 
@@ -126,15 +1032,62 @@ func (s *Server) AgentLockHandler(w http.ResponseWriter, r *http.Request) {
 			http.Error(w, "duration must be a positive integer (seconds)", http.StatusBadRequest)
 			return
 		}
-		s.State.ReleaseAgentLock(time.Duration(duration) * time.Second)
+		targets, err := parseReleaseTargets(r.Form["targets"])
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		threadID := r.FormValue("thread_id")
+		if threadID != "" && !validThreadID(threadID) {
+			http.Error(w, errInvalidThreadID.Error(), http.StatusBadRequest)
+			return
+		}
+
+		override := r.FormValue("is_override") == "true"
+		if !override && s.State.FocusHoldsAgentLock() {
+			http.Error(w, "A focus session holds the agent lock; releasing it needs is_override=true", http.StatusConflict)
+			return
+		}
+		cooldownLeft := s.State.AgentGrantCooldownLeft()
+		if cooldownLeft > 0 {
+			if !override {
+				writeGrantRefusal(w, cooldownLeft)
+				return
+			}
+			log.Info("Override bypassing grant cooldown", "cooldown_left", cooldownLeft)
+		}
 
 		// Journal the decision. The override flag lives only on the wire; the
-		// stored kind carries it.
-		kind := "grant"
-		if r.FormValue("is_override") == "true" {
-			kind = "override"
+		// stored kind carries it. Under a budget, grants journal themselves so
+		// the next grant counts this one.
+		grantID := db.NewRecordID()
+		entry := releaseDecision(grantID, r.FormValue("user_message"), r.FormValue("agent_message"),
+			threadID, duration, override, cooldownLeft)
+		switch {
+		case override:
+			s.logLockDecisionEntry(entry)
+		case s.ReleaseBudget != nil:
+			granted, err := s.ReleaseBudget.Grant(entry)
+			if errors.Is(err, errBudgetSpent) {
+				http.Error(w, err.Error(), http.StatusConflict)
+				return
+			}
+			if err != nil {
+				log.Error("Failed to check release budget", "err", err)
+				http.Error(w, "Failed to check release budget", http.StatusInternalServerError)
+				return
+			}
+			if granted < duration {
+				log.Info("Grant clipped to release budget", "requested", duration, "granted", granted)
+				duration = granted
+			}
+		default:
+			s.logLockDecisionEntry(entry)
+		}
+		if override {
+			s.State.BreakFocusHold()
 		}
-		s.logLockDecision(kind, r.FormValue("user_message"), r.FormValue("agent_message"), duration)
+		s.State.ReleaseAgentLockGrant(time.Duration(duration)*time.Second, targets, grantID)
 
 		writeJSON(w, s.State.GetAgentLockInfo())
 
@@ -146,34 +1099,147 @@ func (s *Server) AgentLockHandler(w http.ResponseWriter, r *http.Request) {
 		s.State.EngageAgentLock()
 		writeJSON(w, s.State.GetAgentLockInfo())
 
+	case "/agent-lock/request-delayed":
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		if err := r.ParseForm(); err != nil {
+			http.Error(w, "Failed to parse form", http.StatusBadRequest)
+			return
+		}
+		delay, duration := s.UnlockDelay, s.UnlockDuration
+		if delay <= 0 {
+			delay = DefaultUnlockDelay
+		}
+		if duration <= 0 {
+			duration = DefaultUnlockDuration
+		}
+		if !s.State.RequestDelayedUnlock(delay, duration, r.FormValue("user_message")) {
+			http.Error(w, "A delayed unlock is already counting down", http.StatusConflict)
+			return
+		}
+		writeJSON(w, s.State.GetAgentLockInfo())
+
+	case "/agent-lock/cancel-delayed":
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		s.State.CancelDelayedUnlock()
+		writeJSON(w, s.State.GetAgentLockInfo())
+
 	case "/agent-lock/state":
 		if r.Method != http.MethodGet {
 			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 			return
 		}
-		s.writeLockState(w)
+		s.writeLockState(w, r)
+
+	case "/agent-lock/trends":
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		s.writeLockTrends(w, r)
 
 	default:
 		http.NotFound(w, r)
 	}
 }
 
+// releaseDecision is the journal entry for a release the judge handed out: an
+// override, flagged when it cut through the grant cooldown, or a plain grant.
+func releaseDecision(id, userMessage, agentMessage, threadID string, duration int, override bool, cooldownLeft time.Duration) db.LockDecision {
+	entry := db.LockDecision{
+		ID: id, Kind: "grant", Source: "agent", ThreadID: threadID,
+		UserMessage: userMessage, AgentMessage: agentMessage, DurationSeconds: duration,
+	}
+	if override {
+		entry.Kind = "override"
+		entry.CooldownBypassed = cooldownLeft > 0
+	}
+	return entry
+}
+
+// GrantRefusal is the body of a grant refused during the cooldown, for the
+// judge to quote back.
+type GrantRefusal struct {
+	Error           string `json:"error"`
+	CooldownSeconds int64  `json:"cooldown_seconds"`
+}
+
+// writeGrantRefusal answers a grant made within the cooldown with 429, a
+// Retry-After header and a GrantRefusal.
+func writeGrantRefusal(w http.ResponseWriter, left time.Duration) {
+	secs := int64((left + time.Second - 1) / time.Second)
+	refusal := GrantRefusal{
+		Error:           fmt.Sprintf("The agent lock was released too recently; the next grant is possible in %d seconds", secs),
+		CooldownSeconds: secs,
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Retry-After", strconv.FormatInt(secs, 10))
+	w.WriteHeader(http.StatusTooManyRequests)
+	json.NewEncoder(w).Encode(refusal)
+}
+
+// parseReleaseTargets reads the targets of a scoped release: hostnames or app
+// packages, repeated or comma-separated. A URL counts as its hostname. Returns
+// nil for a release of everything.
+func parseReleaseTargets(values []string) ([]string, error) {
+	var targets []string
+	seen := map[string]bool{}
+	for _, value := range values {
+		for _, target := range strings.Split(value, ",") {
+			target = strings.ToLower(strings.TrimSpace(target))
+			if target == "" {
+				continue
+			}
+			if strings.Contains(target, "://") {
+				u, err := url.Parse(target)
+				if err != nil || u.Hostname() == "" {
+					return nil, fmt.Errorf("target %q is not a valid URL", target)
+				}
+				target = u.Hostname()
+			}
+			if strings.ContainsAny(target, " \t/:?#@") {
+				return nil, fmt.Errorf("target %q must be a hostname or app package", target)
+			}
+			if !seen[target] {
+				seen[target] = true
+				targets = append(targets, target)
+			}
+		}
+	}
+	return targets, nil
+}
+
 // logLockDecision writes a decision row, best-effort and asynchronous. A failure
 // (or a missing DB in tests) loses the journal row, never the lock action.
-func (s *Server) logLockDecision(kind, userMessage, agentMessage string, durationSeconds int) {
+func (s *Server) logLockDecision(kind, source, userMessage, agentMessage string, durationSeconds int) {
+	s.logLockDecisionEntry(db.LockDecision{
+		Kind: kind, Source: source,
+		UserMessage: userMessage, AgentMessage: agentMessage, DurationSeconds: durationSeconds,
+	})
+}
+
+// logLockDecisionEntry is logLockDecision for a full entry, e.g. one written
+// under a known id.
+func (s *Server) logLockDecisionEntry(d db.LockDecision) {
 	if s.DBManager == nil {
 		return
 	}
 	go func() {
-		if err := s.DBManager.InsertLockDecision(kind, "agent", userMessage, agentMessage, durationSeconds); err != nil {
-			log.Error("Failed to journal lock decision", "kind", kind, "error", err)
+		if err := s.DBManager.InsertLockDecisionEntry(d); err != nil {
+			log.Error("Failed to journal lock decision", "kind", d.Kind, "error", err)
 		}
 	}()
 }
 
-// logTemptation records one blocked attempt, best-effort and asynchronous. A
-// failure (or a missing DB in tests) loses the row, never anything else.
+// logTemptation publishes and records one blocked attempt, best-effort and
+// asynchronous. A failure (or a missing DB in tests) loses the row, never anything else.
 func (s *Server) logTemptation(source, target string) {
+	s.State.Events().Publish(TemptationRecorded{At: time.Now(), Source: source, Target: target})
 	if s.DBManager == nil {
 		return
 	}
@@ -184,24 +1250,38 @@ func (s *Server) logTemptation(source, target string) {
 	}()
 }
 
-// writeLockState answers GET /agent-lock/state from today's journal: total
-// released seconds, override count, and the most recent decisions.
-func (s *Server) writeLockState(w http.ResponseWriter) {
+// writeLockState answers GET /agent-lock/state from today's journal: seconds
+// granted and seconds actually released (ended releases plus the ones still
+// running), override and broken-commitment counts, the release budget when one
+// is set, the most recent decisions and today's tasks, which are empty when no
+// task source is set up or it can't be read.
+func (s *Server) writeLockState(w http.ResponseWriter, r *http.Request) {
 	type recentEntry struct {
 		At              string `json:"at"`
 		Kind            string `json:"kind"`
 		UserMessage     string `json:"user_message"`
 		AgentMessage    string `json:"agent_message"`
 		DurationSeconds int    `json:"duration_seconds"`
+		ThreadID        string `json:"thread_id,omitempty"`
 	}
 	out := struct {
+		GrantedSecondsToday  int           `json:"granted_seconds_today"`
 		ReleasedSecondsToday int           `json:"released_seconds_today"`
 		OverrideCountToday   int           `json:"override_count_today"`
+		BrokenCommitsToday   int           `json:"broken_commitments_today"`
 		TemptationCountToday int           `json:"temptation_count_today"`
+		Budget               *BudgetStatus `json:"budget"`
 		Recent               []recentEntry `json:"recent"`
+		Tasks                TodayTasks    `json:"tasks"`
 	}{Recent: []recentEntry{}}
+	out.ReleasedSecondsToday = s.State.RunningReleaseSeconds()
+	out.Tasks = fetchTodayTasks(r.Context(), s.Tasks)
 
 	if s.DBManager == nil {
+		if s.ReleaseBudget != nil {
+			status := s.ReleaseBudget.Status(nil)
+			out.Budget = &status
+		}
 		writeJSON(w, out)
 		return
 	}
@@ -221,48 +1301,120 @@ func (s *Server) writeLockState(w http.ResponseWriter) {
 		return
 	}
 
+	out.GrantedSecondsToday = handedOutSeconds(decisions)
 	for _, d := range decisions {
-		if d.Kind == "grant" || d.Kind == "override" {
-			out.ReleasedSecondsToday += d.DurationSeconds
+		if d.Kind == "engage" || d.Kind == "expired" {
+			out.ReleasedSecondsToday += d.ActualSeconds
 		}
 		if d.Kind == "override" {
 			out.OverrideCountToday++
 		}
+		if d.Kind == "broken_commitment" {
+			out.BrokenCommitsToday++
+		}
 	}
 
-	// Last 5, newest first (decisions arrive oldest-first).
-	start := len(decisions) - 5
-	if start < 0 {
-		start = 0
+	if s.ReleaseBudget != nil {
+		status, err := s.ReleaseBudget.Today()
+		if err != nil {
+			log.Error("Failed to read release budget", "err", err)
+			http.Error(w, "Failed to read release budget", http.StatusInternalServerError)
+			return
+		}
+		out.Budget = &status
 	}
-	for i := len(decisions) - 1; i >= start; i-- {
-		d := decisions[i]
+
+	for _, d := range recentLockDecisions(decisions, 5) {
 		out.Recent = append(out.Recent, recentEntry{
 			At:              d.Created,
 			Kind:            d.Kind,
 			UserMessage:     d.UserMessage,
 			AgentMessage:    d.AgentMessage,
 			DurationSeconds: d.DurationSeconds,
+			ThreadID:        d.ThreadID,
 		})
 	}
 
 	writeJSON(w, out)
 }
 
-// @Summary Record a lock denial
-// @Description The coach agent posts here when it refuses a release request.
+// handedOutSeconds adds up the releases handed out in decisions: grants,
+// overrides and delayed unlocks. Lock policy windows open on their own and
+// are left out.
+func handedOutSeconds(decisions []db.LockDecision) int {
+	total := 0
+	for _, d := range decisions {
+		if (d.Kind == "grant" || d.Kind == "override" || d.Kind == "delayed") && d.Source != "schedule" {
+			total += d.DurationSeconds
+		}
+	}
+	return total
+}
+
+// pleaDecisionKinds are the journal kinds that answer a plea or break a
+// commitment, as opposed to turns and the ends of releases.
+var pleaDecisionKinds = map[string]bool{
+	"grant": true, "override": true, "denial": true, "broken_commitment": true, "delayed": true,
+}
+
+// isPleaDecision reports whether d answers a plea or breaks a commitment. A
+// lock policy window is journaled as a grant but nobody asked for it.
+func isPleaDecision(d db.LockDecision) bool {
+	return pleaDecisionKinds[d.Kind] && d.Source != "schedule"
+}
+
+// recentLockDecisions returns the last n decisions of the journal (oldest
+// first), newest first, skipping turns, policy windows and release ends.
+func recentLockDecisions(decisions []db.LockDecision, n int) []db.LockDecision {
+	var recent []db.LockDecision
+	for i := len(decisions) - 1; i >= 0 && len(recent) < n; i-- {
+		if isPleaDecision(decisions[i]) {
+			recent = append(recent, decisions[i])
+		}
+	}
+	return recent
+}
+
+// @Summary List lock decisions or record a denial
+// @Description GET pages through the journal, newest first. Filter by from/to
+// @Description (RFC3339 or YYYY-MM-DD; a to date includes the day), kind and source
+// @Description (repeated or comma-separated) and q, a search of both messages. Pass
+// @Description next_cursor back as cursor for the next page. format=csv or
+// @Description format=jsonl downloads every match instead.
+// @Description POST is how the coach agent records refusing a release request.
 // @Description Grants, overrides, and engages go through the lock endpoints
-// @Description that actually change state; this endpoint is denials only.
+// @Description that actually change state; POST is denials only, or with kind "turn"
+// @Description an exchange in a plea that decided nothing yet. thread_id files either
+// @Description under a plea conversation (required for a turn).
 // @Tags agent-lock
 // @Accept json
 // @Produce json
+// @Param from query string false "Earliest decision, RFC3339 or YYYY-MM-DD"
+// @Param to query string false "Latest decision, RFC3339 or YYYY-MM-DD"
+// @Param kind query string false "Only these kinds"
+// @Param source query string false "Only these sources"
+// @Param q query string false "Text in user_message or agent_message"
+// @Param limit query int false "Page size (default 50, max 500)"
+// @Param cursor query string false "next_cursor of the previous page"
+// @Param format query string false "json (default), csv or jsonl"
+// @Success 200 {object} LockDecisionPage
 // @Success 200 {object} map[string]bool
 // @Failure 400 {string} string "Bad request"
 // @Failure 405 {string} string "Method not allowed"
+// @Failure 500 {string} string "Internal server error"
+// @Router /lock-decisions [get]
 // @Router /lock-decisions [post]
 func (s *Server) LockDecisionsHandler(w http.ResponseWriter, r *http.Request) {
 	log.Info("Called /lock-decisions", "method", r.Method)
 
+	if r.Method == http.MethodGet {
+		if s.DBManager == nil {
+			http.Error(w, "Lock decisions are not available without the database", http.StatusServiceUnavailable)
+			return
+		}
+		writeLockHistory(w, r, s.DBManager)
+		return
+	}
 	if r.Method != http.MethodPost {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
@@ -272,28 +1424,78 @@ func (s *Server) LockDecisionsHandler(w http.ResponseWriter, r *http.Request) {
 		Kind         string `json:"kind"`
 		UserMessage  string `json:"user_message"`
 		AgentMessage string `json:"agent_message"`
+		ThreadID     string `json:"thread_id"`
 	}
 	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
 		http.Error(w, "Invalid JSON body", http.StatusBadRequest)
 		return
 	}
-	// Kind is implied: this endpoint only records denials. A caller setting
-	// kind is confused about what this endpoint does.
-	if body.Kind != "" {
-		http.Error(w, "kind is not accepted here; this endpoint records denials only", http.StatusBadRequest)
+	// Kind is implied: this endpoint only records denials, and the turns of a
+	// plea leading up to a decision. A caller setting any other kind is
+	// confused about what this endpoint does.
+	kind := "denial"
+	switch body.Kind {
+	case "":
+	case "turn":
+		if body.ThreadID == "" {
+			http.Error(w, "thread_id is required for a turn", http.StatusBadRequest)
+			return
+		}
+		kind = "turn"
+	default:
+		http.Error(w, "kind is not accepted here; this endpoint records denials and turns only", http.StatusBadRequest)
+		return
+	}
+	if body.ThreadID != "" && !validThreadID(body.ThreadID) {
+		http.Error(w, errInvalidThreadID.Error(), http.StatusBadRequest)
 		return
 	}
 
-	s.logLockDecision("denial", body.UserMessage, body.AgentMessage, 0)
+	s.logLockDecisionEntry(db.LockDecision{
+		Kind: kind, Source: "agent", ThreadID: body.ThreadID,
+		UserMessage: body.UserMessage, AgentMessage: body.AgentMessage,
+	})
 	writeJSON(w, map[string]bool{"ok": true})
 }
 
+// @Summary Get a plea conversation
+// @Description Returns every turn and decision filed under the thread id, oldest
+// @Description first, with the latest decision as outcome and the denials along the way.
+// @Tags agent-lock
+// @Produce json
+// @Param id path string true "Thread id"
+// @Success 200 {object} LockThread
+// @Failure 400 {string} string "Bad request"
+// @Failure 404 {string} string "No lock decisions in that thread"
+// @Failure 500 {string} string "Internal server error"
+// @Router /lock-decisions/threads/{id} [get]
+func (s *Server) LockThreadHandler(w http.ResponseWriter, r *http.Request) {
+	log.Info("Called /lock-decisions/threads", "method", r.Method, "id", r.PathValue("id"))
+
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if s.DBManager == nil {
+		http.Error(w, "Lock decisions are not available without the database", http.StatusServiceUnavailable)
+		return
+	}
+	writeLockThread(w, r.PathValue("id"), s.DBManager)
+}
+
 // @Summary Get focus history
-// @Description Returns focus records for the last N days
+// @Description Returns focus records for the last N days, optionally filtered by
+// @Description label, project or task. With group_by, returns per-value totals instead.
 // @Tags focus
 // @Produce json
 // @Param days query int false "Number of days to look back (default 7)"
+// @Param label query string false "Only sessions with this label"
+// @Param project query string false "Only sessions for this project"
+// @Param task query string false "Only sessions for this task"
+// @Param group_by query string false "label, project or task"
 // @Success 200 {array} db.FocusRecord "Array of focus records"
+// @Success 200 {array} stats.FocusGroup "Per-value totals when group_by is set"
+// @Failure 400 {string} string "Bad request"
 // @Failure 500 {string} string "Internal server error"
 // @Router /history [get]
 func (s *Server) HistoryHandler(w http.ResponseWriter, r *http.Request) {
@@ -315,13 +1517,28 @@ func (s *Server) HistoryHandler(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	records, err := s.DBManager.GetFocusHistory(days)
+	groupBy := r.URL.Query().Get("group_by")
+	if groupBy != "" && !stats.IsFocusGroupKey(groupBy) {
+		http.Error(w, "group_by must be label, project or task", http.StatusBadRequest)
+		return
+	}
+
+	filter := db.HistoryFilter{
+		Label:   r.URL.Query().Get("label"),
+		Project: r.URL.Query().Get("project"),
+		Task:    r.URL.Query().Get("task"),
+	}
+	records, err := s.DBManager.GetFocusHistory(days, filter)
 	if err != nil {
 		log.Error("Failed to get focus history", "err", err)
 		http.Error(w, "Failed to get focus history", http.StatusInternalServerError)
 		return
 	}
 
+	if groupBy != "" {
+		writeJSON(w, stats.GroupFocusRecords(records, groupBy))
+		return
+	}
 	writeJSON(w, records)
 }
 
@@ -373,6 +1590,25 @@ func (s *Server) AttentionHandler(w http.ResponseWriter, r *http.Request) {
 	writeJSON(w, intervals)
 }
 
+// @Summary Today's tasks
+// @Description Incomplete Dimaist tasks due today or earlier, earliest first. When
+// @Description DIMAIST_URL is unset configured is false; when Dimaist can't be read
+// @Description error is set. Tasks is empty in both cases.
+// @Tags tasks
+// @Produce json
+// @Success 200 {object} TodayTasks
+// @Router /tasks/today [get]
+func (s *Server) TodayTasksHandler(w http.ResponseWriter, r *http.Request) {
+	log.Info("Called /tasks/today", "method", r.Method)
+
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	writeJSON(w, fetchTodayTasks(r.Context(), s.Tasks))
+}
+
 // @Summary Today's attention summary
 // @Description What has the user's attention right now, and where today's site time went
 // @Tags attention
@@ -458,6 +1694,11 @@ func (s *Server) WebsocketHandler(w http.ResponseWriter, r *http.Request) {
 			Site     string `json:"site,omitempty"`
 			Source   string `json:"source,omitempty"`
 			Target   string `json:"target,omitempty"`
+			Label    string `json:"label,omitempty"`
+			Project  string `json:"project,omitempty"`
+			Task     string `json:"task,omitempty"`
+			Replace  bool   `json:"replace,omitempty"`
+			Commit   bool   `json:"commit,omitempty"`
 		}
 
 		if err := json.Unmarshal(buf, &message); err != nil {
@@ -476,7 +1717,33 @@ func (s *Server) WebsocketHandler(w http.ResponseWriter, r *http.Request) {
 			if message.Duration > 0 {
 				duration = message.Duration
 			}
-			s.State.HandleFocusChange(true, duration)
+			label := FocusLabel{Label: message.Label, Project: message.Project, Task: message.Task}
+			switch {
+			case message.Replace && s.State.IsCommitted():
+				// Overrides need a plea, which only the HTTP endpoint takes.
+				log.Warn("Refusing to replace committed focus over websocket")
+			case message.Replace:
+				by := message.Source
+				if by == "" {
+					by = "websocket"
+				}
+				s.State.ReplaceFocusing(time.Duration(duration)*time.Second, label, message.Commit, by)
+				go s.State.NotifyAllClients(s.State.GetCurrentFocusInfo())
+			case message.Commit:
+				s.State.SetCommittedFocusing(time.Duration(duration)*time.Second, label)
+				go s.State.NotifyAllClients(s.State.GetCurrentFocusInfo())
+			default:
+				s.State.HandleFocusChange(true, duration, label, "")
+			}
+		case "pause":
+			if s.State.IsCommitted() {
+				// Like a replace, pausing committed focus needs a plea over HTTP
+				log.Warn("Refusing to pause committed focus over websocket")
+				break
+			}
+			s.State.PauseFocus()
+		case "resume":
+			s.State.ResumeFocus()
 		case "attention":
 			switch message.State {
 			case "site", "idle", "away":
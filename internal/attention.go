@@ -31,6 +31,7 @@ type beacon struct {
 // WebSocket read loop is never blocked on PocketBase I/O.
 type AttentionTracker struct {
 	store   attentionStore
+	events  *EventBus
 	beacons chan beacon
 
 	// processing state, owned by the run goroutine
@@ -40,9 +41,10 @@ type AttentionTracker struct {
 	lastSeen time.Time
 }
 
-func NewAttentionTracker(store attentionStore) *AttentionTracker {
+func NewAttentionTracker(store attentionStore, events *EventBus) *AttentionTracker {
 	t := &AttentionTracker{
 		store:   store,
+		events:  events,
 		beacons: make(chan beacon, 64),
 	}
 	go t.run()
@@ -80,6 +82,12 @@ func (t *AttentionTracker) processBeacon(b beacon) {
 		return
 	}
 
+	if t.state != b.state || t.site != b.site {
+		t.events.Publish(AttentionChanged{At: b.at, State: b.state, Site: b.site})
+	}
+	t.state = b.state
+	t.site = b.site
+
 	id, err := t.store.CreateAttentionInterval(b.state, b.site, b.at)
 	if err != nil {
 		log.Error("Failed to create attention interval", "error", err)
@@ -87,7 +95,5 @@ func (t *AttentionTracker) processBeacon(b beacon) {
 		return
 	}
 	t.recordID = id
-	t.state = b.state
-	t.site = b.site
 	t.lastSeen = b.at
 }
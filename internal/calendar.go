@@ -0,0 +1,157 @@
+package coach
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/charmbracelet/log"
+
+	"coach/internal/calendar"
+)
+
+// defaultCalendarFocusPattern matches the event titles that mean focus time.
+const defaultCalendarFocusPattern = `(?i)\b(focus|deep work)\b`
+
+// calendarHorizon is how far ahead the calendar is read.
+const calendarHorizon = 24 * time.Hour
+
+// CalendarSync reads iCalendar files or URLs on an interval. Events whose
+// title matches the focus pattern start a focus session when they begin;
+// every other timed event is a meeting the clients see in FocusInfo.
+type CalendarSync struct {
+	state    *State
+	sources  []string
+	focus    *regexp.Regexp
+	interval time.Duration
+	http     *http.Client
+
+	mu      sync.Mutex
+	events  map[string][]calendar.Event // last good read per source
+	timers  map[string]*time.Timer      // pending focus starts by occurrence
+	started map[string]time.Time        // occurrences already started, by end
+}
+
+func NewCalendarSync(state *State, sources []string, focus *regexp.Regexp, interval time.Duration) *CalendarSync {
+	return &CalendarSync{
+		state:    state,
+		sources:  sources,
+		focus:    focus,
+		interval: interval,
+		http:     &http.Client{Timeout: 30 * time.Second},
+		events:   make(map[string][]calendar.Event),
+		timers:   make(map[string]*time.Timer),
+		started:  make(map[string]time.Time),
+	}
+}
+
+// Start reads the calendars now and then every interval, in the background.
+func (c *CalendarSync) Start() {
+	go func() {
+		c.Refresh(context.Background())
+		for range time.Tick(c.interval) {
+			c.Refresh(context.Background())
+		}
+	}()
+}
+
+// Refresh reads every source, publishes the meetings and re-arms the focus
+// starts. A source that can't be read keeps its last good events.
+func (c *CalendarSync) Refresh(ctx context.Context) {
+	now := time.Now()
+	for _, source := range c.sources {
+		events, err := calendar.Fetch(ctx, c.http, source, now, now.Add(calendarHorizon))
+		if err != nil {
+			log.Warn("Failed to read calendar", "source", calendarSourceName(source), "error", err)
+			continue
+		}
+		c.mu.Lock()
+		c.events[source] = events
+		c.mu.Unlock()
+	}
+
+	c.mu.Lock()
+	var meetings []Meeting
+	focus := map[string]calendar.Event{}
+	for _, events := range c.events {
+		for _, e := range events {
+			switch {
+			case e.AllDay || !e.End.After(now):
+			case c.focus.MatchString(e.Summary):
+				focus[calendarKey(e)] = e
+			case !e.Transparent:
+				meetings = append(meetings, Meeting{Title: e.Summary, Start: e.Start, End: e.End})
+			}
+		}
+	}
+	c.scheduleLocked(focus, now)
+	c.mu.Unlock()
+
+	sort.Slice(meetings, func(a, b int) bool { return meetings[a].Start.Before(meetings[b].Start) })
+	c.state.SetMeetings(meetings)
+}
+
+// scheduleLocked arms a timer for each focus event not yet started and drops
+// timers for events that left the calendar. Must be called with c.mu held.
+func (c *CalendarSync) scheduleLocked(focus map[string]calendar.Event, now time.Time) {
+	for key, timer := range c.timers {
+		if _, ok := focus[key]; !ok {
+			timer.Stop()
+			delete(c.timers, key)
+		}
+	}
+	for key, end := range c.started {
+		if !end.After(now) {
+			delete(c.started, key)
+		}
+	}
+	for key, e := range focus {
+		if _, ok := c.started[key]; ok {
+			continue
+		}
+		if _, ok := c.timers[key]; ok {
+			continue
+		}
+		c.timers[key] = time.AfterFunc(e.Start.Sub(now), func() { c.fire(key, e) })
+	}
+}
+
+// fire starts focus for an event whose time has come, running to the event's
+// end. A session already queued past that end is left alone.
+func (c *CalendarSync) fire(key string, e calendar.Event) {
+	c.mu.Lock()
+	_, pending := c.timers[key]
+	delete(c.timers, key)
+	if pending {
+		c.started[key] = e.End
+	}
+	c.mu.Unlock()
+	if !pending {
+		return
+	}
+
+	label := FocusLabel{Label: strings.TrimSpace(e.Summary)}
+	if c.state.SetFocusingUntil(e.End, label) {
+		log.Info("Calendar focus starting", "summary", e.Summary, "end", e.End)
+	} else {
+		log.Info("Calendar focus already covered", "summary", e.Summary, "end", e.End)
+	}
+}
+
+func calendarKey(e calendar.Event) string {
+	return e.UID + "@" + e.Start.UTC().Format(time.RFC3339)
+}
+
+// calendarSourceName is a source as it may be logged: private calendar URLs
+// carry their secret in the path, so only the host is shown.
+func calendarSourceName(source string) string {
+	if u, err := url.Parse(source); err == nil && u.Host != "" {
+		return u.Scheme + "://" + u.Host
+	}
+	return source
+}
@@ -0,0 +1,509 @@
+// Package calendar reads events from iCalendar (.ics) files and URLs.
+//
+// It covers what calendar exports need in practice: VEVENTs with UTC, TZID or
+// floating times, all-day dates, DURATION, EXDATE, RECURRENCE-ID overrides,
+// cancelled events, and RRULEs with FREQ DAILY, WEEKLY, MONTHLY or YEARLY plus
+// INTERVAL, COUNT, UNTIL and (daily/weekly) BYDAY. A rule using anything else
+// yields only its first occurrence.
+package calendar
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Event is one occurrence of a calendar event.
+type Event struct {
+	UID     string    `json:"uid"`
+	Summary string    `json:"summary"`
+	Start   time.Time `json:"start"`
+	End     time.Time `json:"end"`
+	AllDay  bool      `json:"all_day"`
+	// Transparent events don't block time (TRANSP:TRANSPARENT), e.g. reminders.
+	Transparent bool `json:"transparent"`
+}
+
+// maxOccurrences bounds the expansion of one recurring event. Expansion runs
+// from DTSTART so COUNT holds; this covers a daily event started decades ago.
+const maxOccurrences = 50000
+
+// Fetch reads source, a file path or an http(s) URL, and returns the
+// occurrences that overlap [from, to), earliest first.
+func Fetch(ctx context.Context, client *http.Client, source string, from, to time.Time) ([]Event, error) {
+	if !strings.HasPrefix(source, "http://") && !strings.HasPrefix(source, "https://") {
+		f, err := os.Open(source)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		return Parse(f, from, to)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "GET", source, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		// Leave the URL out: private calendar URLs embed their secret
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("calendar fetch failed with status %d", resp.StatusCode)
+	}
+	return Parse(resp.Body, from, to)
+}
+
+// property is one content line: NAME;PARAM=VALUE:value.
+type property struct {
+	name   string
+	params map[string]string
+	value  string
+}
+
+// vevent is a VEVENT as written, before recurrence expansion.
+type vevent struct {
+	uid          string
+	summary      string
+	start, end   time.Time
+	duration     time.Duration
+	hasEnd       bool
+	allDay       bool
+	transparent  bool
+	cancelled    bool
+	rrule        string
+	exdates      []time.Time
+	recurrenceID *time.Time
+}
+
+// Parse reads an iCalendar stream and returns the occurrences that overlap
+// [from, to), earliest first.
+func Parse(r io.Reader, from, to time.Time) ([]Event, error) {
+	lines, err := unfold(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var events []*vevent
+	var cur *vevent
+	nested := 0 // depth of components inside the current VEVENT, e.g. VALARM
+	for n, line := range lines {
+		p, ok := parseProperty(line)
+		if !ok {
+			continue
+		}
+		switch {
+		case p.name == "BEGIN" && strings.EqualFold(p.value, "VEVENT") && cur == nil:
+			cur = &vevent{}
+		case p.name == "END" && strings.EqualFold(p.value, "VEVENT") && cur != nil && nested == 0:
+			events = append(events, cur)
+			cur = nil
+		case cur == nil:
+		case p.name == "BEGIN":
+			nested++
+		case p.name == "END":
+			nested--
+		case nested > 0:
+		default:
+			if err := cur.set(p); err != nil {
+				return nil, fmt.Errorf("line %d: %w", n+1, err)
+			}
+		}
+	}
+
+	// Overridden instances replace the occurrence they were generated as
+	overridden := map[string]bool{}
+	for _, e := range events {
+		if e.recurrenceID != nil {
+			overridden[occurrenceKey(e.uid, *e.recurrenceID)] = true
+		}
+	}
+
+	var out []Event
+	for _, e := range events {
+		if e.start.IsZero() || e.cancelled {
+			continue
+		}
+		for _, occ := range e.occurrences(to) {
+			if e.recurrenceID == nil && overridden[occurrenceKey(e.uid, occ.Start)] {
+				continue
+			}
+			if occ.Start.Before(to) && (occ.End.After(from) || !occ.Start.Before(from)) {
+				out = append(out, occ)
+			}
+		}
+	}
+	sort.SliceStable(out, func(a, b int) bool { return out[a].Start.Before(out[b].Start) })
+	return out, nil
+}
+
+func occurrenceKey(uid string, start time.Time) string {
+	return uid + "@" + strconv.FormatInt(start.Unix(), 10)
+}
+
+// unfold joins continuation lines (RFC 5545 3.1) and drops line endings.
+func unfold(r io.Reader) ([]string, error) {
+	var lines []string
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) && len(lines) > 0 {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+		lines = append(lines, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read calendar: %w", err)
+	}
+	return lines, nil
+}
+
+// parseProperty splits a content line into name, params and value. The value
+// starts at the first colon outside a quoted param value.
+func parseProperty(line string) (property, bool) {
+	quoted := false
+	colon := -1
+	for i, c := range line {
+		if c == '"' {
+			quoted = !quoted
+		} else if c == ':' && !quoted {
+			colon = i
+			break
+		}
+	}
+	if colon < 0 {
+		return property{}, false
+	}
+	parts := strings.Split(line[:colon], ";")
+	p := property{name: strings.ToUpper(parts[0]), params: map[string]string{}, value: line[colon+1:]}
+	for _, param := range parts[1:] {
+		if k, v, ok := strings.Cut(param, "="); ok {
+			p.params[strings.ToUpper(k)] = strings.Trim(v, `"`)
+		}
+	}
+	return p, true
+}
+
+func (e *vevent) set(p property) error {
+	switch p.name {
+	case "UID":
+		e.uid = p.value
+	case "SUMMARY":
+		e.summary = unescapeText(p.value)
+	case "DTSTART":
+		t, allDay, err := parseTime(p)
+		if err != nil {
+			return fmt.Errorf("DTSTART: %w", err)
+		}
+		e.start, e.allDay = t, allDay
+	case "DTEND":
+		t, _, err := parseTime(p)
+		if err != nil {
+			return fmt.Errorf("DTEND: %w", err)
+		}
+		e.end, e.hasEnd = t, true
+	case "DURATION":
+		d, err := parseDuration(p.value)
+		if err != nil {
+			return fmt.Errorf("DURATION: %w", err)
+		}
+		e.duration = d
+	case "TRANSP":
+		e.transparent = strings.EqualFold(p.value, "TRANSPARENT")
+	case "STATUS":
+		e.cancelled = strings.EqualFold(p.value, "CANCELLED")
+	case "RRULE":
+		e.rrule = p.value
+	case "EXDATE":
+		for _, v := range strings.Split(p.value, ",") {
+			t, _, err := parseTime(property{params: p.params, value: v})
+			if err != nil {
+				return fmt.Errorf("EXDATE: %w", err)
+			}
+			e.exdates = append(e.exdates, t)
+		}
+	case "RECURRENCE-ID":
+		t, _, err := parseTime(p)
+		if err != nil {
+			return fmt.Errorf("RECURRENCE-ID: %w", err)
+		}
+		e.recurrenceID = &t
+	}
+	return nil
+}
+
+// parseTime reads a DATE or DATE-TIME value. UTC times end in Z; others are in
+// their TZID, or local time if the TZID is unknown or absent.
+func parseTime(p property) (time.Time, bool, error) {
+	v := p.value
+	if p.params["VALUE"] == "DATE" || len(v) == len("20060102") {
+		t, err := time.ParseInLocation("20060102", v, time.Local)
+		return t, true, err
+	}
+	if strings.HasSuffix(v, "Z") {
+		t, err := time.Parse("20060102T150405Z", v)
+		return t, false, err
+	}
+	loc := time.Local
+	if tzid := p.params["TZID"]; tzid != "" {
+		if l, err := time.LoadLocation(tzid); err == nil {
+			loc = l
+		}
+	}
+	t, err := time.ParseInLocation("20060102T150405", v, loc)
+	return t, false, err
+}
+
+// parseDuration reads an RFC 5545 duration such as PT1H30M, P1D or -PT15M.
+func parseDuration(v string) (time.Duration, error) {
+	sign := time.Duration(1)
+	switch {
+	case strings.HasPrefix(v, "-"):
+		sign, v = -1, v[1:]
+	case strings.HasPrefix(v, "+"):
+		v = v[1:]
+	}
+	if !strings.HasPrefix(v, "P") || len(v) < 3 {
+		return 0, fmt.Errorf("malformed duration %q", v)
+	}
+	units := map[byte]time.Duration{'W': 7 * 24 * time.Hour, 'D': 24 * time.Hour, 'H': time.Hour, 'M': time.Minute, 'S': time.Second}
+	var total time.Duration
+	num := ""
+	for i := 1; i < len(v); i++ {
+		c := v[i]
+		switch {
+		case c == 'T':
+		case c >= '0' && c <= '9':
+			num += string(c)
+		default:
+			unit, ok := units[c]
+			n, err := strconv.Atoi(num)
+			if !ok || err != nil {
+				return 0, fmt.Errorf("malformed duration %q", v)
+			}
+			total += time.Duration(n) * unit
+			num = ""
+		}
+	}
+	if num != "" {
+		return 0, fmt.Errorf("malformed duration %q", v)
+	}
+	return sign * total, nil
+}
+
+func unescapeText(v string) string {
+	return strings.NewReplacer(`\n`, "\n", `\N`, "\n", `\,`, ",", `\;`, ";", `\\`, `\`).Replace(v)
+}
+
+// length is how long each occurrence lasts.
+func (e *vevent) length() time.Duration {
+	switch {
+	case e.hasEnd:
+		return e.end.Sub(e.start)
+	case e.duration != 0:
+		return e.duration
+	case e.allDay:
+		return 24 * time.Hour
+	}
+	return 0
+}
+
+func (e *vevent) event(start time.Time) Event {
+	end := start.Add(e.length())
+	if e.allDay && e.hasEnd {
+		// Keep all-day events on date boundaries across DST changes
+		days := int(e.end.Sub(e.start).Hours()/24 + 0.5)
+		end = start.AddDate(0, 0, days)
+	}
+	return Event{UID: e.uid, Summary: e.summary, Start: start, End: end, AllDay: e.allDay, Transparent: e.transparent}
+}
+
+// occurrences expands the event's RRULE up to until, minus its EXDATEs.
+func (e *vevent) occurrences(until time.Time) []Event {
+	if e.rrule == "" || e.recurrenceID != nil {
+		return []Event{e.event(e.start)}
+	}
+	rule, ok := parseRule(e.rrule, e.start)
+	if !ok {
+		return []Event{e.event(e.start)}
+	}
+	excluded := map[int64]bool{}
+	for _, x := range e.exdates {
+		excluded[x.Unix()] = true
+	}
+
+	var out []Event
+	for _, start := range rule.starts(e.start, until) {
+		if !excluded[start.Unix()] {
+			out = append(out, e.event(start))
+		}
+	}
+	return out
+}
+
+// rule is the supported subset of an RRULE.
+type rule struct {
+	freq     string
+	interval int
+	count    int
+	until    *time.Time
+	byDay    []time.Weekday
+}
+
+var icalWeekdays = map[string]time.Weekday{
+	"SU": time.Sunday, "MO": time.Monday, "TU": time.Tuesday, "WE": time.Wednesday,
+	"TH": time.Thursday, "FR": time.Friday, "SA": time.Saturday,
+}
+
+// parseRule reads an RRULE value. ok is false for rules outside the supported subset.
+func parseRule(v string, dtstart time.Time) (rule, bool) {
+	r := rule{interval: 1}
+	for _, part := range strings.Split(v, ";") {
+		k, val, _ := strings.Cut(part, "=")
+		switch strings.ToUpper(k) {
+		case "FREQ":
+			r.freq = strings.ToUpper(val)
+		case "INTERVAL":
+			n, err := strconv.Atoi(val)
+			if err != nil || n < 1 {
+				return rule{}, false
+			}
+			r.interval = n
+		case "COUNT":
+			n, err := strconv.Atoi(val)
+			if err != nil || n < 1 {
+				return rule{}, false
+			}
+			r.count = n
+		case "UNTIL":
+			t, _, err := parseTime(property{params: map[string]string{}, value: val})
+			if err != nil {
+				return rule{}, false
+			}
+			if len(val) == len("20060102") {
+				// A date UNTIL includes that whole day
+				t = t.AddDate(0, 0, 1).Add(-time.Second)
+			}
+			r.until = &t
+		case "BYDAY":
+			for _, d := range strings.Split(val, ",") {
+				wd, ok := icalWeekdays[strings.ToUpper(d)]
+				if !ok {
+					return rule{}, false // e.g. 2MO, only meaningful for monthly rules
+				}
+				r.byDay = append(r.byDay, wd)
+			}
+		case "WKST":
+		default:
+			return rule{}, false
+		}
+	}
+	switch r.freq {
+	case "DAILY", "WEEKLY":
+	case "MONTHLY", "YEARLY":
+		if len(r.byDay) > 0 {
+			return rule{}, false
+		}
+	default:
+		return rule{}, false
+	}
+	if r.freq == "WEEKLY" && len(r.byDay) == 0 {
+		r.byDay = []time.Weekday{dtstart.Weekday()}
+	}
+	return r, true
+}
+
+// starts lists occurrence starts from dtstart up to limit (or UNTIL), in order.
+func (r rule) starts(dtstart, limit time.Time) []time.Time {
+	if r.until != nil && r.until.Before(limit) {
+		limit = *r.until
+	}
+	onDay := func(wd time.Weekday) bool {
+		if len(r.byDay) == 0 {
+			return true
+		}
+		for _, d := range r.byDay {
+			if d == wd {
+				return true
+			}
+		}
+		return false
+	}
+
+	var out []time.Time
+	add := func(t time.Time) bool {
+		if t.After(limit) || len(out) >= maxOccurrences || r.count > 0 && len(out) >= r.count {
+			return false
+		}
+		if !t.Before(dtstart) {
+			out = append(out, t)
+		}
+		return true
+	}
+
+	switch r.freq {
+	case "DAILY":
+		for i := 0; ; i += r.interval {
+			t := dtstart.AddDate(0, 0, i)
+			if !onDay(t.Weekday()) {
+				if t.After(limit) {
+					return out
+				}
+				continue
+			}
+			if !add(t) {
+				return out
+			}
+		}
+	case "WEEKLY":
+		// Weeks start on Monday; each week yields its BYDAY days in order
+		monday := dtstart.AddDate(0, 0, -((int(dtstart.Weekday()) + 6) % 7))
+		for w := 0; ; w += r.interval {
+			weekStart := monday.AddDate(0, 0, 7*w)
+			if weekStart.After(limit) {
+				return out
+			}
+			for offset := 0; offset < 7; offset++ {
+				t := weekStart.AddDate(0, 0, offset)
+				if onDay(t.Weekday()) && !add(t) {
+					return out
+				}
+			}
+		}
+	case "MONTHLY", "YEARLY":
+		for i := 0; ; i += r.interval {
+			t := dtstart.AddDate(0, i, 0)
+			if r.freq == "YEARLY" {
+				t = dtstart.AddDate(i, 0, 0)
+			}
+			// AddDate normalizes Jan 31 + 1 month into March; such months have no occurrence
+			if t.Day() != dtstart.Day() {
+				if t.After(limit) {
+					return out
+				}
+				continue
+			}
+			if !add(t) {
+				return out
+			}
+		}
+	}
+	return out
+}
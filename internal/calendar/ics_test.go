@@ -0,0 +1,111 @@
+package calendar
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+const sampleICS = "BEGIN:VCALENDAR\r\n" +
+	"VERSION:2.0\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"UID:standup\r\n" +
+	"SUMMARY:Standup\r\n" +
+	"DTSTART;TZID=Europe/Berlin:20260302T093000\r\n" +
+	"DTEND;TZID=Europe/Berlin:20260302T094500\r\n" +
+	"RRULE:FREQ=WEEKLY;BYDAY=MO,WE,FR;COUNT=6\r\n" +
+	"EXDATE;TZID=Europe/Berlin:20260304T093000\r\n" +
+	"BEGIN:VALARM\r\n" +
+	"SUMMARY:not the event\r\n" +
+	"TRIGGER:-PT5M\r\n" +
+	"END:VALARM\r\n" +
+	"END:VEVENT\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"UID:standup\r\n" +
+	"RECURRENCE-ID;TZID=Europe/Berlin:20260306T093000\r\n" +
+	"SUMMARY:Standup (moved)\r\n" +
+	"DTSTART;TZID=Europe/Berlin:20260306T110000\r\n" +
+	"DURATION:PT15M\r\n" +
+	"END:VEVENT\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"UID:deep\r\n" +
+	"SUMMARY:Deep work\\, no\r\n" +
+	"  meetings\r\n" +
+	"DTSTART:20260303T130000Z\r\n" +
+	"DTEND:20260303T150000Z\r\n" +
+	"END:VEVENT\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"UID:offsite\r\n" +
+	"SUMMARY:Offsite\r\n" +
+	"DTSTART;VALUE=DATE:20260305\r\n" +
+	"DTEND;VALUE=DATE:20260306\r\n" +
+	"END:VEVENT\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"UID:cancelled\r\n" +
+	"SUMMARY:Cancelled sync\r\n" +
+	"STATUS:CANCELLED\r\n" +
+	"DTSTART:20260303T100000Z\r\n" +
+	"DTEND:20260303T110000Z\r\n" +
+	"END:VEVENT\r\n" +
+	"END:VCALENDAR\r\n"
+
+func TestParseExpandsRecurrencesAndOverrides(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skip("no tzdata:", err)
+	}
+	from := time.Date(2026, 3, 2, 0, 0, 0, 0, berlin)
+	events, err := Parse(strings.NewReader(sampleICS), from, from.AddDate(0, 0, 7))
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+
+	var got []string
+	for _, e := range events {
+		got = append(got, e.Summary+" "+e.Start.Format("Mon 15:04"))
+	}
+	want := []string{
+		"Standup Mon 09:30",
+		"Deep work, no meetings Tue 13:00",
+		"Offsite Thu 00:00",
+		"Standup (moved) Fri 11:00",
+	}
+	if strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("Expected %q, got %q", want, got)
+	}
+	if !events[2].AllDay || events[3].End.Sub(events[3].Start) != 15*time.Minute {
+		t.Errorf("Unexpected all-day or duration handling: %+v", events)
+	}
+}
+
+func TestParseStopsRecurrenceAtCountAndWindow(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skip("no tzdata:", err)
+	}
+	// COUNT=6 counts the excluded 4 March too, so the last standup is Fri 13 March
+	from := time.Date(2026, 3, 14, 0, 0, 0, 0, berlin)
+	events, err := Parse(strings.NewReader(sampleICS), from, from.AddDate(0, 0, 14))
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if len(events) != 0 {
+		t.Errorf("Expected no occurrences, got %+v", events)
+	}
+}
+
+func TestParseDuration(t *testing.T) {
+	for in, want := range map[string]time.Duration{
+		"PT1H30M": 90 * time.Minute,
+		"P1D":     24 * time.Hour,
+		"-PT15M":  -15 * time.Minute,
+		"P1W":     7 * 24 * time.Hour,
+	} {
+		if got, err := parseDuration(in); err != nil || got != want {
+			t.Errorf("parseDuration(%q) = %v, %v; want %v", in, got, err, want)
+		}
+	}
+	if _, err := parseDuration("PT1H5"); err == nil {
+		t.Error("Expected an error for a trailing number")
+	}
+}
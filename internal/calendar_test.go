@@ -0,0 +1,74 @@
+package coach
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+	"time"
+)
+
+func writeICS(t *testing.T, events ...string) string {
+	t.Helper()
+	body := "BEGIN:VCALENDAR\r\nVERSION:2.0\r\n"
+	for _, e := range events {
+		body += e
+	}
+	body += "END:VCALENDAR\r\n"
+	path := filepath.Join(t.TempDir(), "work.ics")
+	if err := os.WriteFile(path, []byte(body), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func icsEvent(uid, summary string, start, end time.Time) string {
+	return fmt.Sprintf("BEGIN:VEVENT\r\nUID:%s\r\nSUMMARY:%s\r\nDTSTART:%s\r\nDTEND:%s\r\nEND:VEVENT\r\n",
+		uid, summary, start.UTC().Format("20060102T150405Z"), end.UTC().Format("20060102T150405Z"))
+}
+
+func TestCalendarStartsFocusAndListsMeetings(t *testing.T) {
+	now := time.Now().Truncate(time.Second)
+	path := writeICS(t,
+		icsEvent("deep", "Deep work", now.Add(-10*time.Minute), now.Add(50*time.Minute)),
+		icsEvent("sync", "Team sync", now.Add(2*time.Hour), now.Add(150*time.Minute)),
+		icsEvent("done", "Retro", now.Add(-2*time.Hour), now.Add(-time.Hour)),
+	)
+	state := &State{}
+	cal := NewCalendarSync(state, []string{path}, regexp.MustCompile(defaultCalendarFocusPattern), time.Minute)
+
+	cal.Refresh(context.Background())
+	time.Sleep(50 * time.Millisecond)
+
+	info := state.GetCurrentFocusInfo()
+	if !info.Focusing || info.FocusTimeLeft < 49*60 || info.FocusTimeLeft > 50*60 {
+		t.Fatalf("Expected focus until the event's end, got %+v", info)
+	}
+	if len(info.UpcomingMeetings) != 1 || info.UpcomingMeetings[0].Title != "Team sync" {
+		t.Errorf("Expected only the upcoming sync as a meeting, got %+v", info.UpcomingMeetings)
+	}
+
+	// Stopping early sticks: the next poll doesn't start the same event again
+	state.HandleFocusChange(false, 0, FocusLabel{}, "test")
+	cal.Refresh(context.Background())
+	time.Sleep(50 * time.Millisecond)
+	if state.GetCurrentFocusInfo().Focusing {
+		t.Error("A focus event that already started should not start again")
+	}
+}
+
+func TestCalendarKeepsLastGoodReadWhenSourceFails(t *testing.T) {
+	now := time.Now()
+	path := writeICS(t, icsEvent("sync", "Team sync", now.Add(time.Hour), now.Add(90*time.Minute)))
+	state := &State{}
+	cal := NewCalendarSync(state, []string{path}, regexp.MustCompile(defaultCalendarFocusPattern), time.Minute)
+	cal.Refresh(context.Background())
+
+	os.Remove(path)
+	cal.Refresh(context.Background())
+	if meetings := state.GetCurrentFocusInfo().UpcomingMeetings; len(meetings) != 1 {
+		t.Errorf("Expected the meeting from the last good read, got %+v", meetings)
+	}
+}
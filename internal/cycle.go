@@ -0,0 +1,171 @@
+package coach
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/charmbracelet/log"
+)
+
+// Cycle phases, as reported in FocusInfo.Phase. Empty means no cycle is running.
+const (
+	PhaseFocus      = "focus"
+	PhaseShortBreak = "short_break"
+	PhaseLongBreak  = "long_break"
+)
+
+// CycleConfig describes a pomodoro-style run of focus phases separated by breaks.
+type CycleConfig struct {
+	Focus      time.Duration
+	ShortBreak time.Duration
+	LongBreak  time.Duration
+	LongEvery  int // a long break follows every LongEvery-th focus phase
+	Cycles     int // number of focus phases before the cycle ends
+}
+
+// DefaultCycleConfig is the classic 25/5/15 pomodoro, four rounds long.
+func DefaultCycleConfig() CycleConfig {
+	return CycleConfig{
+		Focus:      25 * time.Minute,
+		ShortBreak: 5 * time.Minute,
+		LongBreak:  15 * time.Minute,
+		LongEvery:  4,
+		Cycles:     4,
+	}
+}
+
+// Validate rejects configs the engine can't run.
+func (c CycleConfig) Validate() error {
+	if c.Focus <= 0 || c.ShortBreak <= 0 || c.LongBreak <= 0 {
+		return fmt.Errorf("focus, short_break and long_break must be positive")
+	}
+	if c.LongEvery < 1 || c.Cycles < 1 {
+		return fmt.Errorf("long_every and cycles must be at least 1")
+	}
+	return nil
+}
+
+// cycleState is the running cycle. Focus phases are ordinary focus requests, so
+// the expiry timer ends them; breaks have their own timer.
+type cycleState struct {
+	config     CycleConfig
+	label      FocusLabel
+	phase      string
+	index      int // 1-based number of the current (or just finished) focus phase
+	breakEnds  time.Time
+	breakTimer *time.Timer
+}
+
+// StartCycle replaces any current focus with the first focus phase of a new cycle.
+func (s *State) StartCycle(config CycleConfig, label FocusLabel, by string) error {
+	if err := config.Validate(); err != nil {
+		return err
+	}
+	s.clearFocus("replaced", by)
+
+	s.mu.Lock()
+	s.cycle = &cycleState{config: config, label: label, phase: PhaseFocus, index: 1}
+	s.mu.Unlock()
+
+	log.Info("Cycle started", "focus", config.Focus, "cycles", config.Cycles)
+	s.SetLabeledFocusing(config.Focus, label)
+	go s.NotifyAllClients(s.GetCurrentFocusInfo())
+	return nil
+}
+
+// StopCycle ends the running cycle, stopping the current focus phase if there is one.
+// Returns false if no cycle was running.
+func (s *State) StopCycle(by string) bool {
+	s.mu.Lock()
+	running := s.cycle != nil
+	s.mu.Unlock()
+	if !running {
+		return false
+	}
+	s.clearFocus("stopped", by)
+	s.syncFocusHold()
+	log.Info("Cycle stopped", "by", by)
+	go s.NotifyAllClients(s.GetCurrentFocusInfo())
+	return true
+}
+
+// endCycleLocked forgets the running cycle and its break timer. Must be called with s.mu held.
+func (s *State) endCycleLocked() {
+	if s.cycle == nil {
+		return
+	}
+	if s.cycle.breakTimer != nil {
+		s.cycle.breakTimer.Stop()
+	}
+	s.cycle = nil
+}
+
+// yieldCycleBreakLocked ends a cycle that is between phases, so a focus started
+// from elsewhere during a break takes over instead of having the next phase
+// queued on top of it when the break ends. Must be called with s.mu held.
+func (s *State) yieldCycleBreakLocked() {
+	if s.cycle == nil || s.cycle.phase == PhaseFocus {
+		return
+	}
+	log.Info("Cycle stopped by a focus started during its break", "after", s.cycle.index)
+	s.endCycleLocked()
+}
+
+// advanceCycleLocked runs when every focus period has expired. In a cycle that
+// means a focus phase just ended: start the break that follows it, or finish
+// the cycle after the last one. Must be called with s.mu held.
+func (s *State) advanceCycleLocked() {
+	c := s.cycle
+	if c == nil || c.phase != PhaseFocus {
+		return
+	}
+	if c.index >= c.config.Cycles {
+		log.Info("Cycle finished", "cycles", c.index)
+		s.cycle = nil
+		return
+	}
+
+	c.phase, c.breakEnds = PhaseShortBreak, time.Now().Add(c.config.ShortBreak)
+	if c.index%c.config.LongEvery == 0 {
+		c.phase, c.breakEnds = PhaseLongBreak, time.Now().Add(c.config.LongBreak)
+	}
+	c.breakTimer = time.AfterFunc(time.Until(c.breakEnds), s.onBreakEnd)
+	log.Info("Cycle break started", "phase", c.phase, "after", c.index)
+}
+
+// onBreakEnd starts the next focus phase. It rechecks the cycle so that a stop
+// racing the timer doesn't resurrect it.
+func (s *State) onBreakEnd() {
+	s.mu.Lock()
+	c := s.cycle
+	if c == nil || c.phase == PhaseFocus {
+		s.mu.Unlock()
+		return
+	}
+	c.index++
+	c.phase = PhaseFocus
+	c.breakTimer = nil
+	config, label, index := c.config, c.label, c.index
+	s.mu.Unlock()
+
+	log.Info("Cycle focus phase started", "index", index)
+	s.SetLabeledFocusing(config.Focus, label)
+	go s.NotifyAllClients(s.GetCurrentFocusInfo())
+}
+
+// cyclePhaseLocked returns the current phase, its 1-based cycle index and the
+// time left in it. Must be called with s.mu held.
+func (s *State) cyclePhaseLocked() (phase string, index int, left time.Duration) {
+	c := s.cycle
+	if c == nil {
+		return "", 0, 0
+	}
+	if c.phase == PhaseFocus {
+		return c.phase, c.index, s.getTimeLeftLocked()
+	}
+	left = time.Until(c.breakEnds)
+	if left < 0 {
+		left = 0
+	}
+	return c.phase, c.index, left
+}
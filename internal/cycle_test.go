@@ -0,0 +1,144 @@
+package coach
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testCycleConfig has phases long enough never to end by themselves; the tests
+// end them with finishFocusPhase and finishBreak.
+func testCycleConfig() CycleConfig {
+	return CycleConfig{
+		Focus:      time.Hour,
+		ShortBreak: time.Hour,
+		LongBreak:  time.Hour,
+		LongEvery:  2,
+		Cycles:     3,
+	}
+}
+
+// finishFocusPhase cuts the running focus phase short and waits for the expiry
+// timer to close it out.
+func finishFocusPhase(t *testing.T, state *State, ended <-chan FocusEnded) {
+	t.Helper()
+	state.mu.Lock()
+	for i := range state.focusRequests {
+		state.focusRequests[i].EndTime = time.Now().Add(time.Millisecond)
+	}
+	state.scheduleExpiryTimer()
+	state.mu.Unlock()
+	waitEvent(t, ended)
+}
+
+// finishBreak ends the running break as if its timer had fired.
+func finishBreak(state *State) {
+	state.mu.Lock()
+	state.cycle.breakTimer.Stop()
+	state.mu.Unlock()
+	state.onBreakEnd()
+}
+
+func TestCycleRunsFocusAndBreakPhases(t *testing.T) {
+	state := &State{}
+	ended := make(chan FocusEnded, 4)
+	On(state.Events(), func(e FocusEnded) { ended <- e })
+
+	if err := state.StartCycle(testCycleConfig(), FocusLabel{Label: "deep"}, "test"); err != nil {
+		t.Fatalf("StartCycle: %v", err)
+	}
+	if info := state.GetCurrentFocusInfo(); info.Phase != PhaseFocus || info.CycleIndex != 1 || !info.Focusing {
+		t.Fatalf("Expected focus phase 1, got %+v", info)
+	}
+
+	finishFocusPhase(t, state, ended)
+	if info := state.GetCurrentFocusInfo(); info.Phase != PhaseShortBreak || info.Focusing {
+		t.Fatalf("Expected short break after phase 1, got %+v", info)
+	}
+
+	finishBreak(state)
+	if info := state.GetCurrentFocusInfo(); info.Phase != PhaseFocus || info.CycleIndex != 2 || !info.Focusing {
+		t.Fatalf("Expected focus phase 2, got %+v", info)
+	}
+
+	state.mu.Lock()
+	label := state.focusRequests[0].Label
+	state.mu.Unlock()
+	if label != "deep" {
+		t.Errorf("Focus phases should carry the cycle label, got %q", label)
+	}
+
+	finishFocusPhase(t, state, ended)
+	if info := state.GetCurrentFocusInfo(); info.Phase != PhaseLongBreak {
+		t.Fatalf("Expected long break after phase 2, got %+v", info)
+	}
+}
+
+func TestCycleFinishesAfterLastPhase(t *testing.T) {
+	state := &State{}
+	ended := make(chan FocusEnded, 1)
+	On(state.Events(), func(e FocusEnded) { ended <- e })
+
+	config := testCycleConfig()
+	config.Cycles = 1
+	state.StartCycle(config, FocusLabel{}, "test")
+
+	finishFocusPhase(t, state, ended)
+	if info := state.GetCurrentFocusInfo(); info.Phase != "" || info.Focusing {
+		t.Errorf("Expected the cycle to finish without a break, got %+v", info)
+	}
+}
+
+func TestStopCycleDuringBreak(t *testing.T) {
+	state := &State{}
+	ended := make(chan FocusEnded, 1)
+	On(state.Events(), func(e FocusEnded) { ended <- e })
+
+	state.StartCycle(testCycleConfig(), FocusLabel{}, "test")
+	finishFocusPhase(t, state, ended)
+	state.StopCycle("test")
+
+	// A break timer that already fired still runs after the stop
+	state.onBreakEnd()
+	if info := state.GetCurrentFocusInfo(); info.Phase != "" || info.Focusing {
+		t.Errorf("A stopped cycle should not start another focus phase, got %+v", info)
+	}
+}
+
+func TestFocusDuringBreakTakesOverFromTheCycle(t *testing.T) {
+	state := &State{}
+	ended := make(chan FocusEnded, 1)
+	On(state.Events(), func(e FocusEnded) { ended <- e })
+
+	state.StartCycle(testCycleConfig(), FocusLabel{Label: "deep"}, "test")
+	finishFocusPhase(t, state, ended)
+	state.HandleFocusChange(true, 600, FocusLabel{Label: "email"}, "user")
+
+	state.onBreakEnd()
+	info := state.GetCurrentFocusInfo()
+	if info.Phase != "" || !info.Focusing {
+		t.Fatalf("The manual focus should run without the cycle, got %+v", info)
+	}
+	state.mu.Lock()
+	requests := append([]FocusRequest(nil), state.focusRequests...)
+	state.mu.Unlock()
+	if len(requests) != 1 || requests[0].Label != "email" {
+		t.Errorf("The break end should not queue a cycle phase on the manual focus, got %+v", requests)
+	}
+}
+
+func TestFocusCycleEndpointRejectsBadConfig(t *testing.T) {
+	server := &Server{State: &State{}}
+
+	for _, body := range []string{"focus=abc", "cycles=0", "short_break=-5"} {
+		req := httptest.NewRequest(http.MethodPost, "/focus-cycle", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		rr := httptest.NewRecorder()
+		server.FocusCycleHandler(rr, req)
+		if rr.Code != http.StatusBadRequest {
+			t.Errorf("%q should be 400, got %d", body, rr.Code)
+		}
+	}
+}
@@ -10,19 +10,66 @@ import (
 
 // AgentLockRecord is the singleton record in the agent_lock collection.
 type AgentLockRecord struct {
-	RecordID     string `json:"id"`
-	ReleaseUntil string `json:"release_until"`
+	RecordID             string                  `json:"id"`
+	ReleaseUntil         string                  `json:"release_until"`
+	ReleaseScope         map[string]string       `json:"release_scope"`
+	PendingUnlockAt      string                  `json:"pending_unlock_at"`
+	PendingUnlockSeconds int                     `json:"pending_unlock_seconds"`
+	PendingUnlockMessage string                  `json:"pending_unlock_message"`
+	ReleaseGrants        map[string]ReleaseGrant `json:"release_grants"`
+	ReleaseEndedAt       string                  `json:"release_ended_at"`
 }
 
+// ReleaseGrant links a running release to the lock_decisions entry that
+// granted it (ID, empty if it wasn't journaled) and says since when that grant
+// has been in effect. Scheduled marks a lock policy window rather than a grant.
+// Superseded are the grants the release ran under before a later one extended
+// it, oldest first.
+type ReleaseGrant struct {
+	ID         string            `json:"id"`
+	Since      time.Time         `json:"since"`
+	Scheduled  bool              `json:"scheduled,omitempty"`
+	Superseded []SupersededGrant `json:"superseded,omitempty"`
+}
+
+// SupersededGrant is a grant a release ran under from Since until Until, when
+// a later grant extending the release took over.
+type SupersededGrant struct {
+	ID    string    `json:"id"`
+	Since time.Time `json:"since"`
+	Until time.Time `json:"until"`
+}
+
+// FullReleaseKey is the release_grants key of the full release; the other
+// keys are targets.
+const FullReleaseKey = "*"
+
 // agentLockCollection is the schema for the agent_lock collection.
 //
 //	release_until (text) — RFC3339 timestamp, or empty when the lock is engaged
+//	release_scope (json) — target releases: hostname or app package to RFC3339 end
+//	pending_unlock_at (text) — RFC3339 time a delayed unlock fires, or empty
+//	pending_unlock_seconds (number) — how long that unlock releases the lock
+//	pending_unlock_message (text) — why the user asked for it
+//	release_grants (json) — per running release (FullReleaseKey or a target), the
+//	                        grant that opened it and since when
+//	release_ended_at (text) — RFC3339 time a grant's release last ended, or empty
 var agentLockCollection = Collection{
 	Name: "agent_lock",
 	Type: "base",
-	Fields: append([]Field{
+	Fields: append(append([]Field{
 		{Name: "release_until", Type: "text", Required: false},
-	}, TimestampFields()...),
+	}, agentLockFields...), TimestampFields()...),
+}
+
+// agentLockFields are the agent_lock fields added after release_until.
+var agentLockFields = []Field{
+	{Name: "release_scope", Type: "json", Required: false},
+	{Name: "pending_unlock_at", Type: "text", Required: false},
+	{Name: "pending_unlock_seconds", Type: "number", Required: false},
+	{Name: "pending_unlock_message", Type: "text", Required: false},
+	{Name: "release_grants", Type: "json", Required: false},
+	{Name: "release_ended_at", Type: "text", Required: false},
 }
 
 // EnsureAgentLockCollection creates the agent_lock collection if it doesn't exist.
@@ -31,33 +78,140 @@ func (m *Manager) EnsureAgentLockCollection() (created bool, err error) {
 	return m.EnsureCollection(agentLockCollection)
 }
 
-// GetAgentReleaseUntil reads the singleton agent_lock record. Returns nil if no record
-// exists, the field is empty, or the parsed time is in the past.
-func (m *Manager) GetAgentReleaseUntil() (*time.Time, error) {
+// EnsureAgentLockFields adds fields introduced after the agent_lock collection
+// was first created. Idempotent.
+func (m *Manager) EnsureAgentLockFields() (added []string, err error) {
+	return m.EnsureFields("agent_lock", agentLockFields)
+}
+
+// GetAgentRelease reads the singleton agent_lock record: when the full release
+// ends, and when each target release ends. Releases already in the past are
+// left out; until is nil when nothing is fully released.
+func (m *Manager) GetAgentRelease() (until *time.Time, scope map[string]time.Time, err error) {
 	rec, err := m.fetchAgentLockRecord()
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
-	if rec == nil || rec.ReleaseUntil == "" {
-		return nil, nil
+	scope = map[string]time.Time{}
+	if rec == nil {
+		return nil, scope, nil
+	}
+	now := time.Now()
+	for target, v := range rec.ReleaseScope {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to parse release_scope[%q] %q: %w", target, v, err)
+		}
+		if now.Before(t) {
+			scope[target] = t
+		}
+	}
+	if rec.ReleaseUntil == "" {
+		return nil, scope, nil
 	}
 	t, err := time.Parse(time.RFC3339, rec.ReleaseUntil)
 	if err != nil {
-		return nil, fmt.Errorf("failed to parse release_until %q: %w", rec.ReleaseUntil, err)
+		return nil, nil, fmt.Errorf("failed to parse release_until %q: %w", rec.ReleaseUntil, err)
 	}
-	if !time.Now().Before(t) {
-		return nil, nil
+	if !now.Before(t) {
+		return nil, scope, nil
+	}
+	return &t, scope, nil
+}
+
+// GetReleaseGrants reads which grant opened each running release, keyed like
+// release_grants. Entries for releases that have since ended may be included.
+func (m *Manager) GetReleaseGrants() (map[string]ReleaseGrant, error) {
+	rec, err := m.fetchAgentLockRecord()
+	if err != nil || rec == nil {
+		return nil, err
+	}
+	return rec.ReleaseGrants, nil
+}
+
+// GetReleaseEndedAt reads when a grant's release last ended, or the zero time.
+func (m *Manager) GetReleaseEndedAt() (time.Time, error) {
+	rec, err := m.fetchAgentLockRecord()
+	if err != nil || rec == nil || rec.ReleaseEndedAt == "" {
+		return time.Time{}, err
+	}
+	t, err := time.Parse(time.RFC3339, rec.ReleaseEndedAt)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to parse release_ended_at %q: %w", rec.ReleaseEndedAt, err)
 	}
-	return &t, nil
+	return t, nil
 }
 
-// SetAgentReleaseUntil upserts the singleton record. Pass nil to engage the lock.
-func (m *Manager) SetAgentReleaseUntil(t *time.Time) error {
+// SetAgentRelease upserts the singleton record with the releases, the grants
+// behind them and when a grant's release last ended (zero if never). Pass nil
+// and empty maps to engage the lock.
+func (m *Manager) SetAgentRelease(until *time.Time, scope map[string]time.Time, grants map[string]ReleaseGrant, endedAt time.Time) error {
 	var s string
-	if t != nil {
-		s = t.UTC().Format(time.RFC3339)
+	if until != nil {
+		s = until.UTC().Format(time.RFC3339)
+	}
+	scoped := make(map[string]string, len(scope))
+	for target, t := range scope {
+		scoped[target] = t.UTC().Format(time.RFC3339)
+	}
+	if grants == nil {
+		grants = map[string]ReleaseGrant{}
+	}
+	var ended string
+	if !endedAt.IsZero() {
+		ended = endedAt.UTC().Format(time.RFC3339)
+	}
+	payload := map[string]any{"release_until": s, "release_scope": scoped, "release_grants": grants, "release_ended_at": ended}
+
+	rec, err := m.fetchAgentLockRecord()
+	if err != nil {
+		return err
+	}
+	if rec == nil {
+		_, err := m.createRecord("agent_lock", payload)
+		return err
+	}
+	return m.updateRecord("agent_lock", rec.RecordID, payload)
+}
+
+// PendingUnlock is a delayed unlock waiting to fire: at At the lock is
+// released for Duration.
+type PendingUnlock struct {
+	At          time.Time
+	Duration    time.Duration
+	UserMessage string
+}
+
+// GetPendingUnlock reads the delayed unlock from the singleton agent_lock
+// record, or nil if none is pending. One that already fired while the server
+// was down is still returned; the caller decides what is left of it.
+func (m *Manager) GetPendingUnlock() (*PendingUnlock, error) {
+	rec, err := m.fetchAgentLockRecord()
+	if err != nil || rec == nil || rec.PendingUnlockAt == "" {
+		return nil, err
+	}
+	at, err := time.Parse(time.RFC3339, rec.PendingUnlockAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse pending_unlock_at %q: %w", rec.PendingUnlockAt, err)
+	}
+	return &PendingUnlock{
+		At:          at,
+		Duration:    time.Duration(rec.PendingUnlockSeconds) * time.Second,
+		UserMessage: rec.PendingUnlockMessage,
+	}, nil
+}
+
+// SetPendingUnlock upserts the delayed unlock on the singleton record, leaving
+// the releases alone. Pass nil when it fires or is cancelled.
+func (m *Manager) SetPendingUnlock(p *PendingUnlock) error {
+	payload := map[string]any{"pending_unlock_at": "", "pending_unlock_seconds": 0, "pending_unlock_message": ""}
+	if p != nil {
+		payload = map[string]any{
+			"pending_unlock_at":      p.At.UTC().Format(time.RFC3339),
+			"pending_unlock_seconds": int(p.Duration / time.Second),
+			"pending_unlock_message": p.UserMessage,
+		}
 	}
-	payload := map[string]any{"release_until": s}
 
 	rec, err := m.fetchAgentLockRecord()
 	if err != nil {
@@ -0,0 +1,116 @@
+package db
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// focusBlocksCollection holds focus blocks booked for later. One row per block.
+//
+//	start_at (text) — RFC3339 timestamp the block starts
+//	end_at   (text) — RFC3339 timestamp the block ends
+//	label    (text) — what the block is for, copied onto the focus session
+//	project  (text) — project the block counts towards
+//	task     (text) — task the block works on
+//	status   (text) — "pending", "started", "cancelled" or "missed"
+var focusBlocksCollection = Collection{
+	Name: "focus_blocks",
+	Type: "base",
+	Fields: append([]Field{
+		{Name: "start_at", Type: "text", Required: true},
+		{Name: "end_at", Type: "text", Required: true},
+		{Name: "label", Type: "text", Required: false},
+		{Name: "project", Type: "text", Required: false},
+		{Name: "task", Type: "text", Required: false},
+		{Name: "status", Type: "text", Required: true},
+	}, TimestampFields()...),
+}
+
+// EnsureFocusBlocksCollection creates the focus_blocks collection if it doesn't
+// exist. Idempotent.
+func (m *Manager) EnsureFocusBlocksCollection() (created bool, err error) {
+	return m.EnsureCollection(focusBlocksCollection)
+}
+
+// FocusBlock is one booked block as stored in PB.
+type FocusBlock struct {
+	RecordID string `json:"id"`
+	StartAt  string `json:"start_at"`
+	EndAt    string `json:"end_at"`
+	Label    string `json:"label"`
+	Project  string `json:"project"`
+	Task     string `json:"task"`
+	Status   string `json:"status"`
+}
+
+// InsertFocusBlock books a pending block and returns its record ID.
+func (m *Manager) InsertFocusBlock(start, end time.Time, label, project, task string) (string, error) {
+	return m.createRecord("focus_blocks", map[string]any{
+		"start_at": start.UTC().Format(time.RFC3339),
+		"end_at":   end.UTC().Format(time.RFC3339),
+		"label":    label,
+		"project":  project,
+		"task":     task,
+		"status":   "pending",
+	})
+}
+
+// SetFocusBlockStatus moves a block out of pending.
+func (m *Manager) SetFocusBlockStatus(recordID, status string) error {
+	return m.updateRecord("focus_blocks", recordID, map[string]any{"status": status})
+}
+
+// GetPendingFocusBlocks returns every block still waiting to start, earliest first.
+func (m *Manager) GetPendingFocusBlocks() ([]FocusBlock, error) {
+	blocks := []FocusBlock{}
+	for page := 1; ; page++ {
+		u, err := url.Parse(fmt.Sprintf("%s/api/collections/focus_blocks/records", m.BaseURL))
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse URL: %w", err)
+		}
+		q := u.Query()
+		q.Set("filter", "status = 'pending'")
+		q.Set("sort", "start_at")
+		q.Set("perPage", "500")
+		q.Set("page", strconv.Itoa(page))
+		u.RawQuery = q.Encode()
+
+		req, err := http.NewRequest("GET", u.String(), nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create request: %w", err)
+		}
+
+		resp, err := m.DoRequest(req)
+		if err != nil {
+			return nil, fmt.Errorf("failed to send request: %w", err)
+		}
+
+		body, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read response body: %w", err)
+		}
+
+		if resp.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("focus_blocks fetch failed with status %d: %s", resp.StatusCode, string(body))
+		}
+
+		var result struct {
+			Items      []FocusBlock `json:"items"`
+			TotalPages int          `json:"totalPages"`
+		}
+		if err := json.Unmarshal(body, &result); err != nil {
+			return nil, fmt.Errorf("failed to parse response: %w", err)
+		}
+
+		blocks = append(blocks, result.Items...)
+		if page >= result.TotalPages {
+			return blocks, nil
+		}
+	}
+}
@@ -0,0 +1,127 @@
+package db
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// FocusPause is the singleton record in the focus_pause collection.
+type FocusPause struct {
+	RecordID         string `json:"id"`
+	PausedAt         string `json:"paused_at"`
+	RemainingSeconds int    `json:"remaining_seconds"`
+	EndsAt           string `json:"ends_at"`
+}
+
+// PausedSince returns the parsed paused_at, or nil when running or malformed.
+func (p FocusPause) PausedSince() *time.Time {
+	return parseOptionalTime(p.PausedAt)
+}
+
+// ResumedEnd returns the parsed ends_at, or nil when unset or malformed.
+func (p FocusPause) ResumedEnd() *time.Time {
+	return parseOptionalTime(p.EndsAt)
+}
+
+// focusPauseCollection is the schema for the focus_pause collection. The coach
+// records only know the planned span of a session, so a pause has to live
+// somewhere else to survive a restart.
+//
+//	paused_at         (text)   — RFC3339 timestamp of the pause, or empty while running
+//	remaining_seconds (number) — focus time left across the queue when paused
+//	ends_at           (text)   — RFC3339 end of a resumed session, pushed out by the
+//	                             time spent paused; empty when nothing was resumed
+var focusPauseCollection = Collection{
+	Name: "focus_pause",
+	Type: "base",
+	Fields: append([]Field{
+		{Name: "paused_at", Type: "text", Required: false},
+		{Name: "remaining_seconds", Type: "number", Required: false},
+		{Name: "ends_at", Type: "text", Required: false},
+	}, TimestampFields()...),
+}
+
+// EnsureFocusPauseCollection creates the focus_pause collection if it doesn't
+// exist. Idempotent.
+func (m *Manager) EnsureFocusPauseCollection() (created bool, err error) {
+	return m.EnsureCollection(focusPauseCollection)
+}
+
+// GetFocusPause reads the singleton focus_pause record. Returns the zero value
+// if no record exists.
+func (m *Manager) GetFocusPause() (FocusPause, error) {
+	endpoint := fmt.Sprintf("%s/api/collections/focus_pause/records?sort=-created&perPage=1", m.BaseURL)
+	req, err := http.NewRequest("GET", endpoint, nil)
+	if err != nil {
+		return FocusPause{}, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	resp, err := m.DoRequest(req)
+	if err != nil {
+		return FocusPause{}, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return FocusPause{}, fmt.Errorf("failed to read response body: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return FocusPause{}, fmt.Errorf("focus_pause fetch failed with status %d: %s", resp.StatusCode, string(body))
+	}
+
+	var result struct {
+		Items []FocusPause `json:"items"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return FocusPause{}, fmt.Errorf("failed to parse response: %w", err)
+	}
+
+	if len(result.Items) == 0 {
+		return FocusPause{}, nil
+	}
+	return result.Items[0], nil
+}
+
+// SetFocusPause upserts the singleton record. Pass nil times to clear them.
+func (m *Manager) SetFocusPause(pausedAt *time.Time, remaining time.Duration, endsAt *time.Time) error {
+	payload := map[string]any{
+		"paused_at":         formatOptionalTime(pausedAt),
+		"remaining_seconds": int(remaining / time.Second),
+		"ends_at":           formatOptionalTime(endsAt),
+	}
+
+	rec, err := m.GetFocusPause()
+	if err != nil {
+		return err
+	}
+	if rec.RecordID == "" {
+		_, err := m.createRecord("focus_pause", payload)
+		return err
+	}
+	return m.updateRecord("focus_pause", rec.RecordID, payload)
+}
+
+// formatOptionalTime renders t as RFC3339 UTC, or "" when t is nil.
+func formatOptionalTime(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+// parseOptionalTime parses an RFC3339 field, returning nil when empty or malformed.
+func parseOptionalTime(s string) *time.Time {
+	if s == "" {
+		return nil
+	}
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return nil
+	}
+	return &t
+}
@@ -0,0 +1,121 @@
+package db
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+)
+
+// focusRulesCollection holds recurring weekly focus windows. One row per rule.
+//
+//	weekdays         (text)   — comma-separated days the window repeats on, e.g. "mon,tue,wed"
+//	start            (text)   — "HH:MM" local time the window opens
+//	duration_seconds (number) — how long the window stays open
+//	exceptions       (text)   — comma-separated "YYYY-MM-DD" dates the window is skipped
+//	label            (text)   — what the window is for, copied onto the focus session
+//	project          (text)   — project the window counts towards
+//	task             (text)   — task the window works on
+//	enabled          (bool)   — disabled rules are kept but never open
+var focusRulesCollection = Collection{
+	Name: "focus_rules",
+	Type: "base",
+	Fields: append([]Field{
+		{Name: "weekdays", Type: "text", Required: true},
+		{Name: "start", Type: "text", Required: true},
+		{Name: "duration_seconds", Type: "number", Required: true},
+		{Name: "exceptions", Type: "text", Required: false},
+		{Name: "label", Type: "text", Required: false},
+		{Name: "project", Type: "text", Required: false},
+		{Name: "task", Type: "text", Required: false},
+		{Name: "enabled", Type: "bool", Required: false},
+	}, TimestampFields()...),
+}
+
+// EnsureFocusRulesCollection creates the focus_rules collection if it doesn't
+// exist. Idempotent.
+func (m *Manager) EnsureFocusRulesCollection() (created bool, err error) {
+	return m.EnsureCollection(focusRulesCollection)
+}
+
+// FocusRule is one recurring rule as stored in PB.
+type FocusRule struct {
+	RecordID        string `json:"id,omitempty"`
+	Weekdays        string `json:"weekdays"`
+	Start           string `json:"start"`
+	DurationSeconds int    `json:"duration_seconds"`
+	Exceptions      string `json:"exceptions"`
+	Label           string `json:"label"`
+	Project         string `json:"project"`
+	Task            string `json:"task"`
+	Enabled         bool   `json:"enabled"`
+}
+
+func (r FocusRule) payload() map[string]any {
+	return map[string]any{
+		"weekdays":         r.Weekdays,
+		"start":            r.Start,
+		"duration_seconds": r.DurationSeconds,
+		"exceptions":       r.Exceptions,
+		"label":            r.Label,
+		"project":          r.Project,
+		"task":             r.Task,
+		"enabled":          r.Enabled,
+	}
+}
+
+// InsertFocusRule stores a new rule and returns its record ID.
+func (m *Manager) InsertFocusRule(r FocusRule) (string, error) {
+	return m.createRecord("focus_rules", r.payload())
+}
+
+// UpdateFocusRule overwrites the rule with r.RecordID.
+func (m *Manager) UpdateFocusRule(r FocusRule) error {
+	return m.updateRecord("focus_rules", r.RecordID, r.payload())
+}
+
+// DeleteFocusRule removes a rule.
+func (m *Manager) DeleteFocusRule(recordID string) error {
+	return m.deleteRecord("focus_rules", recordID)
+}
+
+// GetFocusRules returns every rule, oldest first.
+func (m *Manager) GetFocusRules() ([]FocusRule, error) {
+	u, err := url.Parse(fmt.Sprintf("%s/api/collections/focus_rules/records", m.BaseURL))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse URL: %w", err)
+	}
+	q := u.Query()
+	q.Set("sort", "created")
+	q.Set("perPage", "500")
+	u.RawQuery = q.Encode()
+
+	req, err := http.NewRequest("GET", u.String(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	resp, err := m.DoRequest(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response body: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("focus_rules fetch failed with status %d: %s", resp.StatusCode, string(body))
+	}
+
+	var result struct {
+		Items []FocusRule `json:"items"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, fmt.Errorf("failed to parse response: %w", err)
+	}
+	return result.Items, nil
+}
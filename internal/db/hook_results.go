@@ -0,0 +1,139 @@
+package db
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// hookResultsCollection holds what hooks produced for the user. One row per result.
+//
+//	hook_id (text) — hook that produced the result
+//	content (text) — the result itself, e.g. a nudge or a summary
+var hookResultsCollection = Collection{
+	Name: "hook_results",
+	Type: "base",
+	Fields: append([]Field{
+		{Name: "hook_id", Type: "text", Required: true},
+		{Name: "content", Type: "text", Required: true},
+	}, TimestampFields()...),
+}
+
+// hookResultSeenCollection records which client has seen which result, so each
+// device gets every result exactly once. One row per (result, client).
+//
+//	result (text) — hook_results record ID
+//	client (text) — ID the client chose for itself, e.g. "phone" or "laptop-firefox"
+var hookResultSeenCollection = Collection{
+	Name: "hook_result_seen",
+	Type: "base",
+	Fields: append([]Field{
+		{Name: "result", Type: "text", Required: true},
+		{Name: "client", Type: "text", Required: true},
+	}, TimestampFields()...),
+	Indexes: []string{"CREATE UNIQUE INDEX `idx_hook_result_seen` ON `hook_result_seen` (`result`, `client`)"},
+}
+
+// EnsureHookResultsCollections creates the hook_results and hook_result_seen
+// collections if they don't exist. Idempotent.
+func (m *Manager) EnsureHookResultsCollections() (created []string, err error) {
+	for _, c := range []Collection{hookResultsCollection, hookResultSeenCollection} {
+		ok, err := m.EnsureCollection(c)
+		if err != nil {
+			return created, err
+		}
+		if ok {
+			created = append(created, c.Name)
+		}
+	}
+	return created, nil
+}
+
+// HookResult is one stored hook result.
+type HookResult struct {
+	RecordID string `json:"id"`
+	HookID   string `json:"hook_id"`
+	Content  string `json:"content"`
+	Created  string `json:"created"`
+}
+
+// InsertHookResult stores a result. Created is stamped locally; PB's own stamp
+// differs by the request latency at most.
+func (m *Manager) InsertHookResult(hookID, content string) (HookResult, error) {
+	id, err := m.createRecord("hook_results", map[string]any{
+		"hook_id": hookID,
+		"content": content,
+	})
+	if err != nil {
+		return HookResult{}, err
+	}
+	return HookResult{
+		RecordID: id,
+		HookID:   hookID,
+		Content:  content,
+		Created:  time.Now().UTC().Format(pbTimeLayout),
+	}, nil
+}
+
+// GetRecentHookResults returns up to limit results, newest first.
+func (m *Manager) GetRecentHookResults(limit int) ([]HookResult, error) {
+	var result struct {
+		Items []HookResult `json:"items"`
+	}
+	if err := m.listRecords("hook_results", "", "-created", limit, &result); err != nil {
+		return nil, err
+	}
+	return result.Items, nil
+}
+
+// GetHookResultsBefore returns up to limit results older than the one created
+// at created with id, newest first; an empty created starts from the newest.
+func (m *Manager) GetHookResultsBefore(created, id string, limit int) ([]HookResult, error) {
+	filter := ""
+	if created != "" {
+		c := quoteFilterValue(created)
+		filter = fmt.Sprintf("(created < %s || (created = %s && id < %s))", c, c, quoteFilterValue(id))
+	}
+	var result struct {
+		Items []HookResult `json:"items"`
+	}
+	if err := m.listRecords("hook_results", filter, "-created,-id", limit, &result); err != nil {
+		return nil, err
+	}
+	return result.Items, nil
+}
+
+// GetSeenHookResults returns which of resultIDs client has already seen.
+func (m *Manager) GetSeenHookResults(client string, resultIDs []string) (map[string]bool, error) {
+	seen := map[string]bool{}
+	if len(resultIDs) == 0 {
+		return seen, nil
+	}
+	ids := make([]string, len(resultIDs))
+	for i, id := range resultIDs {
+		ids[i] = "result = " + quoteFilterValue(id)
+	}
+	filter := fmt.Sprintf("client = %s && (%s)", quoteFilterValue(client), strings.Join(ids, " || "))
+
+	var result struct {
+		Items []struct {
+			Result string `json:"result"`
+		} `json:"items"`
+	}
+	if err := m.listRecords("hook_result_seen", filter, "", len(resultIDs), &result); err != nil {
+		return nil, err
+	}
+	for _, item := range result.Items {
+		seen[item.Result] = true
+	}
+	return seen, nil
+}
+
+// MarkHookResultSeen records that client has seen a result.
+func (m *Manager) MarkHookResultSeen(resultID, client string) error {
+	_, err := m.createRecord("hook_result_seen", map[string]any{
+		"result": resultID,
+		"client": client,
+	})
+	return err
+}
@@ -0,0 +1,114 @@
+package db
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+)
+
+// hooksCollection holds the configuration of each Go hook. One row per hook,
+// keyed by hook_id; hooks without a row run with their defaults, disabled.
+//
+//	hook_id   (text) — ID of the hook implementation this configures
+//	enabled   (bool) — disabled hooks never fire on schedule
+//	trigger   (text) — "scheduled"
+//	first_run (text) — "HH:MM" local time of the first run each day
+//	last_run  (text) — "HH:MM" local time of the last run each day
+//	frequency (text) — Go duration between runs, e.g. "30m" or "2h"
+//	params    (json) — hook-specific parameters, string to string
+var hooksCollection = Collection{
+	Name: "hooks",
+	Type: "base",
+	Fields: append([]Field{
+		{Name: "hook_id", Type: "text", Required: true},
+		{Name: "enabled", Type: "bool", Required: false},
+		{Name: "trigger", Type: "text", Required: false},
+		{Name: "first_run", Type: "text", Required: false},
+		{Name: "last_run", Type: "text", Required: false},
+		{Name: "frequency", Type: "text", Required: false},
+		{Name: "params", Type: "json", Required: false},
+	}, TimestampFields()...),
+	Indexes: []string{"CREATE UNIQUE INDEX `idx_hooks_hook_id` ON `hooks` (`hook_id`)"},
+}
+
+// EnsureHooksCollection creates the hooks collection if it doesn't exist. Idempotent.
+func (m *Manager) EnsureHooksCollection() (created bool, err error) {
+	return m.EnsureCollection(hooksCollection)
+}
+
+// HookConfig is one hook's configuration as stored in PB.
+type HookConfig struct {
+	RecordID  string            `json:"id,omitempty"`
+	HookID    string            `json:"hook_id"`
+	Enabled   bool              `json:"enabled"`
+	Trigger   string            `json:"trigger"`
+	FirstRun  string            `json:"first_run"`
+	LastRun   string            `json:"last_run"`
+	Frequency string            `json:"frequency"`
+	Params    map[string]string `json:"params"`
+}
+
+func (c HookConfig) payload() map[string]any {
+	params := c.Params
+	if params == nil {
+		params = map[string]string{}
+	}
+	return map[string]any{
+		"hook_id":   c.HookID,
+		"enabled":   c.Enabled,
+		"trigger":   c.Trigger,
+		"first_run": c.FirstRun,
+		"last_run":  c.LastRun,
+		"frequency": c.Frequency,
+		"params":    params,
+	}
+}
+
+// SaveHookConfig inserts c, or overwrites the row with c.RecordID. Returns the record ID.
+func (m *Manager) SaveHookConfig(c HookConfig) (string, error) {
+	if c.RecordID == "" {
+		return m.createRecord("hooks", c.payload())
+	}
+	return c.RecordID, m.updateRecord("hooks", c.RecordID, c.payload())
+}
+
+// GetHookConfigs returns every stored hook configuration.
+func (m *Manager) GetHookConfigs() ([]HookConfig, error) {
+	u, err := url.Parse(fmt.Sprintf("%s/api/collections/hooks/records", m.BaseURL))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse URL: %w", err)
+	}
+	q := u.Query()
+	q.Set("perPage", "500")
+	u.RawQuery = q.Encode()
+
+	req, err := http.NewRequest("GET", u.String(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	resp, err := m.DoRequest(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response body: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("hooks fetch failed with status %d: %s", resp.StatusCode, string(body))
+	}
+
+	var result struct {
+		Items []HookConfig `json:"items"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, fmt.Errorf("failed to parse response: %w", err)
+	}
+	return result.Items, nil
+}
@@ -6,27 +6,52 @@ import (
 	"io"
 	"net/http"
 	"net/url"
+	"strings"
 	"time"
 )
 
 // lockDecisionsCollection records every agent-lock decision: a plea and the
-// coach's answer. One row per decision.
+// coach's answer. One row per decision. Breaking a committed focus session is
+// journaled here too, since it is the same kind of plea.
 //
-//	kind             — "grant", "override", or "denial"
-//	source           — who reported it (currently always "agent")
+//	kind             — "grant", "override", "denial", "broken_commitment",
+//	                   "delayed" (a delayed unlock the user waited out), "turn"
+//	                   (an exchange in a plea that decided nothing yet), or the
+//	                   end of a release: "engage" (cut short) or "expired" (ran out)
+//	source           — who reported it ("agent" for lock decisions, "schedule"
+//	                   for lock policy windows, "user" for delayed unlocks; the
+//	                   focus client for broken commitments)
 //	user_message     — what the user said, verbatim
 //	agent_message    — what the coach replied
-//	duration_seconds — release length for grant/override; 0 for denial
+//	duration_seconds — release length for grant/override/delayed; committed
+//	                   focus abandoned for broken_commitment; 0 for denial
+//	grant_id         — for engage/expired, the id of the entry whose release
+//	                   ended (empty if it wasn't journaled); a release extended
+//	                   by later grants ends once per grant
+//	actual_seconds   — for engage/expired, how long the release ran under that
+//	                   grant, by the clock: time focus held it back counts too
+//	thread_id        — the plea conversation the entry belongs to, set by the
+//	                   judge so an override can be traced to the denials before it
+//	cooldown_bypassed — for override, whether it released the lock within
+//	                    the grant cooldown, which refuses plain grants
 var lockDecisionsCollection = Collection{
 	Name: "lock_decisions",
 	Type: "base",
-	Fields: append([]Field{
+	Fields: append(append([]Field{
 		{Name: "kind", Type: "text", Required: true},
 		{Name: "source", Type: "text", Required: false},
 		{Name: "user_message", Type: "text", Required: false},
 		{Name: "agent_message", Type: "text", Required: false},
 		{Name: "duration_seconds", Type: "number", Required: false},
-	}, TimestampFields()...),
+	}, lockDecisionFields...), TimestampFields()...),
+}
+
+// lockDecisionFields are the lock_decisions fields added after duration_seconds.
+var lockDecisionFields = []Field{
+	{Name: "grant_id", Type: "text", Required: false},
+	{Name: "actual_seconds", Type: "number", Required: false},
+	{Name: "thread_id", Type: "text", Required: false},
+	{Name: "cooldown_bypassed", Type: "bool", Required: false},
 }
 
 // EnsureLockDecisionsCollection creates the lock_decisions collection if it
@@ -35,25 +60,58 @@ func (m *Manager) EnsureLockDecisionsCollection() (created bool, err error) {
 	return m.EnsureCollection(lockDecisionsCollection)
 }
 
+// EnsureLockDecisionFields adds fields introduced after the lock_decisions
+// collection was first created. Idempotent.
+func (m *Manager) EnsureLockDecisionFields() (added []string, err error) {
+	return m.EnsureFields("lock_decisions", lockDecisionFields)
+}
+
 // InsertLockDecision writes one decision row.
 func (m *Manager) InsertLockDecision(kind, source, userMessage, agentMessage string, durationSeconds int) error {
-	_, err := m.createRecord("lock_decisions", map[string]any{
-		"kind":             kind,
-		"source":           source,
-		"user_message":     userMessage,
-		"agent_message":    agentMessage,
-		"duration_seconds": durationSeconds,
+	return m.InsertLockDecisionEntry(LockDecision{
+		Kind:            kind,
+		Source:          source,
+		UserMessage:     userMessage,
+		AgentMessage:    agentMessage,
+		DurationSeconds: durationSeconds,
 	})
+}
+
+// InsertLockDecisionEntry writes one decision row, under d.ID when it is set
+// (see NewRecordID) so the release it grants can point back to it before the
+// write lands.
+func (m *Manager) InsertLockDecisionEntry(d LockDecision) error {
+	data := map[string]any{
+		"kind":              d.Kind,
+		"source":            d.Source,
+		"user_message":      d.UserMessage,
+		"agent_message":     d.AgentMessage,
+		"duration_seconds":  d.DurationSeconds,
+		"grant_id":          d.GrantID,
+		"actual_seconds":    d.ActualSeconds,
+		"thread_id":         d.ThreadID,
+		"cooldown_bypassed": d.CooldownBypassed,
+	}
+	if d.ID != "" {
+		data["id"] = d.ID
+	}
+	_, err := m.createRecord("lock_decisions", data)
 	return err
 }
 
 // LockDecision is one decision row as stored in PB.
 type LockDecision struct {
-	Kind            string `json:"kind"`
-	UserMessage     string `json:"user_message"`
-	AgentMessage    string `json:"agent_message"`
-	DurationSeconds int    `json:"duration_seconds"`
-	Created         string `json:"created"`
+	ID               string `json:"id"`
+	Kind             string `json:"kind"`
+	Source           string `json:"source"`
+	UserMessage      string `json:"user_message"`
+	AgentMessage     string `json:"agent_message"`
+	DurationSeconds  int    `json:"duration_seconds"`
+	GrantID          string `json:"grant_id"`
+	ActualSeconds    int    `json:"actual_seconds"`
+	ThreadID         string `json:"thread_id"`
+	CooldownBypassed bool   `json:"cooldown_bypassed"`
+	Created          string `json:"created"`
 }
 
 // GetTodayLockDecisions returns today's decisions, oldest first. "Today" is the
@@ -99,3 +157,85 @@ func (m *Manager) GetTodayLockDecisions() ([]LockDecision, error) {
 	}
 	return result.Items, nil
 }
+
+// LockDecisionQuery selects decisions for the history API, newest first. Zero
+// fields don't filter.
+type LockDecisionQuery struct {
+	From    time.Time // created at or after
+	To      time.Time // created before
+	Kinds   []string
+	Sources []string
+	Search  string // substring of user_message or agent_message, any case
+	// AfterCreated and AfterID continue a listing after the decision with that
+	// created time and id, the last one of the previous page.
+	AfterCreated string
+	AfterID      string
+	Limit        int
+}
+
+// pbTime formats t the way PB stores created, for comparing against it.
+func pbTime(t time.Time) string {
+	return t.UTC().Format(pbTimeLayout)
+}
+
+// CreatedAt parses Created, which PB writes in UTC.
+func (d LockDecision) CreatedAt() (time.Time, error) {
+	return time.Parse(pbTimeLayout, d.Created)
+}
+
+// filter builds the PB filter for the query.
+func (q LockDecisionQuery) filter() string {
+	var clauses []string
+	if !q.From.IsZero() {
+		clauses = append(clauses, "created >= "+quoteFilterValue(pbTime(q.From)))
+	}
+	if !q.To.IsZero() {
+		clauses = append(clauses, "created < "+quoteFilterValue(pbTime(q.To)))
+	}
+	for _, f := range []struct {
+		field  string
+		values []string
+	}{{"kind", q.Kinds}, {"source", q.Sources}} {
+		if len(f.values) == 0 {
+			continue
+		}
+		var alts []string
+		for _, v := range f.values {
+			alts = append(alts, f.field+" = "+quoteFilterValue(v))
+		}
+		clauses = append(clauses, "("+strings.Join(alts, " || ")+")")
+	}
+	if q.Search != "" {
+		v := quoteFilterValue(q.Search)
+		clauses = append(clauses, fmt.Sprintf("(user_message ~ %s || agent_message ~ %s)", v, v))
+	}
+	if q.AfterCreated != "" {
+		c, id := quoteFilterValue(q.AfterCreated), quoteFilterValue(q.AfterID)
+		clauses = append(clauses, fmt.Sprintf("(created < %s || (created = %s && id < %s))", c, c, id))
+	}
+	return strings.Join(clauses, " && ")
+}
+
+// QueryLockDecisions returns up to q.Limit decisions matching q, newest first
+// (ties broken by id, so a cursor never skips or repeats one).
+func (m *Manager) QueryLockDecisions(q LockDecisionQuery) ([]LockDecision, error) {
+	var result struct {
+		Items []LockDecision `json:"items"`
+	}
+	if err := m.listRecords("lock_decisions", q.filter(), "-created,-id", q.Limit, &result); err != nil {
+		return nil, err
+	}
+	return result.Items, nil
+}
+
+// GetLockThread returns the entries of one plea conversation, oldest first.
+func (m *Manager) GetLockThread(threadID string) ([]LockDecision, error) {
+	var result struct {
+		Items []LockDecision `json:"items"`
+	}
+	filter := "thread_id = " + quoteFilterValue(threadID)
+	if err := m.listRecords("lock_decisions", filter, "created,id", 500, &result); err != nil {
+		return nil, err
+	}
+	return result.Items, nil
+}
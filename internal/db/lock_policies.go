@@ -0,0 +1,73 @@
+package db
+
+// lockPoliciesCollection holds time-of-day windows in which the agent lock is
+// released without asking. One row per policy.
+//
+//	name     (text) — what the window is, e.g. "weekday evenings"
+//	weekdays (text) — comma-separated days the window opens on, e.g. "mon,tue,wed"
+//	start    (text) — "HH:MM" local time the window opens
+//	end      (text) — "HH:MM" local time it closes; at or before start runs past midnight, "24:00" is midnight
+//	enabled  (bool) — disabled policies are kept but never open
+var lockPoliciesCollection = Collection{
+	Name: "lock_policies",
+	Type: "base",
+	Fields: append([]Field{
+		{Name: "name", Type: "text", Required: false},
+		{Name: "weekdays", Type: "text", Required: true},
+		{Name: "start", Type: "text", Required: true},
+		{Name: "end", Type: "text", Required: true},
+		{Name: "enabled", Type: "bool", Required: false},
+	}, TimestampFields()...),
+}
+
+// EnsureLockPoliciesCollection creates the lock_policies collection if it
+// doesn't exist. Idempotent.
+func (m *Manager) EnsureLockPoliciesCollection() (created bool, err error) {
+	return m.EnsureCollection(lockPoliciesCollection)
+}
+
+// LockPolicy is one policy as stored in PB.
+type LockPolicy struct {
+	RecordID string `json:"id,omitempty"`
+	Name     string `json:"name"`
+	Weekdays string `json:"weekdays"`
+	Start    string `json:"start"`
+	End      string `json:"end"`
+	Enabled  bool   `json:"enabled"`
+}
+
+func (p LockPolicy) payload() map[string]any {
+	return map[string]any{
+		"name":     p.Name,
+		"weekdays": p.Weekdays,
+		"start":    p.Start,
+		"end":      p.End,
+		"enabled":  p.Enabled,
+	}
+}
+
+// InsertLockPolicy stores a new policy and returns its record ID.
+func (m *Manager) InsertLockPolicy(p LockPolicy) (string, error) {
+	return m.createRecord("lock_policies", p.payload())
+}
+
+// UpdateLockPolicy overwrites the policy with p.RecordID.
+func (m *Manager) UpdateLockPolicy(p LockPolicy) error {
+	return m.updateRecord("lock_policies", p.RecordID, p.payload())
+}
+
+// DeleteLockPolicy removes a policy.
+func (m *Manager) DeleteLockPolicy(recordID string) error {
+	return m.deleteRecord("lock_policies", recordID)
+}
+
+// GetLockPolicies returns every policy, oldest first.
+func (m *Manager) GetLockPolicies() ([]LockPolicy, error) {
+	var result struct {
+		Items []LockPolicy `json:"items"`
+	}
+	if err := m.listRecords("lock_policies", "", "created", 500, &result); err != nil {
+		return nil, err
+	}
+	return result.Items, nil
+}
@@ -7,6 +7,7 @@ import (
 	"net/http"
 	"net/url"
 	"os"
+	"strconv"
 	"strings"
 	"time"
 
@@ -35,10 +36,109 @@ type ErrorResponse struct {
 	Data    any    `json:"data"`
 }
 
-// FocusRecord represents a focus session record
+// pbTimeLayout is how PocketBase renders date fields.
+const pbTimeLayout = "2006-01-02 15:04:05.000Z"
+
+// FocusRecord represents a focus session record. Duration is the planned
+// length; ActualDuration, EndReason and StoppedBy are set once the session
+// is closed out, and EndReason stays empty while it may still be running.
 type FocusRecord struct {
-	Timestamp time.Time `json:"timestamp"`
-	Duration  int       `json:"duration"`
+	ID             string    `json:"id"`
+	Timestamp      time.Time `json:"timestamp"`
+	Duration       int       `json:"duration"`
+	Label          string    `json:"label"`
+	Project        string    `json:"project"`
+	Task           string    `json:"task"`
+	ActualDuration int       `json:"actual_duration"`
+	EndReason      string    `json:"end_reason"`
+	StoppedBy      string    `json:"stopped_by"`
+	Committed      bool      `json:"committed"`
+}
+
+// FocusedSeconds is how long the session actually lasted: the actual duration
+// once closed out, the planned one before that.
+func (r FocusRecord) FocusedSeconds() int {
+	if r.EndReason == "" {
+		return r.Duration
+	}
+	return r.ActualDuration
+}
+
+// focusRecordItem is a coach record as PocketBase returns it.
+type focusRecordItem struct {
+	ID             string `json:"id"`
+	Timestamp      string `json:"timestamp"`
+	Duration       int    `json:"duration"`
+	Label          string `json:"label"`
+	Project        string `json:"project"`
+	Task           string `json:"task"`
+	ActualDuration int    `json:"actual_duration"`
+	EndReason      string `json:"end_reason"`
+	StoppedBy      string `json:"stopped_by"`
+	Committed      bool   `json:"committed"`
+}
+
+func (item focusRecordItem) toRecord() (FocusRecord, error) {
+	ts, err := time.Parse(pbTimeLayout, item.Timestamp)
+	if err != nil {
+		return FocusRecord{}, fmt.Errorf("failed to parse timestamp: %w", err)
+	}
+	return FocusRecord{
+		ID:             item.ID,
+		Timestamp:      ts,
+		Duration:       item.Duration,
+		Label:          item.Label,
+		Project:        item.Project,
+		Task:           item.Task,
+		ActualDuration: item.ActualDuration,
+		EndReason:      item.EndReason,
+		StoppedBy:      item.StoppedBy,
+		Committed:      item.Committed,
+	}, nil
+}
+
+// focusRecordFields are the optional coach fields added after the original
+// timestamp/duration pair.
+//
+//	label           (text)   — free-form name of the session
+//	project         (text)   — project the session counts towards
+//	task            (text)   — reference to a task (e.g. a Dimaist task ID)
+//	actual_duration (number) — seconds actually focused, set when the session ends
+//	end_reason      (text)   — "expired", "stopped" or "replaced"; empty while running
+//	stopped_by      (text)   — who stopped or replaced the session
+//	committed       (bool)   — the session could only be cancelled with an override
+var focusRecordFields = []Field{
+	{Name: "label", Type: "text", Required: false},
+	{Name: "project", Type: "text", Required: false},
+	{Name: "task", Type: "text", Required: false},
+	{Name: "actual_duration", Type: "number", Required: false},
+	{Name: "end_reason", Type: "text", Required: false},
+	{Name: "stopped_by", Type: "text", Required: false},
+	{Name: "committed", Type: "bool", Required: false},
+}
+
+// FocusRecordFields returns the optional fields for schemas that declare the coach collection.
+func FocusRecordFields() []Field {
+	return append([]Field(nil), focusRecordFields...)
+}
+
+// EnsureFocusRecordFields adds the optional fields to the coach collection if
+// it predates them. Idempotent.
+func (m *Manager) EnsureFocusRecordFields() (added []string, err error) {
+	return m.EnsureFields("coach", focusRecordFields)
+}
+
+// HistoryFilter narrows GetFocusHistory to matching sessions. Empty fields match anything.
+type HistoryFilter struct {
+	Label   string
+	Project string
+	Task    string
+}
+
+// quoteFilterValue renders s as a single-quoted PocketBase filter literal.
+func quoteFilterValue(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	return "'" + strings.ReplaceAll(s, "'", `\'`) + "'"
 }
 
 // Manager handles database operations and authentication
@@ -157,82 +257,79 @@ func (m *Manager) GetTodayFocusCount() (int, error) {
 	return result.TotalItems, nil
 }
 
-// GetActiveFocus returns the remaining duration if a focus session is still active (timestamp + duration > now).
-// Returns 0 if no active session exists.
-func (m *Manager) GetActiveFocus() (time.Duration, error) {
-	log.Info("Checking for active focus session")
+// GetLatestFocusRecords returns the n most recent focus records, newest first.
+// A record with an empty EndReason has not been closed out yet and may still be running.
+func (m *Manager) GetLatestFocusRecords(n int) ([]FocusRecord, error) {
+	log.Info("Checking for latest focus records")
 
 	baseEndpoint := fmt.Sprintf("%s/api/collections/coach/records", m.BaseURL)
 	u, err := url.Parse(baseEndpoint)
 	if err != nil {
-		return 0, fmt.Errorf("failed to parse URL: %w", err)
+		return nil, fmt.Errorf("failed to parse URL: %w", err)
 	}
 
 	q := u.Query()
 	q.Set("sort", "-timestamp")
-	q.Set("perPage", "1")
+	q.Set("perPage", strconv.Itoa(n))
 	u.RawQuery = q.Encode()
 
 	req, err := http.NewRequest("GET", u.String(), nil)
 	if err != nil {
-		return 0, fmt.Errorf("failed to create request: %w", err)
+		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
 
 	req.Header.Set("Authorization", m.AuthToken)
 
 	resp, err := m.Client.Do(req)
 	if err != nil {
-		return 0, fmt.Errorf("failed to send request: %w", err)
+		return nil, fmt.Errorf("failed to send request: %w", err)
 	}
 	defer resp.Body.Close()
 
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return 0, fmt.Errorf("failed to read response body: %w", err)
+		return nil, fmt.Errorf("failed to read response body: %w", err)
 	}
 
 	if resp.StatusCode != http.StatusOK {
 		var errResp ErrorResponse
 		if err := json.Unmarshal(body, &errResp); err != nil {
-			return 0, fmt.Errorf("request failed with status %d: %s", resp.StatusCode, string(body))
+			return nil, fmt.Errorf("request failed with status %d: %s", resp.StatusCode, string(body))
 		}
-		return 0, fmt.Errorf("request failed: %s", errResp.Message)
+		return nil, fmt.Errorf("request failed: %s", errResp.Message)
 	}
 
 	var result struct {
-		Items []struct {
-			Timestamp string `json:"timestamp"`
-			Duration  int    `json:"duration"`
-		} `json:"items"`
+		Items []focusRecordItem `json:"items"`
 	}
 
 	if err := json.Unmarshal(body, &result); err != nil {
-		return 0, fmt.Errorf("failed to parse response: %w", err)
-	}
-
-	if len(result.Items) == 0 {
-		return 0, nil
-	}
-
-	item := result.Items[0]
-	ts, err := time.Parse("2006-01-02 15:04:05.000Z", item.Timestamp)
-	if err != nil {
-		return 0, fmt.Errorf("failed to parse timestamp: %w", err)
+		return nil, fmt.Errorf("failed to parse response: %w", err)
 	}
 
-	endTime := ts.Add(time.Duration(item.Duration) * time.Second)
-	remaining := time.Until(endTime)
-	if remaining <= 0 {
-		return 0, nil
+	records := make([]FocusRecord, 0, len(result.Items))
+	for _, item := range result.Items {
+		record, err := item.toRecord()
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, record)
 	}
+	return records, nil
+}
 
-	log.Info("Found active focus session", "remaining", remaining)
-	return remaining, nil
+// CloseFocusRecord records how the session saved under id actually ended.
+func (m *Manager) CloseFocusRecord(id string, actual time.Duration, reason, stoppedBy string) error {
+	return m.updateRecord("coach", id, map[string]any{
+		"actual_duration": int(actual / time.Second),
+		"end_reason":      reason,
+		"stopped_by":      stoppedBy,
+	})
 }
 
-// GetFocusHistory returns focus records for the last N days
-func (m *Manager) GetFocusHistory(days int) ([]FocusRecord, error) {
-	log.Info("Getting focus history", "days", days)
+// GetFocusHistory returns focus records for the last N days that match the filter
+func (m *Manager) GetFocusHistory(days int, match HistoryFilter) ([]FocusRecord, error) {
+	log.Info("Getting focus history", "days", days, "filter", match)
 
 	// Calculate the start date
 	startDate := time.Now().AddDate(0, 0, -days).Format("2006-01-02")
@@ -245,6 +342,11 @@ func (m *Manager) GetFocusHistory(days int) ([]FocusRecord, error) {
 
 	q := u.Query()
 	filter := fmt.Sprintf("timestamp >= '%s 00:00:00'", startDate)
+	for _, f := range [][2]string{{"label", match.Label}, {"project", match.Project}, {"task", match.Task}} {
+		if f[1] != "" {
+			filter += fmt.Sprintf(" && %s = %s", f[0], quoteFilterValue(f[1]))
+		}
+	}
 	q.Set("filter", filter)
 	q.Set("sort", "-timestamp")
 	q.Set("perPage", "500") // Get up to 500 records
@@ -279,12 +381,8 @@ func (m *Manager) GetFocusHistory(days int) ([]FocusRecord, error) {
 	}
 
 	var result struct {
-		Items []struct {
-			ID        string `json:"id"`
-			Timestamp string `json:"timestamp"`
-			Duration  int    `json:"duration"`
-		} `json:"items"`
-		TotalItems int `json:"totalItems"`
+		Items      []focusRecordItem `json:"items"`
+		TotalItems int               `json:"totalItems"`
 	}
 
 	if err := json.Unmarshal(body, &result); err != nil {
@@ -294,16 +392,12 @@ func (m *Manager) GetFocusHistory(days int) ([]FocusRecord, error) {
 	// Convert to FocusRecord slice
 	records := make([]FocusRecord, 0, len(result.Items))
 	for _, item := range result.Items {
-		// PocketBase uses "2006-01-02 15:04:05.000Z" format
-		ts, err := time.Parse("2006-01-02 15:04:05.000Z", item.Timestamp)
+		record, err := item.toRecord()
 		if err != nil {
 			log.Warn("Failed to parse timestamp", "timestamp", item.Timestamp, "error", err)
 			continue
 		}
-		records = append(records, FocusRecord{
-			Timestamp: ts,
-			Duration:  item.Duration,
-		})
+		records = append(records, record)
 	}
 
 	log.Info("Found focus records", "count", len(records))
@@ -123,3 +123,85 @@ func (m *Manager) EnsureCollection(c Collection) (created bool, err error) {
 	}
 	return true, m.CreateCollection(c)
 }
+
+// EnsureFields adds any of fields missing from an existing collection, leaving
+// the rest of its schema untouched. Returns the names of the fields it added.
+// PocketBase silently drops unknown fields on write, so a collection that
+// grows new fields must be patched before the server writes them.
+func (m *Manager) EnsureFields(name string, fields []Field) (added []string, err error) {
+	endpoint := fmt.Sprintf("%s/api/collections/%s", m.BaseURL, name)
+	req, err := http.NewRequest("GET", endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := m.DoRequest(req)
+	if err != nil {
+		return nil, err
+	}
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to read collection %s (status %d): %s", name, resp.StatusCode, string(body))
+	}
+
+	// Existing fields round-trip as raw maps so their ids and options survive
+	// the PATCH; PB replaces the whole field list.
+	var current struct {
+		Fields []map[string]any `json:"fields"`
+	}
+	if err := json.Unmarshal(body, &current); err != nil {
+		return nil, err
+	}
+
+	have := map[string]bool{}
+	for _, f := range current.Fields {
+		if n, ok := f["name"].(string); ok {
+			have[n] = true
+		}
+	}
+	for _, f := range fields {
+		if have[f.Name] {
+			continue
+		}
+		raw, err := json.Marshal(f)
+		if err != nil {
+			return nil, err
+		}
+		var asMap map[string]any
+		if err := json.Unmarshal(raw, &asMap); err != nil {
+			return nil, err
+		}
+		current.Fields = append(current.Fields, asMap)
+		added = append(added, f.Name)
+	}
+	if len(added) == 0 {
+		return nil, nil
+	}
+
+	jsonData, err := json.Marshal(map[string]any{"fields": current.Fields})
+	if err != nil {
+		return nil, err
+	}
+	req, err = http.NewRequest("PATCH", endpoint, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err = m.DoRequest(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("failed to update collection %s (status %d): %s", name, resp.StatusCode, string(body))
+	}
+	return added, nil
+}
@@ -1,13 +1,30 @@
 package db
 
 import (
+	"crypto/rand"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
+	"strconv"
 	"strings"
 )
 
+// recordIDAlphabet is what PocketBase draws its 15-character record ids from.
+const recordIDAlphabet = "abcdefghijklmnopqrstuvwxyz0123456789"
+
+// NewRecordID returns a fresh id in PocketBase's format, for records whose id
+// has to be known before they are written.
+func NewRecordID() string {
+	b := make([]byte, 15)
+	rand.Read(b)
+	for i := range b {
+		b[i] = recordIDAlphabet[int(b[i])%len(recordIDAlphabet)]
+	}
+	return string(b)
+}
+
 // createRecord creates a record in a PocketBase collection and returns the record ID
 func (m *Manager) createRecord(collection string, data map[string]any) (string, error) {
 	jsonData, err := json.Marshal(data)
@@ -74,3 +91,68 @@ func (m *Manager) updateRecord(collection, recordID string, data map[string]any)
 
 	return nil
 }
+
+// deleteRecord deletes a record from a PocketBase collection
+func (m *Manager) deleteRecord(collection, recordID string) error {
+	endpoint := fmt.Sprintf("%s/api/collections/%s/records/%s", m.BaseURL, collection, recordID)
+	req, err := http.NewRequest("DELETE", endpoint, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+
+	resp, err := m.DoRequest(req)
+	if err != nil {
+		return fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("delete failed with status %d: %s", resp.StatusCode, string(body))
+	}
+
+	return nil
+}
+
+// listRecords fetches one page of a collection into out, which must have an
+// Items field matching the records.
+func (m *Manager) listRecords(collection, filter, sort string, perPage int, out any) error {
+	u, err := url.Parse(fmt.Sprintf("%s/api/collections/%s/records", m.BaseURL, collection))
+	if err != nil {
+		return fmt.Errorf("failed to parse URL: %w", err)
+	}
+	q := u.Query()
+	if filter != "" {
+		q.Set("filter", filter)
+	}
+	if sort != "" {
+		q.Set("sort", sort)
+	}
+	q.Set("perPage", strconv.Itoa(perPage))
+	u.RawQuery = q.Encode()
+
+	req, err := http.NewRequest("GET", u.String(), nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+
+	resp, err := m.DoRequest(req)
+	if err != nil {
+		return fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read response body: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s fetch failed with status %d: %s", collection, resp.StatusCode, string(body))
+	}
+
+	if err := json.Unmarshal(body, out); err != nil {
+		return fmt.Errorf("failed to parse response: %w", err)
+	}
+	return nil
+}
@@ -81,3 +81,38 @@ func (m *Manager) CountTodayTemptations() (int, error) {
 	}
 	return result.TotalItems, nil
 }
+
+// temptationsPageSize is how many rows GetTemptationTimes reads per request.
+const temptationsPageSize = 500
+
+// GetTemptationTimes returns when each temptation in [from, to) was recorded,
+// oldest first, paging through all of them.
+func (m *Manager) GetTemptationTimes(from, to time.Time) ([]time.Time, error) {
+	window := fmt.Sprintf("created >= %s && created < %s", quoteFilterValue(pbTime(from)), quoteFilterValue(pbTime(to)))
+	filter := window
+	var times []time.Time
+	for {
+		var result struct {
+			Items []struct {
+				ID      string `json:"id"`
+				Created string `json:"created"`
+			} `json:"items"`
+		}
+		if err := m.listRecords("temptations", filter, "created,id", temptationsPageSize, &result); err != nil {
+			return nil, err
+		}
+		for _, item := range result.Items {
+			t, err := time.Parse(pbTimeLayout, item.Created)
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse temptation created %q: %w", item.Created, err)
+			}
+			times = append(times, t)
+		}
+		if len(result.Items) < temptationsPageSize {
+			return times, nil
+		}
+		last := result.Items[len(result.Items)-1]
+		c := quoteFilterValue(last.Created)
+		filter = fmt.Sprintf("%s && (created > %s || (created = %s && id > %s))", window, c, c, quoteFilterValue(last.ID))
+	}
+}
@@ -0,0 +1,181 @@
+package db
+
+import (
+	"fmt"
+	"time"
+)
+
+// webhooksCollection holds outbound webhook subscriptions. One row per endpoint.
+//
+//	url     (text) — where events are POSTed
+//	secret  (text) — HMAC-SHA256 key for the signature header; empty sends unsigned
+//	events  (text) — comma-separated event names to send; empty means all
+//	enabled (bool) — disabled webhooks are kept but get nothing
+var webhooksCollection = Collection{
+	Name: "webhooks",
+	Type: "base",
+	Fields: append([]Field{
+		{Name: "url", Type: "text", Required: true},
+		{Name: "secret", Type: "text", Required: false},
+		{Name: "events", Type: "text", Required: false},
+		{Name: "enabled", Type: "bool", Required: false},
+	}, TimestampFields()...),
+}
+
+// webhookDeliveriesCollection is the delivery queue and log. One row per event
+// per webhook, updated on every attempt.
+//
+//	webhook         (text)   — webhooks record ID
+//	event           (text)   — event name, e.g. "focus_started"
+//	payload         (text)   — exact JSON body sent, so retries sign the same bytes
+//	status          (text)   — "pending", "delivered" or "failed" (gave up)
+//	attempts        (number) — attempts made so far
+//	next_attempt_at (text)   — RFC3339 time a pending delivery is next tried
+//	last_error      (text)   — why the latest attempt failed
+//	response_status (number) — HTTP status of the latest attempt; 0 if none
+var webhookDeliveriesCollection = Collection{
+	Name: "webhook_deliveries",
+	Type: "base",
+	Fields: append([]Field{
+		{Name: "webhook", Type: "text", Required: true},
+		{Name: "event", Type: "text", Required: true},
+		{Name: "payload", Type: "text", Required: true},
+		{Name: "status", Type: "text", Required: true},
+		{Name: "attempts", Type: "number", Required: false},
+		{Name: "next_attempt_at", Type: "text", Required: false},
+		{Name: "last_error", Type: "text", Required: false},
+		{Name: "response_status", Type: "number", Required: false},
+	}, TimestampFields()...),
+}
+
+// EnsureWebhookCollections creates the webhooks and webhook_deliveries
+// collections if they don't exist. Idempotent.
+func (m *Manager) EnsureWebhookCollections() (created []string, err error) {
+	for _, c := range []Collection{webhooksCollection, webhookDeliveriesCollection} {
+		ok, err := m.EnsureCollection(c)
+		if err != nil {
+			return created, err
+		}
+		if ok {
+			created = append(created, c.Name)
+		}
+	}
+	return created, nil
+}
+
+// Webhook is one subscription as stored in PB.
+type Webhook struct {
+	RecordID string `json:"id,omitempty"`
+	URL      string `json:"url"`
+	Secret   string `json:"secret"`
+	Events   string `json:"events"`
+	Enabled  bool   `json:"enabled"`
+}
+
+func (w Webhook) payload() map[string]any {
+	return map[string]any{
+		"url":     w.URL,
+		"secret":  w.Secret,
+		"events":  w.Events,
+		"enabled": w.Enabled,
+	}
+}
+
+// InsertWebhook stores a new subscription and returns its record ID.
+func (m *Manager) InsertWebhook(w Webhook) (string, error) {
+	return m.createRecord("webhooks", w.payload())
+}
+
+// UpdateWebhook overwrites the subscription with w.RecordID.
+func (m *Manager) UpdateWebhook(w Webhook) error {
+	return m.updateRecord("webhooks", w.RecordID, w.payload())
+}
+
+// DeleteWebhook removes a subscription. Its delivery log stays.
+func (m *Manager) DeleteWebhook(recordID string) error {
+	return m.deleteRecord("webhooks", recordID)
+}
+
+// GetWebhooks returns every subscription, oldest first.
+func (m *Manager) GetWebhooks() ([]Webhook, error) {
+	var result struct {
+		Items []Webhook `json:"items"`
+	}
+	if err := m.listRecords("webhooks", "", "created", 500, &result); err != nil {
+		return nil, err
+	}
+	return result.Items, nil
+}
+
+// WebhookDelivery is one queued or finished delivery as stored in PB.
+type WebhookDelivery struct {
+	RecordID       string `json:"id,omitempty"`
+	Webhook        string `json:"webhook"`
+	Event          string `json:"event"`
+	Payload        string `json:"payload"`
+	Status         string `json:"status"`
+	Attempts       int    `json:"attempts"`
+	NextAttemptAt  string `json:"next_attempt_at"`
+	LastError      string `json:"last_error"`
+	ResponseStatus int    `json:"response_status"`
+	Created        string `json:"created,omitempty"`
+	Updated        string `json:"updated,omitempty"`
+}
+
+func (d WebhookDelivery) payload() map[string]any {
+	return map[string]any{
+		"webhook":         d.Webhook,
+		"event":           d.Event,
+		"payload":         d.Payload,
+		"status":          d.Status,
+		"attempts":        d.Attempts,
+		"next_attempt_at": d.NextAttemptAt,
+		"last_error":      d.LastError,
+		"response_status": d.ResponseStatus,
+	}
+}
+
+// InsertWebhookDelivery queues a delivery, under d.RecordID when it is set (see
+// NewRecordID), and returns its record ID.
+func (m *Manager) InsertWebhookDelivery(d WebhookDelivery) (string, error) {
+	data := d.payload()
+	if d.RecordID != "" {
+		data["id"] = d.RecordID
+	}
+	return m.createRecord("webhook_deliveries", data)
+}
+
+// UpdateWebhookDelivery records the outcome of an attempt.
+func (m *Manager) UpdateWebhookDelivery(d WebhookDelivery) error {
+	return m.updateRecord("webhook_deliveries", d.RecordID, d.payload())
+}
+
+// GetDueWebhookDeliveries returns pending deliveries whose next attempt is at
+// or before now, oldest first.
+func (m *Manager) GetDueWebhookDeliveries(now time.Time) ([]WebhookDelivery, error) {
+	filter := fmt.Sprintf("status = 'pending' && next_attempt_at <= %s",
+		quoteFilterValue(now.UTC().Format(time.RFC3339)))
+	var result struct {
+		Items []WebhookDelivery `json:"items"`
+	}
+	if err := m.listRecords("webhook_deliveries", filter, "created", 100, &result); err != nil {
+		return nil, err
+	}
+	return result.Items, nil
+}
+
+// GetRecentWebhookDeliveries returns up to limit deliveries, most recently
+// attempted first, optionally only those with status.
+func (m *Manager) GetRecentWebhookDeliveries(limit int, status string) ([]WebhookDelivery, error) {
+	filter := ""
+	if status != "" {
+		filter = "status = " + quoteFilterValue(status)
+	}
+	var result struct {
+		Items []WebhookDelivery `json:"items"`
+	}
+	if err := m.listRecords("webhook_deliveries", filter, "-updated", limit, &result); err != nil {
+		return nil, err
+	}
+	return result.Items, nil
+}
@@ -0,0 +1,196 @@
+package coach
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/charmbracelet/log"
+
+	"coach/internal/db"
+)
+
+// Defaults for the delayed unlock: the user waits DefaultUnlockDelay, then the
+// lock opens for DefaultUnlockDuration.
+const (
+	DefaultUnlockDelay    = 10 * time.Minute
+	DefaultUnlockDuration = 15 * time.Minute
+)
+
+// pendingUnlockBroadcastEvery is how often clients are sent the countdown.
+const pendingUnlockBroadcastEvery = 30 * time.Second
+
+// pendingUnlock is a delayed unlock counting down. Its timer fires for every
+// countdown broadcast and, at the end, for the release itself.
+type pendingUnlock struct {
+	at          time.Time
+	duration    time.Duration
+	userMessage string
+	timer       *time.Timer
+}
+
+// pendingUnlockStore is the slice of db.Manager the delayed unlock is
+// persisted to (kept narrow for tests).
+type pendingUnlockStore interface {
+	SetPendingUnlock(p *db.PendingUnlock) error
+}
+
+// pendingUnlockWriter persists the delayed unlock in the background, one write
+// at a time. Writes are numbered as they are queued and one that comes up after
+// a later write is dropped, so a request can't land after the cancel that
+// followed it. The zero value is ready to use.
+type pendingUnlockWriter struct {
+	queued  atomic.Uint64
+	mu      sync.Mutex
+	written uint64
+}
+
+// write queues rec (nil to clear) to be written to store.
+func (w *pendingUnlockWriter) write(store pendingUnlockStore, rec *db.PendingUnlock) {
+	seq := w.queued.Add(1)
+	go func() {
+		w.mu.Lock()
+		defer w.mu.Unlock()
+		if seq < w.written {
+			return
+		}
+		w.written = seq
+		if err := store.SetPendingUnlock(rec); err != nil {
+			log.Error("Failed to persist delayed unlock", "error", err)
+		}
+	}()
+}
+
+// PendingUnlockInfo is the public shape of a delayed unlock counting down.
+type PendingUnlockInfo struct {
+	SecondsLeft     int64 `json:"seconds_left"`
+	DurationSeconds int64 `json:"duration_seconds"`
+}
+
+// RequestDelayedUnlock starts a countdown of delay after which the agent lock is
+// released for duration, unless cancelled first. It is the way out that doesn't
+// ask the judge, so it costs patience instead. Returns false if one is already
+// counting down.
+func (s *State) RequestDelayedUnlock(delay, duration time.Duration, userMessage string) bool {
+	s.mu.Lock()
+	if s.pendingUnlock != nil {
+		s.mu.Unlock()
+		return false
+	}
+	s.pendingUnlock = &pendingUnlock{at: time.Now().Add(delay), duration: duration, userMessage: userMessage}
+	s.schedulePendingUnlockLocked()
+	s.persistPendingUnlockLocked()
+	s.mu.Unlock()
+
+	log.Info("Delayed unlock requested", "delay", delay, "duration", duration)
+	go s.NotifyAllClients(s.GetCurrentFocusInfo())
+	return true
+}
+
+// CancelDelayedUnlock stops the countdown. Returns false if none was running.
+func (s *State) CancelDelayedUnlock() bool {
+	s.mu.Lock()
+	p := s.pendingUnlock
+	if p == nil {
+		s.mu.Unlock()
+		return false
+	}
+	p.timer.Stop()
+	s.pendingUnlock = nil
+	s.persistPendingUnlockLocked()
+	s.mu.Unlock()
+
+	log.Info("Delayed unlock cancelled", "seconds_left", int64(time.Until(p.at)/time.Second))
+	go s.NotifyAllClients(s.GetCurrentFocusInfo())
+	return true
+}
+
+// RestorePendingUnlock picks up a delayed unlock persisted before a restart. One
+// that came due while the server was down releases for whatever is left of it.
+func (s *State) RestorePendingUnlock(p db.PendingUnlock) {
+	s.mu.Lock()
+	s.pendingUnlock = &pendingUnlock{at: p.At, duration: p.Duration, userMessage: p.UserMessage}
+	s.schedulePendingUnlockLocked()
+	s.mu.Unlock()
+	log.Info("Restored delayed unlock", "at", p.At, "duration", p.Duration)
+}
+
+// schedulePendingUnlockLocked arms the timer for the next broadcast or the
+// release, whichever is sooner. Must be called with s.mu held.
+func (s *State) schedulePendingUnlockLocked() {
+	p := s.pendingUnlock
+	d := max(time.Until(p.at), 0)
+	if d > pendingUnlockBroadcastEvery {
+		d = pendingUnlockBroadcastEvery
+	}
+	p.timer = time.AfterFunc(d, func() { s.onPendingUnlockTimer(p) })
+}
+
+// onPendingUnlockTimer broadcasts the countdown or, once it is over, journals
+// the unlock and releases the lock. p is the unlock the timer was armed for, so
+// a cancel racing the timer doesn't release a newer one. The release counts as
+// one the user asked for: when it ends it arms the grant cooldown, so waiting
+// out a countdown can't be chained straight into a grant.
+func (s *State) onPendingUnlockTimer(p *pendingUnlock) {
+	s.mu.Lock()
+	if s.pendingUnlock != p {
+		s.mu.Unlock()
+		return
+	}
+	if time.Now().Before(p.at) {
+		s.schedulePendingUnlockLocked()
+		s.mu.Unlock()
+		go s.NotifyAllClients(s.GetCurrentFocusInfo())
+		return
+	}
+	s.pendingUnlock = nil
+	s.persistPendingUnlockLocked()
+	dbManager := s.dbManager
+	s.mu.Unlock()
+
+	end := p.at.Add(p.duration)
+	id := db.NewRecordID()
+	log.Info("Delayed unlock due", "until", end)
+	// Journal before releasing, like a grant, so the end of the release can't
+	// be journaled ahead of it
+	if dbManager != nil {
+		entry := db.LockDecision{
+			ID: id, Kind: "delayed", Source: "user",
+			UserMessage: p.userMessage, DurationSeconds: int(p.duration / time.Second),
+		}
+		if err := dbManager.InsertLockDecisionEntry(entry); err != nil {
+			log.Error("Failed to journal delayed unlock", "error", err)
+		}
+	}
+	if !end.After(time.Now()) || !s.releaseAgentLockUntil(end, nil, db.ReleaseGrant{ID: id}) {
+		// Nothing left to release, or a longer release already covers it
+		go s.NotifyAllClients(s.GetCurrentFocusInfo())
+	}
+}
+
+// pendingUnlockInfoLocked returns the countdown, or nil if none is running.
+// Must be called with s.mu held.
+func (s *State) pendingUnlockInfoLocked() *PendingUnlockInfo {
+	p := s.pendingUnlock
+	if p == nil {
+		return nil
+	}
+	left := max(time.Until(p.at), 0)
+	return &PendingUnlockInfo{
+		SecondsLeft:     int64((left + time.Second - 1) / time.Second),
+		DurationSeconds: int64(p.duration / time.Second),
+	}
+}
+
+// persistPendingUnlockLocked writes the countdown to the DB. Best-effort, async,
+// in order. Must be called with s.mu held.
+func (s *State) persistPendingUnlockLocked() {
+	if s.dbManager == nil {
+		return
+	}
+	var rec *db.PendingUnlock
+	if p := s.pendingUnlock; p != nil {
+		rec = &db.PendingUnlock{At: p.at, Duration: p.duration, UserMessage: p.userMessage}
+	}
+	s.pendingUnlockWrites.write(s.dbManager, rec)
+}
@@ -0,0 +1,167 @@
+package coach
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"coach/internal/db"
+)
+
+func TestDelayedUnlockReleasesAfterCountdown(t *testing.T) {
+	state := &State{}
+
+	if !state.RequestDelayedUnlock(100*time.Millisecond, time.Minute, "need the docs") {
+		t.Fatal("Expected the countdown to start")
+	}
+	if state.RequestDelayedUnlock(100*time.Millisecond, time.Minute, "again") {
+		t.Error("A second request shouldn't start another countdown")
+	}
+	info := state.GetAgentLockInfo()
+	if !agentLocked(state) || info.PendingUnlock == nil {
+		t.Fatal("The lock should stay engaged while the countdown runs")
+	}
+	if info.PendingUnlock.SecondsLeft != 1 || info.PendingUnlock.DurationSeconds != 60 {
+		t.Errorf("Unexpected countdown %+v", *info.PendingUnlock)
+	}
+	if state.GetCurrentFocusInfo().AgentPendingUnlock == nil {
+		t.Error("Expected FocusInfo to carry the countdown")
+	}
+
+	time.Sleep(250 * time.Millisecond)
+	info = state.GetAgentLockInfo()
+	if info.PendingUnlock != nil {
+		t.Error("The countdown should be over")
+	}
+	if info.TimeLeftSeconds == nil || *info.TimeLeftSeconds < 58 {
+		t.Errorf("Expected a ~60s release after the countdown, got %v", info.TimeLeftSeconds)
+	}
+}
+
+func TestCancelDelayedUnlock(t *testing.T) {
+	state := &State{}
+	state.RequestDelayedUnlock(100*time.Millisecond, time.Minute, "")
+
+	if !state.CancelDelayedUnlock() {
+		t.Fatal("Expected the countdown to be cancelled")
+	}
+	if state.CancelDelayedUnlock() {
+		t.Error("Nothing is left to cancel")
+	}
+	time.Sleep(250 * time.Millisecond)
+	if !agentLocked(state) {
+		t.Error("A cancelled countdown must not release the lock")
+	}
+}
+
+func TestRestorePendingUnlockThatCameDueWhileDown(t *testing.T) {
+	state := &State{}
+	state.RestorePendingUnlock(db.PendingUnlock{At: time.Now().Add(-time.Minute), Duration: 3 * time.Minute})
+
+	time.Sleep(50 * time.Millisecond)
+	info := state.GetAgentLockInfo()
+	if info.PendingUnlock != nil {
+		t.Error("An overdue countdown should fire right away")
+	}
+	if info.TimeLeftSeconds == nil || *info.TimeLeftSeconds > 120 {
+		t.Errorf("Expected only what is left of the release (~120s), got %v", info.TimeLeftSeconds)
+	}
+}
+
+func TestDelayedUnlockArmsTheGrantCooldown(t *testing.T) {
+	state := &State{}
+	state.SetAgentGrantCooldown(time.Minute)
+	released := make(chan LockReleased, 1)
+	On(state.Events(), func(e LockReleased) { released <- e })
+
+	state.RequestDelayedUnlock(0, time.Hour, "need the docs")
+	waitEvent(t, released)
+	if left := state.AgentGrantCooldownLeft(); left != 0 {
+		t.Errorf("Expected no cooldown while the release runs, got %v", left)
+	}
+	state.EngageAgentLock()
+	if left := state.AgentGrantCooldownLeft(); left <= 0 {
+		t.Error("Expected the end of a delayed unlock to arm the cooldown")
+	}
+}
+
+func TestDelayedUnlockEndpoints(t *testing.T) {
+	server := &Server{State: &State{}, UnlockDelay: time.Hour, UnlockDuration: time.Minute}
+	post := func(path string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, path, strings.NewReader("user_message=need+a+break"))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		rr := httptest.NewRecorder()
+		server.AgentLockHandler(rr, req)
+		return rr
+	}
+
+	rr := post("/agent-lock/request-delayed")
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if !strings.Contains(rr.Body.String(), `"seconds_left":3600`) {
+		t.Errorf("Expected the countdown in the response, got %s", rr.Body.String())
+	}
+	if rr := post("/agent-lock/request-delayed"); rr.Code != http.StatusConflict {
+		t.Errorf("Expected 409 while counting down, got %d", rr.Code)
+	}
+
+	if rr := post("/agent-lock/cancel-delayed"); rr.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d", rr.Code)
+	}
+	if server.State.GetAgentLockInfo().PendingUnlock != nil {
+		t.Error("Expected the countdown cancelled")
+	}
+}
+
+// fakePendingUnlockStore records every write it gets, in order.
+type fakePendingUnlockStore struct {
+	mu     sync.Mutex
+	writes []*db.PendingUnlock
+}
+
+func (f *fakePendingUnlockStore) SetPendingUnlock(p *db.PendingUnlock) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.writes = append(f.writes, p)
+	return nil
+}
+
+func TestPendingUnlockWritesLandInOrder(t *testing.T) {
+	store := &fakePendingUnlockStore{}
+	var w pendingUnlockWriter
+
+	// Queue request/cancel pairs while a write is in flight, so the
+	// goroutines all race for the store at once
+	w.mu.Lock()
+	for i := 0; i < 20; i++ {
+		w.write(store, &db.PendingUnlock{At: time.Now(), Duration: time.Minute})
+		w.write(store, nil)
+	}
+	w.mu.Unlock()
+
+	deadline := time.Now().Add(time.Second)
+	for {
+		w.mu.Lock()
+		done := w.written == 40
+		w.mu.Unlock()
+		if done {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("Timed out waiting for the last write")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	// Stragglers behind the last write are dropped, not written late
+	time.Sleep(10 * time.Millisecond)
+
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	if last := store.writes[len(store.writes)-1]; last != nil {
+		t.Errorf("Expected the final cancel to be the last write, got %+v", last)
+	}
+}
@@ -0,0 +1,136 @@
+// Package dimaist reads tasks from a Dimaist server.
+package dimaist
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Project is the part of a Dimaist project a task carries.
+type Project struct {
+	Name string `json:"name"`
+}
+
+// Task is a Dimaist task, trimmed to what coach uses.
+type Task struct {
+	ID          int      `json:"id"`
+	Title       string   `json:"title"`
+	Description string   `json:"description,omitempty"`
+	DueDate     string   `json:"due_date,omitempty"`
+	DueDatetime string   `json:"due_datetime,omitempty"`
+	CompletedAt string   `json:"completed_at,omitempty"`
+	DeletedAt   string   `json:"deleted_at,omitempty"`
+	Labels      []string `json:"labels,omitempty"`
+	Project     *Project `json:"project,omitempty"`
+}
+
+// due returns when the task is due, or false if it has no due date. A bare
+// date counts from that day's midnight, local time.
+func (t Task) due(loc *time.Location) (time.Time, bool) {
+	if t.DueDatetime != "" {
+		if at, err := time.Parse(time.RFC3339, t.DueDatetime); err == nil {
+			return at, true
+		}
+	}
+	if len(t.DueDate) >= len("2006-01-02") {
+		if day, err := time.ParseInLocation("2006-01-02", t.DueDate[:10], loc); err == nil {
+			return day, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// Client talks to a Dimaist server. Dimaist has no auth.
+type Client struct {
+	BaseURL string
+	HTTP    *http.Client
+}
+
+func NewClient(baseURL string) *Client {
+	return &Client{
+		BaseURL: strings.TrimSuffix(baseURL, "/"),
+		HTTP:    &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// NewClientFromEnv builds a client from DIMAIST_URL. Returns nil if it is
+// unset, so callers can treat tasks as optional.
+func NewClientFromEnv() *Client {
+	baseURL := os.Getenv("DIMAIST_URL")
+	if baseURL == "" {
+		return nil
+	}
+	return NewClient(baseURL)
+}
+
+// GetTasks fetches every task.
+func (c *Client) GetTasks(ctx context.Context) ([]Task, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", c.BaseURL+"/tasks", nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	resp, err := c.HTTP.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response body: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("tasks fetch failed with status %d: %s", resp.StatusCode, string(body))
+	}
+
+	var tasks []Task
+	if err := json.Unmarshal(body, &tasks); err != nil {
+		return nil, fmt.Errorf("failed to parse response: %w", err)
+	}
+	return tasks, nil
+}
+
+// GetTodayTasks returns the incomplete tasks due today or earlier.
+func (c *Client) GetTodayTasks(ctx context.Context) ([]Task, error) {
+	tasks, err := c.GetTasks(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return DueBy(tasks, time.Now()), nil
+}
+
+// DueBy keeps the tasks that are neither completed nor deleted and are due on
+// or before now's date, earliest due first. This matches the CLI's --due today.
+func DueBy(tasks []Task, now time.Time) []Task {
+	endOfDay := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location()).AddDate(0, 0, 1)
+	type dueTask struct {
+		task Task
+		due  time.Time
+	}
+	var kept []dueTask
+	for _, t := range tasks {
+		if t.CompletedAt != "" || t.DeletedAt != "" {
+			continue
+		}
+		due, ok := t.due(now.Location())
+		if !ok || !due.Before(endOfDay) {
+			continue
+		}
+		kept = append(kept, dueTask{t, due})
+	}
+	sort.SliceStable(kept, func(a, b int) bool { return kept[a].due.Before(kept[b].due) })
+	out := make([]Task, len(kept))
+	for i, k := range kept {
+		out[i] = k.task
+	}
+	return out
+}
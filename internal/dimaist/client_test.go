@@ -0,0 +1,47 @@
+package dimaist
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestDueByKeepsOpenTasksDueTodayOrEarlier(t *testing.T) {
+	now := time.Date(2026, 3, 10, 14, 0, 0, 0, time.Local)
+	tasks := []Task{
+		{ID: 1, Title: "tomorrow", DueDate: "2026-03-11"},
+		{ID: 2, Title: "tonight", DueDatetime: time.Date(2026, 3, 10, 22, 0, 0, 0, time.Local).Format(time.RFC3339)},
+		{ID: 3, Title: "overdue", DueDate: "2026-03-08T00:00:00Z"},
+		{ID: 4, Title: "done", DueDate: "2026-03-10", CompletedAt: "2026-03-10T09:00:00Z"},
+		{ID: 5, Title: "deleted", DueDate: "2026-03-10", DeletedAt: "2026-03-10T09:00:00Z"},
+		{ID: 6, Title: "someday"},
+		{ID: 7, Title: "today", DueDate: "2026-03-10"},
+	}
+
+	got := DueBy(tasks, now)
+	want := []string{"overdue", "today", "tonight"}
+	if len(got) != len(want) {
+		t.Fatalf("Expected %v, got %+v", want, got)
+	}
+	for i, title := range want {
+		if got[i].Title != title {
+			t.Errorf("Task %d: expected %q, got %q", i, title, got[i].Title)
+		}
+	}
+}
+
+func TestGetTasksReportsServiceErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/tasks" {
+			t.Errorf("Unexpected path %s", r.URL.Path)
+		}
+		http.Error(w, "boom", http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	if _, err := NewClient(server.URL + "/").GetTodayTasks(context.Background()); err == nil {
+		t.Fatal("Expected an error for a 500")
+	}
+}
@@ -0,0 +1,194 @@
+package coach
+
+import (
+	"sync"
+	"time"
+
+	"github.com/charmbracelet/log"
+)
+
+// Event is something that happened to focus, the agent lock or attention.
+// Subscribers switch on the concrete type; Name is its wire name.
+type Event interface {
+	Name() string
+}
+
+// FocusStarted is published when focus is queued while none was running.
+// RecordID is the id its coach record is saved under.
+type FocusStarted struct {
+	At        time.Time `json:"at"`
+	Start     time.Time `json:"start"`
+	End       time.Time `json:"end"`
+	Committed bool      `json:"committed"`
+	RecordID  string    `json:"record_id"`
+	FocusLabel
+}
+
+// FocusExtended is published when focus is queued behind a running session.
+type FocusExtended struct {
+	At        time.Time `json:"at"`
+	Start     time.Time `json:"start"`
+	End       time.Time `json:"end"`
+	Committed bool      `json:"committed"`
+	RecordID  string    `json:"record_id"`
+	FocusLabel
+}
+
+// FocusEnded is published when queued focus periods end: "expired", "stopped"
+// or "replaced". By is who stopped or replaced them. Records says how long
+// each period ran, for closing out its coach record.
+type FocusEnded struct {
+	At             time.Time        `json:"at"`
+	Reason         string           `json:"reason"`
+	By             string           `json:"by,omitempty"`
+	Periods        int              `json:"periods"`
+	FocusedSeconds int              `json:"focused_seconds"`
+	Records        []FocusRecordEnd `json:"-"`
+}
+
+// FocusRecordEnd is how long the period saved under RecordID actually ran.
+type FocusRecordEnd struct {
+	RecordID string
+	Focused  time.Duration
+}
+
+// LockReleased is published when the agent lock opens or its release grows.
+// Targets lists the hostnames or app packages released; empty means everything.
+// Reason is "focus_ended" when a focus session stops holding the lock over a
+// release that is still running.
+type LockReleased struct {
+	At      time.Time `json:"at"`
+	Until   time.Time `json:"until"`
+	Targets []string  `json:"targets,omitempty"`
+	Reason  string    `json:"reason,omitempty"`
+}
+
+// LockEngaged is published when a release is cancelled early. Reason is
+// "focus" when a focus session takes hold of the lock over a running release.
+type LockEngaged struct {
+	At     time.Time `json:"at"`
+	Reason string    `json:"reason,omitempty"`
+}
+
+// LockExpired is published when a release runs out and the lock snaps back.
+// Targets lists the target releases that ran out; empty means the full release.
+type LockExpired struct {
+	At      time.Time `json:"at"`
+	Targets []string  `json:"targets,omitempty"`
+}
+
+// TemptationRecorded is published for every blocked attempt a client reports.
+type TemptationRecorded struct {
+	At     time.Time `json:"at"`
+	Source string    `json:"source"`
+	Target string    `json:"target"`
+}
+
+// AttentionChanged is published when the attention state or site changes.
+type AttentionChanged struct {
+	At    time.Time `json:"at"`
+	State string    `json:"state"`
+	Site  string    `json:"site,omitempty"`
+}
+
+func (FocusStarted) Name() string       { return "focus_started" }
+func (FocusExtended) Name() string      { return "focus_extended" }
+func (FocusEnded) Name() string         { return "focus_ended" }
+func (LockReleased) Name() string       { return "lock_released" }
+func (LockEngaged) Name() string        { return "lock_engaged" }
+func (LockExpired) Name() string        { return "lock_expired" }
+func (TemptationRecorded) Name() string { return "temptation_recorded" }
+func (AttentionChanged) Name() string   { return "attention_changed" }
+
+// EventBus fans events out to subscribers. Each subscriber has its own queue
+// and worker goroutine, so it sees events in publish order while a slow
+// subscriber never holds up the publisher or the others; a panicking one is
+// logged and forgotten. A nil bus drops everything.
+type EventBus struct {
+	mu   sync.RWMutex
+	subs []*subscriber
+}
+
+func NewEventBus() *EventBus {
+	return &EventBus{}
+}
+
+// subscriber is one subscriber's FIFO. The queue is unbounded rather than a
+// buffered channel so Publish never blocks: it is called with State's lock
+// held, which a subscriber may be waiting on.
+type subscriber struct {
+	fn    func(Event)
+	mu    sync.Mutex
+	queue []Event
+	wake  chan struct{}
+}
+
+// Subscribe registers fn for every event.
+func (b *EventBus) Subscribe(fn func(Event)) {
+	sub := &subscriber{fn: fn, wake: make(chan struct{}, 1)}
+	go sub.run()
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.subs = append(b.subs, sub)
+}
+
+// On registers fn for events of type E only.
+func On[E Event](b *EventBus, fn func(E)) {
+	b.Subscribe(func(e Event) {
+		if typed, ok := e.(E); ok {
+			fn(typed)
+		}
+	})
+}
+
+// Publish queues e for every subscriber and returns without waiting for them.
+// Safe to call with other locks held.
+func (b *EventBus) Publish(e Event) {
+	if b == nil {
+		return
+	}
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	for _, sub := range b.subs {
+		sub.push(e)
+	}
+}
+
+func (sub *subscriber) push(e Event) {
+	sub.mu.Lock()
+	sub.queue = append(sub.queue, e)
+	sub.mu.Unlock()
+	select {
+	case sub.wake <- struct{}{}:
+	default: // the worker is already due to drain the queue
+	}
+}
+
+// run delivers queued events one at a time, oldest first, for the life of the process.
+func (sub *subscriber) run() {
+	for range sub.wake {
+		for {
+			sub.mu.Lock()
+			if len(sub.queue) == 0 {
+				sub.mu.Unlock()
+				break
+			}
+			e := sub.queue[0]
+			sub.queue[0] = nil
+			sub.queue = sub.queue[1:]
+			sub.mu.Unlock()
+			deliver(sub.fn, e)
+		}
+	}
+}
+
+func deliver(fn func(Event), e Event) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Error("Event subscriber panicked", "event", e.Name(), "panic", r)
+		}
+	}()
+	fn(e)
+}
@@ -0,0 +1,121 @@
+package coach
+
+import (
+	"testing"
+	"time"
+)
+
+// waitEvent returns the next event from ch, failing the test if none arrives.
+func waitEvent[E Event](t *testing.T, ch <-chan E) E {
+	t.Helper()
+	select {
+	case e := <-ch:
+		return e
+	case <-time.After(time.Second):
+		var zero E
+		t.Fatalf("Timed out waiting for %s", zero.Name())
+		return zero
+	}
+}
+
+func TestEventBusOnFiltersByType(t *testing.T) {
+	bus := NewEventBus()
+	engaged := make(chan LockEngaged, 4)
+	On(bus, func(e LockEngaged) { engaged <- e })
+
+	bus.Publish(LockExpired{At: time.Now()})
+	bus.Publish(LockEngaged{At: time.Now()})
+
+	waitEvent(t, engaged)
+	select {
+	case e := <-engaged:
+		t.Errorf("Expected a single lock_engaged, got another: %+v", e)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestEventBusIsolatesPanics(t *testing.T) {
+	bus := NewEventBus()
+	got := make(chan TemptationRecorded, 1)
+	bus.Subscribe(func(Event) { panic("boom") })
+	On(bus, func(e TemptationRecorded) { got <- e })
+
+	bus.Publish(TemptationRecorded{Source: "firefox", Target: "reddit.com"})
+
+	if e := waitEvent(t, got); e.Target != "reddit.com" {
+		t.Errorf("Expected reddit.com, got %+v", e)
+	}
+}
+
+func TestEventBusDeliversInOrder(t *testing.T) {
+	bus := NewEventBus()
+	got := make(chan Event, 100)
+	bus.Subscribe(func(e Event) {
+		if _, ok := e.(FocusEnded); ok {
+			time.Sleep(time.Millisecond) // a slow end must not let the next start overtake it
+		}
+		got <- e
+	})
+
+	for i := range 50 {
+		bus.Publish(FocusEnded{Periods: i})
+		bus.Publish(FocusStarted{})
+	}
+	next := func() Event {
+		select {
+		case e := <-got:
+			return e
+		case <-time.After(time.Second):
+			t.Fatal("Timed out waiting for an event")
+			return nil
+		}
+	}
+	for i := range 50 {
+		if e, ok := next().(FocusEnded); !ok || e.Periods != i {
+			t.Fatalf("Expected focus_ended %d, got %+v", i, e)
+		}
+		if _, ok := next().(FocusStarted); !ok {
+			t.Fatalf("Expected focus_started after focus_ended %d", i)
+		}
+	}
+}
+
+func TestNilEventBusDropsEvents(t *testing.T) {
+	var bus *EventBus
+	bus.Publish(LockEngaged{}) // must not panic
+}
+
+func TestStatePublishesFocusLifecycle(t *testing.T) {
+	state := &State{}
+	started := make(chan FocusStarted, 1)
+	extended := make(chan FocusExtended, 1)
+	ended := make(chan FocusEnded, 1)
+	On(state.Events(), func(e FocusStarted) { started <- e })
+	On(state.Events(), func(e FocusExtended) { extended <- e })
+	On(state.Events(), func(e FocusEnded) { ended <- e })
+
+	state.SetLabeledFocusing(30*time.Second, FocusLabel{Label: "write"})
+	if e := waitEvent(t, started); e.Label != "write" || e.End.Sub(e.Start) != 30*time.Second {
+		t.Errorf("Unexpected focus_started: %+v", e)
+	}
+
+	state.SetFocusing(10 * time.Second)
+	waitEvent(t, extended)
+
+	state.HandleFocusChange(false, 0, FocusLabel{}, "test")
+	if e := waitEvent(t, ended); e.Reason != "stopped" || e.By != "test" || e.Periods != 2 {
+		t.Errorf("Unexpected focus_ended: %+v", e)
+	}
+}
+
+func TestStatePublishesLockChanges(t *testing.T) {
+	state := &State{}
+	released := make(chan LockReleased, 1)
+	expired := make(chan LockExpired, 1)
+	On(state.Events(), func(e LockReleased) { released <- e })
+	On(state.Events(), func(e LockExpired) { expired <- e })
+
+	state.ReleaseAgentLock(50 * time.Millisecond)
+	waitEvent(t, released)
+	waitEvent(t, expired)
+}
@@ -0,0 +1,163 @@
+package coach
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/charmbracelet/log"
+
+	"coach/internal/db"
+)
+
+// focusBlockStore is the slice of db.Manager the scheduler needs (kept narrow for tests).
+type focusBlockStore interface {
+	InsertFocusBlock(start, end time.Time, label, project, task string) (string, error)
+	SetFocusBlockStatus(recordID, status string) error
+	GetPendingFocusBlocks() ([]db.FocusBlock, error)
+}
+
+// FocusBlock is a focus session booked for later.
+type FocusBlock struct {
+	ID    string    `json:"id"`
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+	FocusLabel
+}
+
+// FocusScheduler starts booked focus blocks when their time comes. Pending blocks
+// live in PocketBase so they survive restarts; each one has a timer here.
+type FocusScheduler struct {
+	state *State
+	store focusBlockStore
+
+	mu     sync.Mutex
+	blocks map[string]*scheduledBlock
+}
+
+type scheduledBlock struct {
+	block FocusBlock
+	timer *time.Timer
+}
+
+func NewFocusScheduler(state *State, store focusBlockStore) *FocusScheduler {
+	return &FocusScheduler{
+		state:  state,
+		store:  store,
+		blocks: make(map[string]*scheduledBlock),
+	}
+}
+
+// Load schedules every pending block from the store. Blocks that ended while the
+// server was down are marked missed; blocks that started but haven't ended start now.
+func (f *FocusScheduler) Load() error {
+	pending, err := f.store.GetPendingFocusBlocks()
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	for _, p := range pending {
+		start, err := time.Parse(time.RFC3339, p.StartAt)
+		if err != nil {
+			log.Warn("Skipping focus block with malformed start", "id", p.RecordID, "start_at", p.StartAt)
+			continue
+		}
+		end, err := time.Parse(time.RFC3339, p.EndAt)
+		if err != nil {
+			log.Warn("Skipping focus block with malformed end", "id", p.RecordID, "end_at", p.EndAt)
+			continue
+		}
+		if !end.After(now) {
+			f.setStatus(p.RecordID, "missed")
+			continue
+		}
+		f.schedule(FocusBlock{
+			ID:         p.RecordID,
+			Start:      start,
+			End:        end,
+			FocusLabel: FocusLabel{Label: p.Label, Project: p.Project, Task: p.Task},
+		})
+	}
+	log.Info("Loaded focus blocks", "pending", len(f.List()))
+	return nil
+}
+
+// Book persists a block and schedules it.
+func (f *FocusScheduler) Book(start, end time.Time, label FocusLabel) (FocusBlock, error) {
+	if !end.After(start) {
+		return FocusBlock{}, fmt.Errorf("end must be after start")
+	}
+	if !end.After(time.Now()) {
+		return FocusBlock{}, fmt.Errorf("block is already over")
+	}
+	id, err := f.store.InsertFocusBlock(start, end, label.Label, label.Project, label.Task)
+	if err != nil {
+		return FocusBlock{}, err
+	}
+	block := FocusBlock{ID: id, Start: start, End: end, FocusLabel: label}
+	f.schedule(block)
+	log.Info("Focus block booked", "id", id, "start", start, "end", end)
+	return block, nil
+}
+
+// Cancel drops a pending block. Returns false if no pending block has that ID.
+func (f *FocusScheduler) Cancel(id string) (bool, error) {
+	f.mu.Lock()
+	sb, ok := f.blocks[id]
+	if ok {
+		sb.timer.Stop()
+		delete(f.blocks, id)
+	}
+	f.mu.Unlock()
+	if !ok {
+		return false, nil
+	}
+	log.Info("Focus block cancelled", "id", id)
+	return true, f.store.SetFocusBlockStatus(id, "cancelled")
+}
+
+// List returns the pending blocks, earliest first.
+func (f *FocusScheduler) List() []FocusBlock {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	out := make([]FocusBlock, 0, len(f.blocks))
+	for _, sb := range f.blocks {
+		out = append(out, sb.block)
+	}
+	sort.Slice(out, func(a, b int) bool { return out[a].Start.Before(out[b].Start) })
+	return out
+}
+
+func (f *FocusScheduler) schedule(block FocusBlock) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.blocks[block.ID] = &scheduledBlock{
+		block: block,
+		timer: time.AfterFunc(time.Until(block.Start), func() { f.fire(block.ID) }),
+	}
+}
+
+// fire starts a block whose time has come. A Cancel racing the timer wins.
+func (f *FocusScheduler) fire(id string) {
+	f.mu.Lock()
+	sb, ok := f.blocks[id]
+	delete(f.blocks, id)
+	f.mu.Unlock()
+	if !ok {
+		return
+	}
+
+	log.Info("Focus block starting", "id", id, "end", sb.block.End)
+	f.state.SetFocusingUntil(sb.block.End, sb.block.FocusLabel)
+	f.setStatus(id, "started")
+}
+
+// setStatus records a status change, best-effort and asynchronous.
+func (f *FocusScheduler) setStatus(id, status string) {
+	go func() {
+		if err := f.store.SetFocusBlockStatus(id, status); err != nil {
+			log.Error("Failed to update focus block", "id", id, "status", status, "error", err)
+		}
+	}()
+}
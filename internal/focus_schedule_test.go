@@ -0,0 +1,150 @@
+package coach
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"coach/internal/db"
+)
+
+// fakeFocusBlockStore keeps blocks in memory instead of PocketBase.
+type fakeFocusBlockStore struct {
+	mu       sync.Mutex
+	pending  []db.FocusBlock
+	statuses map[string]string
+	nextID   int
+}
+
+func newFakeFocusBlockStore() *fakeFocusBlockStore {
+	return &fakeFocusBlockStore{statuses: map[string]string{}}
+}
+
+func (f *fakeFocusBlockStore) InsertFocusBlock(start, end time.Time, label, project, task string) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.nextID++
+	return "blk" + string(rune('0'+f.nextID)), nil
+}
+
+func (f *fakeFocusBlockStore) SetFocusBlockStatus(recordID, status string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.statuses[recordID] = status
+	return nil
+}
+
+func (f *fakeFocusBlockStore) GetPendingFocusBlocks() ([]db.FocusBlock, error) {
+	return f.pending, nil
+}
+
+func (f *fakeFocusBlockStore) status(id string) string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.statuses[id]
+}
+
+func TestFocusBlockStartsAtItsTime(t *testing.T) {
+	state := &State{}
+	store := newFakeFocusBlockStore()
+	scheduler := NewFocusScheduler(state, store)
+
+	start := time.Now().Add(100 * time.Millisecond)
+	block, err := scheduler.Book(start, start.Add(30*time.Second), FocusLabel{Label: "booked"})
+	if err != nil {
+		t.Fatalf("Book: %v", err)
+	}
+	if isFocusing(state) {
+		t.Fatal("Block should not start before its time")
+	}
+
+	time.Sleep(200 * time.Millisecond)
+	if !isFocusing(state) {
+		t.Fatal("Block should have started")
+	}
+	if timeLeft := remaining(state); timeLeft < 29*time.Second || timeLeft > 31*time.Second {
+		t.Errorf("Expected focus to run to the block end (~30s), got %v", timeLeft)
+	}
+	if len(scheduler.List()) != 0 {
+		t.Error("A started block should no longer be pending")
+	}
+	time.Sleep(10 * time.Millisecond)
+	if got := store.status(block.ID); got != "started" {
+		t.Errorf("Expected block marked started, got %q", got)
+	}
+}
+
+func TestFocusBlockCancel(t *testing.T) {
+	state := &State{}
+	store := newFakeFocusBlockStore()
+	scheduler := NewFocusScheduler(state, store)
+
+	start := time.Now().Add(100 * time.Millisecond)
+	block, _ := scheduler.Book(start, start.Add(time.Minute), FocusLabel{})
+
+	found, err := scheduler.Cancel(block.ID)
+	if !found || err != nil {
+		t.Fatalf("Cancel = %v, %v; want true, nil", found, err)
+	}
+	time.Sleep(200 * time.Millisecond)
+	if isFocusing(state) {
+		t.Error("A cancelled block should never start")
+	}
+	if found, _ := scheduler.Cancel(block.ID); found {
+		t.Error("Cancelling twice should report nothing found")
+	}
+}
+
+func TestFocusBlockRejectsBadSpans(t *testing.T) {
+	scheduler := NewFocusScheduler(&State{}, newFakeFocusBlockStore())
+	now := time.Now()
+
+	if _, err := scheduler.Book(now.Add(time.Hour), now.Add(time.Minute), FocusLabel{}); err == nil {
+		t.Error("End before start should be rejected")
+	}
+	if _, err := scheduler.Book(now.Add(-time.Hour), now.Add(-time.Minute), FocusLabel{}); err == nil {
+		t.Error("A block that is already over should be rejected")
+	}
+}
+
+func TestFocusBlockLoadAfterRestart(t *testing.T) {
+	state := &State{}
+	store := newFakeFocusBlockStore()
+	now := time.Now()
+	store.pending = []db.FocusBlock{
+		{RecordID: "over", StartAt: now.Add(-2 * time.Hour).Format(time.RFC3339), EndAt: now.Add(-time.Hour).Format(time.RFC3339), Status: "pending"},
+		{RecordID: "running", StartAt: now.Add(-time.Minute).Format(time.RFC3339), EndAt: now.Add(time.Minute).Format(time.RFC3339), Status: "pending"},
+		{RecordID: "later", StartAt: now.Add(time.Hour).Format(time.RFC3339), EndAt: now.Add(2 * time.Hour).Format(time.RFC3339), Status: "pending"},
+	}
+	scheduler := NewFocusScheduler(state, store)
+
+	if err := scheduler.Load(); err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	time.Sleep(50 * time.Millisecond)
+
+	if got := store.status("over"); got != "missed" {
+		t.Errorf("Expected block that ended during downtime marked missed, got %q", got)
+	}
+	if !isFocusing(state) {
+		t.Error("A block already under way should start right after restart")
+	}
+	if pending := scheduler.List(); len(pending) != 1 || pending[0].ID != "later" {
+		t.Errorf("Expected only the later block pending, got %+v", pending)
+	}
+}
+
+func TestParseScheduleTime(t *testing.T) {
+	now := time.Date(2026, 6, 10, 9, 0, 0, 0, time.Local)
+
+	got, err := parseScheduleTime("14:00", now)
+	if err != nil || !got.Equal(time.Date(2026, 6, 10, 14, 0, 0, 0, time.Local)) {
+		t.Errorf("parseScheduleTime(14:00) = %v, %v", got, err)
+	}
+	if _, err := parseScheduleTime("2026-06-10T15:30:00Z", now); err != nil {
+		t.Errorf("RFC3339 should parse: %v", err)
+	}
+	if _, err := parseScheduleTime("tomorrow", now); err == nil {
+		t.Error("Garbage should not parse")
+	}
+}
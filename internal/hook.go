@@ -1,6 +1,9 @@
 package coach
 
 import (
+	"context"
+	"fmt"
+	"sync"
 	"time"
 
 	"github.com/charmbracelet/log"
@@ -8,38 +11,466 @@ import (
 	"coach/internal/db"
 )
 
-// Hook is a function that is called when focus state changes.
-type Hook func(*State)
+// focusRecordStore is the slice of db.Manager DatabaseHook writes to (kept narrow for tests).
+type focusRecordStore interface {
+	AddRecord(data map[string]any) error
+	CloseFocusRecord(id string, actual time.Duration, reason, stoppedBy string) error
+}
 
-// DatabaseHook creates a hook that records focus state changes to the database
-func DatabaseHook(manager *db.Manager) Hook {
-	return func(s *State) {
-		if len(s.focusRequests) == 0 {
-			log.Error("No focus requests found")
+// DatabaseHook records every focus period as it is queued and closes it out when
+// it ends, subscribed to the state's event bus. The bus delivers in order, so a
+// close never overtakes the insert of its record.
+func DatabaseHook(manager focusRecordStore) func(Event) {
+	return func(e Event) {
+		var start, end time.Time
+		var label FocusLabel
+		var committed bool
+		var id string
+		switch e := e.(type) {
+		case FocusStarted:
+			start, end, label, committed, id = e.Start, e.End, e.FocusLabel, e.Committed, e.RecordID
+		case FocusExtended:
+			start, end, label, committed, id = e.Start, e.End, e.FocusLabel, e.Committed, e.RecordID
+		case FocusEnded:
+			for _, r := range e.Records {
+				if err := manager.CloseFocusRecord(r.RecordID, r.Focused, e.Reason, e.By); err != nil {
+					log.Error("Failed to close out focus record", "id", r.RecordID, "reason", e.Reason, "error", err)
+				}
+			}
+			return
+		default:
 			return
 		}
 
-		s.mu.Lock()
-		// get latest focus request
-		request := s.focusRequests[len(s.focusRequests)-1]
-		s.mu.Unlock()
-
-		duration := request.EndTime.Sub(request.StartTime)
+		duration := end.Sub(start)
 
 		record := map[string]any{
-			"timestamp": request.StartTime.Format(time.RFC3339),
+			"id":        id,
+			"timestamp": start.Format(time.RFC3339),
 			"duration":  int(duration.Seconds()),
+			"label":     label.Label,
+			"project":   label.Project,
+			"task":      label.Task,
+			"committed": committed,
 		}
 
-		go func() {
-			if err := manager.AddRecord(record); err != nil {
-				log.Error("Failed to add focus record to database", "error", err)
-				return
-			}
+		if err := manager.AddRecord(record); err != nil {
+			log.Error("Failed to add focus record to database", "error", err)
+			return
+		}
+
+		log.Info("Focus record saved to database",
+			"timestamp", record["timestamp"],
+			"duration", duration.String())
+	}
+}
+
+// ParamDef describes one configurable parameter of a hook, for the admin UI.
+type ParamDef struct {
+	Key     string   `json:"key"`
+	Name    string   `json:"name"`
+	Type    string   `json:"type"` // "text", "textarea" or "select"
+	Default string   `json:"default"`
+	Options []string `json:"options,omitempty"` // only for "select"
+}
+
+// HookDef is a hook implemented in Go. The admin configures when it runs and
+// with which params; Run does the work.
+type HookDef struct {
+	ID          string                  `json:"id"`
+	Name        string                  `json:"name"`
+	Description string                  `json:"description"`
+	Params      []ParamDef              `json:"params"`
+	Run         func(HookContext) error `json:"-"`
+}
+
+// HookContext is what a hook run gets: why it ran, the focus state, and its
+// params with defaults filled in.
+type HookContext struct {
+	Trigger string // "scheduled" or "manual"
+	State   *State
+	Params  map[string]string
+
+	hookID string
+	store  hookStore
+	tasks  taskSource
+}
+
+// TodayTasks returns the user's tasks due today or earlier. Hooks that use
+// them should go on without when Configured is false or Error is set.
+func (c HookContext) TodayTasks(ctx context.Context) TodayTasks {
+	return fetchTodayTasks(ctx, c.tasks)
+}
+
+// hookResultMessage is the WebSocket broadcast of a new hook result.
+type hookResultMessage struct {
+	Type string `json:"type"`
+	db.HookResult
+}
+
+// Deliver stores content as a result for the user and broadcasts it to the
+// connected clients. Clients that connect later fetch it from /hook-results.
+func (c HookContext) Deliver(content string) error {
+	result, err := c.store.InsertHookResult(c.hookID, content)
+	if err != nil {
+		return fmt.Errorf("failed to store hook result: %w", err)
+	}
+	log.Info("Hook result delivered", "hook", c.hookID, "id", result.RecordID)
+	go c.State.NotifyAllClients(hookResultMessage{Type: "hook_result", HookResult: result})
+	return nil
+}
+
+// HookConfig says when a hook runs: every Frequency from FirstRun to LastRun
+// (local HH:MM, inclusive), every day.
+type HookConfig struct {
+	HookID    string            `json:"hook_id"`
+	Enabled   bool              `json:"enabled"`
+	Trigger   string            `json:"trigger"`
+	FirstRun  string            `json:"first_run"`
+	LastRun   string            `json:"last_run"`
+	Frequency string            `json:"frequency"`
+	Params    map[string]string `json:"params"`
+}
+
+// defaultHookConfig is the config of a hook nobody has configured yet.
+func defaultHookConfig(id string) HookConfig {
+	return HookConfig{
+		HookID:    id,
+		Trigger:   "scheduled",
+		FirstRun:  "09:00",
+		LastRun:   "21:00",
+		Frequency: "2h",
+		Params:    map[string]string{},
+	}
+}
+
+// Validate rejects configs the scheduler can't evaluate.
+func (c HookConfig) Validate() error {
+	if c.Trigger != "scheduled" {
+		return fmt.Errorf("trigger must be scheduled")
+	}
+	first, err := time.Parse("15:04", c.FirstRun)
+	if err != nil {
+		return fmt.Errorf("first_run must be HH:MM")
+	}
+	last, err := time.Parse("15:04", c.LastRun)
+	if err != nil {
+		return fmt.Errorf("last_run must be HH:MM")
+	}
+	if last.Before(first) {
+		return fmt.Errorf("last_run must not be before first_run")
+	}
+	if freq, err := time.ParseDuration(c.Frequency); err != nil || freq < time.Minute {
+		return fmt.Errorf("frequency must be a duration of at least 1m, like 30m or 2h")
+	}
+	return nil
+}
+
+// nextHookRun returns the first run of c's daily window after now. c must be valid.
+func nextHookRun(c HookConfig, now time.Time) time.Time {
+	first, _ := time.Parse("15:04", c.FirstRun)
+	last, _ := time.Parse("15:04", c.LastRun)
+	freq, _ := time.ParseDuration(c.Frequency)
+	at := func(day time.Time, clock time.Time) time.Time {
+		return time.Date(day.Year(), day.Month(), day.Day(), clock.Hour(), clock.Minute(), 0, 0, day.Location())
+	}
+	for t, end := at(now, first), at(now, last); !t.After(end); t = t.Add(freq) {
+		if t.After(now) {
+			return t
+		}
+	}
+	return at(now.AddDate(0, 0, 1), first)
+}
+
+// hookStore is the slice of db.Manager the hook runner needs (kept narrow for tests).
+type hookStore interface {
+	GetHookConfigs() ([]db.HookConfig, error)
+	SaveHookConfig(c db.HookConfig) (string, error)
+	InsertHookResult(hookID, content string) (db.HookResult, error)
+}
+
+// HookInfo is a registered hook with its current config, as listed to the admin.
+type HookInfo struct {
+	HookDef
+	Config  HookConfig `json:"config"`
+	NextRun *time.Time `json:"next_run"`
+}
+
+// HookRunner runs registered hooks on their daily windows. Configs live in
+// PocketBase; each enabled hook has one timer for its next run, re-armed after
+// every run and whenever its config changes.
+type HookRunner struct {
+	state *State
+	store hookStore
+	tasks taskSource
+
+	mu        sync.Mutex
+	defs      []HookDef
+	configs   map[string]HookConfig
+	recordIDs map[string]string
+	timers    map[string]*time.Timer
+	nextRuns  map[string]time.Time
+}
+
+func NewHookRunner(state *State, store hookStore) *HookRunner {
+	return &HookRunner{
+		state:     state,
+		store:     store,
+		configs:   make(map[string]HookConfig),
+		recordIDs: make(map[string]string),
+		timers:    make(map[string]*time.Timer),
+		nextRuns:  make(map[string]time.Time),
+	}
+}
+
+// UseTasks gives every hook run access to today's tasks through
+// HookContext.TodayTasks. Call before Load.
+func (r *HookRunner) UseTasks(src taskSource) {
+	r.tasks = src
+}
+
+// Register adds a hook implementation. Call before Load.
+func (r *HookRunner) Register(def HookDef) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.defs = append(r.defs, def)
+	r.configs[def.ID] = defaultHookConfig(def.ID)
+}
+
+// Load reads the stored configs and schedules every enabled hook.
+func (r *HookRunner) Load() error {
+	records, err := r.store.GetHookConfigs()
+	if err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, rec := range records {
+		if r.defLocked(rec.HookID) == nil {
+			log.Warn("Skipping config of unknown hook", "hook", rec.HookID)
+			continue
+		}
+		config := hookConfigFromRecord(rec)
+		r.recordIDs[rec.HookID] = rec.RecordID
+		if err := config.Validate(); err != nil {
+			log.Warn("Skipping invalid hook config", "hook", rec.HookID, "error", err)
+			continue
+		}
+		r.configs[rec.HookID] = config
+	}
+	for _, def := range r.defs {
+		r.scheduleLocked(def.ID)
+	}
+	log.Info("Loaded hooks", "registered", len(r.defs), "configured", len(records))
+	return nil
+}
+
+// Hooks lists every registered hook with its config, in registration order.
+func (r *HookRunner) Hooks() []HookInfo {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	out := make([]HookInfo, 0, len(r.defs))
+	for _, def := range r.defs {
+		info := HookInfo{HookDef: def, Config: r.configs[def.ID]}
+		if next, ok := r.nextRuns[def.ID]; ok {
+			info.NextRun = &next
+		}
+		out = append(out, info)
+	}
+	return out
+}
+
+// UpdateConfig stores a hook's new config and reschedules it right away.
+func (r *HookRunner) UpdateConfig(config HookConfig) (HookConfig, error) {
+	if config.Trigger == "" {
+		config.Trigger = "scheduled"
+	}
+	if config.Params == nil {
+		config.Params = map[string]string{}
+	}
+	r.mu.Lock()
+	known := r.defLocked(config.HookID) != nil
+	recordID := r.recordIDs[config.HookID]
+	r.mu.Unlock()
+	if !known {
+		return HookConfig{}, fmt.Errorf("no hook with id %q", config.HookID)
+	}
+	if err := config.Validate(); err != nil {
+		return HookConfig{}, err
+	}
+
+	id, err := r.store.SaveHookConfig(db.HookConfig{
+		RecordID:  recordID,
+		HookID:    config.HookID,
+		Enabled:   config.Enabled,
+		Trigger:   config.Trigger,
+		FirstRun:  config.FirstRun,
+		LastRun:   config.LastRun,
+		Frequency: config.Frequency,
+		Params:    config.Params,
+	})
+	if err != nil {
+		return HookConfig{}, err
+	}
+
+	r.mu.Lock()
+	r.recordIDs[config.HookID] = id
+	r.configs[config.HookID] = config
+	r.scheduleLocked(config.HookID)
+	r.mu.Unlock()
+
+	log.Info("Hook config updated", "hook", config.HookID, "enabled", config.Enabled,
+		"first_run", config.FirstRun, "last_run", config.LastRun, "frequency", config.Frequency)
+	return config, nil
+}
+
+// Has reports whether a hook is registered under id.
+func (r *HookRunner) Has(id string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.defLocked(id) != nil
+}
+
+// RunHook runs a hook once, now, skipping the heuristics scheduled runs obey.
+func (r *HookRunner) RunHook(id string) error {
+	r.mu.Lock()
+	def := r.defLocked(id)
+	config := r.configs[id]
+	r.mu.Unlock()
+	if def == nil {
+		return fmt.Errorf("no hook with id %q", id)
+	}
+	return r.run(*def, config, "manual")
+}
+
+// defLocked returns the hook registered under id, or nil. Must be called with r.mu held.
+func (r *HookRunner) defLocked(id string) *HookDef {
+	for i := range r.defs {
+		if r.defs[i].ID == id {
+			return &r.defs[i]
+		}
+	}
+	return nil
+}
+
+// scheduleLocked re-arms the timer for a hook's next run, or drops it if the
+// hook is disabled. Must be called with r.mu held.
+func (r *HookRunner) scheduleLocked(id string) {
+	if t := r.timers[id]; t != nil {
+		t.Stop()
+		delete(r.timers, id)
+	}
+	delete(r.nextRuns, id)
+	config := r.configs[id]
+	if !config.Enabled || config.Validate() != nil {
+		return
+	}
+	next := nextHookRun(config, time.Now())
+	r.nextRuns[id] = next
+	r.timers[id] = time.AfterFunc(time.Until(next), func() { r.fire(id) })
+}
+
+// fire is a scheduled run. It skips the tick when nobody is connected to see
+// the result or the user is focusing, then arms the next one either way.
+func (r *HookRunner) fire(id string) {
+	r.mu.Lock()
+	def := r.defLocked(id)
+	config := r.configs[id]
+	r.scheduleLocked(id)
+	r.mu.Unlock()
+	if def == nil || !config.Enabled {
+		return
+	}
 
-			log.Info("Focus record saved to database",
-				"timestamp", record["timestamp"],
-				"duration", duration.String())
-		}()
+	if !r.state.HasClients() {
+		log.Debug("Skipping hook run, no clients connected", "hook", id)
+		return
+	}
+	if r.state.GetCurrentFocusInfo().Focusing {
+		log.Debug("Skipping hook run while focusing", "hook", id)
+		return
+	}
+	if err := r.run(*def, config, "scheduled"); err != nil {
+		log.Error("Hook run failed", "hook", id, "error", err)
+	}
+}
+
+// run calls the hook with its params resolved. A panicking hook is turned into an error.
+func (r *HookRunner) run(def HookDef, config HookConfig, trigger string) (err error) {
+	params := make(map[string]string, len(def.Params))
+	for _, p := range def.Params {
+		params[p.Key] = p.Default
+	}
+	for k, v := range config.Params {
+		params[k] = v
+	}
+
+	defer func() {
+		if p := recover(); p != nil {
+			err = fmt.Errorf("hook %s panicked: %v", def.ID, p)
+		}
+	}()
+	log.Info("Running hook", "hook", def.ID, "trigger", trigger)
+	return def.Run(HookContext{Trigger: trigger, State: r.state, Params: params, hookID: def.ID, store: r.store, tasks: r.tasks})
+}
+
+func hookConfigFromRecord(rec db.HookConfig) HookConfig {
+	config := HookConfig{
+		HookID:    rec.HookID,
+		Enabled:   rec.Enabled,
+		Trigger:   rec.Trigger,
+		FirstRun:  rec.FirstRun,
+		LastRun:   rec.LastRun,
+		Frequency: rec.Frequency,
+		Params:    rec.Params,
+	}
+	if config.Trigger == "" {
+		config.Trigger = "scheduled"
+	}
+	if config.Params == nil {
+		config.Params = map[string]string{}
+	}
+	return config
+}
+
+// hookResultsReader is the slice of db.Manager unseenHookResults reads (kept narrow for tests).
+type hookResultsReader interface {
+	GetHookResultsBefore(created, id string, limit int) ([]db.HookResult, error)
+	GetSeenHookResults(client string, resultIDs []string) (map[string]bool, error)
+}
+
+// unseenHookResults returns up to limit results client hasn't seen, newest
+// first. It pages back through every result until it has limit of them, so a
+// device that was away for long still gets the older ones on later polls.
+func unseenHookResults(store hookResultsReader, client string, limit int) ([]db.HookResult, error) {
+	unseen := []db.HookResult{}
+	var created, id string
+	for {
+		page, err := store.GetHookResultsBefore(created, id, limit)
+		if err != nil {
+			return nil, err
+		}
+		ids := make([]string, len(page))
+		for i, res := range page {
+			ids[i] = res.RecordID
+		}
+		seen, err := store.GetSeenHookResults(client, ids)
+		if err != nil {
+			return nil, err
+		}
+		for _, res := range page {
+			if !seen[res.RecordID] {
+				unseen = append(unseen, res)
+				if len(unseen) == limit {
+					return unseen, nil
+				}
+			}
+		}
+		if len(page) < limit {
+			return unseen, nil
+		}
+		last := page[len(page)-1]
+		created, id = last.Created, last.RecordID
 	}
 }
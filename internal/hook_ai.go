@@ -0,0 +1,150 @@
+package coach
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/log"
+
+	"coach/internal/ai"
+	"coach/internal/db"
+	"coach/internal/stats"
+)
+
+// defaultCoachPrompt is the ai_request hook's system prompt until the admin changes it.
+const defaultCoachPrompt = `You are a focus coach. You get a snapshot of the user's day: focus sessions, ` +
+	`where their attention went, what they asked the agent lock for, how often they were tempted ` +
+	`and, when known, the tasks due today. Reply with two or three sentences: name what you see, ` +
+	`then suggest one concrete next step, usually a focus session on one of their tasks. ` +
+	`Be direct and specific, never cheesy.`
+
+// coachContextStore is the slice of db.Manager the AI hook reads context from (kept narrow for tests).
+type coachContextStore interface {
+	GetAttentionIntervals(from, to time.Time) ([]db.AttentionInterval, error)
+	GetTodayLockDecisions() ([]db.LockDecision, error)
+	CountTodayTemptations() (int, error)
+}
+
+// NewAIHookDef is the ai_request hook: it sends today's coach context to the
+// model and delivers the reply. defaultModel seeds the model param.
+func NewAIHookDef(client *ai.Client, store coachContextStore, defaultModel string) HookDef {
+	return HookDef{
+		ID:          "ai_request",
+		Name:        "AI Coaching Prompt",
+		Description: "Sends today's focus, attention and lock context to the AI and delivers its reply",
+		Params: []ParamDef{
+			{Key: "model", Name: "Model", Type: "text", Default: defaultModel},
+			{Key: "prompt", Name: "System prompt", Type: "textarea", Default: defaultCoachPrompt},
+		},
+		Run: func(ctx HookContext) error {
+			model := ctx.Params["model"]
+			if model == "" {
+				return fmt.Errorf("model param is empty")
+			}
+			callCtx, cancel := context.WithTimeout(context.Background(), 90*time.Second)
+			defer cancel()
+			message := buildCoachContext(ctx.State, store, ctx.TodayTasks(callCtx), time.Now())
+
+			reply, err := client.Complete(callCtx, model, ctx.Params["prompt"], message)
+			if err != nil {
+				return err
+			}
+			return ctx.Deliver(reply)
+		},
+	}
+}
+
+// buildCoachContext renders what the coach knows about today as the user
+// message. A section whose data can't be read is left out rather than failing
+// the whole run.
+func buildCoachContext(state *State, store coachContextStore, tasks TodayTasks, now time.Time) string {
+	var b strings.Builder
+	info := state.GetCurrentFocusInfo()
+
+	b.WriteString("## Focus\n")
+	fmt.Fprintf(&b, "- Currently focusing: %t\n", info.Focusing)
+	if info.Focusing || info.Paused {
+		fmt.Fprintf(&b, "- Time left: %s\n", (info.FocusTimeLeft * time.Second).Round(time.Second))
+	}
+	if info.Paused {
+		b.WriteString("- Session is paused\n")
+	}
+	fmt.Fprintf(&b, "- Sessions today: %d\n", info.NumFocuses)
+	fmt.Fprintf(&b, "- Time since last change: %ds\n", int(info.SinceLastChange))
+
+	dayStart := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	if intervals, err := store.GetAttentionIntervals(dayStart, now); err != nil {
+		log.Warn("AI hook: skipping attention context", "error", err)
+	} else {
+		summary := stats.SummarizeAttention(intervals, dayStart, now)
+		b.WriteString("\n## Attention today\n")
+		if summary.Now != nil {
+			if summary.Now.Site != "" {
+				fmt.Fprintf(&b, "- Right now: %s on %s for %d min\n", summary.Now.State, summary.Now.Site, summary.Now.Minutes)
+			} else {
+				fmt.Fprintf(&b, "- Right now: %s for %d min\n", summary.Now.State, summary.Now.Minutes)
+			}
+		}
+		fmt.Fprintf(&b, "- Minutes on sites: %d\n", summary.SiteMinutesToday)
+		for _, s := range summary.TopSitesToday {
+			fmt.Fprintf(&b, "- %s: %d min\n", s.Site, s.Minutes)
+		}
+	}
+
+	b.WriteString("\n## Agent lock today\n")
+	lock := state.GetAgentLockInfo()
+	if left := lock.TimeLeftSeconds; left != nil {
+		fmt.Fprintf(&b, "- Released for %ds more\n", *left)
+	} else if lock.HeldByFocus {
+		b.WriteString("- Locked for the running focus session\n")
+	} else {
+		b.WriteString("- Locked\n")
+	}
+	if lock.CooldownSeconds > 0 {
+		fmt.Fprintf(&b, "- Cooldown: no grant for %ds more\n", lock.CooldownSeconds)
+	}
+	if decisions, err := store.GetTodayLockDecisions(); err != nil {
+		log.Warn("AI hook: skipping lock decision context", "error", err)
+	} else {
+		counts := map[string]int{}
+		for _, d := range decisions {
+			// A lock policy window is journaled as a grant nobody asked for
+			if d.Source != "schedule" {
+				counts[d.Kind]++
+			}
+		}
+		fmt.Fprintf(&b, "- Grants: %d, overrides: %d, denials: %d, broken commitments: %d\n",
+			counts["grant"], counts["override"], counts["denial"], counts["broken_commitment"])
+		// The latest plea, not a turn, a policy window or the end of a release
+		for i := len(decisions) - 1; i >= 0; i-- {
+			if last := decisions[i]; isPleaDecision(last) {
+				fmt.Fprintf(&b, "- Latest (%s): %q\n", last.Kind, last.UserMessage)
+				break
+			}
+		}
+	}
+
+	if count, err := store.CountTodayTemptations(); err != nil {
+		log.Warn("AI hook: skipping temptation context", "error", err)
+	} else {
+		fmt.Fprintf(&b, "\n## Temptations today\n- Blocked attempts: %d\n", count)
+	}
+
+	if tasks.Configured && tasks.Error == "" {
+		b.WriteString("\n## Today's Tasks\n")
+		if len(tasks.Tasks) == 0 {
+			b.WriteString("- Nothing due\n")
+		}
+		for i, t := range tasks.Tasks {
+			if t.Project != nil && t.Project.Name != "" {
+				fmt.Fprintf(&b, "%d. %s (project: %s)\n", i+1, t.Title, t.Project.Name)
+			} else {
+				fmt.Fprintf(&b, "%d. %s\n", i+1, t.Title)
+			}
+		}
+	}
+
+	return b.String()
+}
@@ -0,0 +1,118 @@
+package coach
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"coach/internal/ai"
+	"coach/internal/db"
+	"coach/internal/dimaist"
+)
+
+// fakeCoachContextStore serves canned context instead of PocketBase.
+type fakeCoachContextStore struct {
+	intervals   []db.AttentionInterval
+	decisions   []db.LockDecision
+	temptations int
+	failLocks   bool
+}
+
+func (f *fakeCoachContextStore) GetAttentionIntervals(from, to time.Time) ([]db.AttentionInterval, error) {
+	return f.intervals, nil
+}
+
+func (f *fakeCoachContextStore) GetTodayLockDecisions() ([]db.LockDecision, error) {
+	if f.failLocks {
+		return nil, fmt.Errorf("pb down")
+	}
+	return f.decisions, nil
+}
+
+func (f *fakeCoachContextStore) CountTodayTemptations() (int, error) { return f.temptations, nil }
+
+// fakeTaskSource serves canned tasks instead of Dimaist.
+type fakeTaskSource struct {
+	tasks []dimaist.Task
+	err   error
+}
+
+func (f *fakeTaskSource) GetTodayTasks(ctx context.Context) ([]dimaist.Task, error) {
+	return f.tasks, f.err
+}
+
+func TestAIHookDeliversReplyToCoachContext(t *testing.T) {
+	var prompt struct {
+		Model    string `json:"model"`
+		Messages []struct {
+			Content string `json:"content"`
+		} `json:"messages"`
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&prompt)
+		w.Write([]byte(`{"choices":[{"message":{"role":"assistant","content":"Close reddit, start 25 minutes."}}]}`))
+	}))
+	defer server.Close()
+
+	now := time.Now()
+	contextStore := &fakeCoachContextStore{
+		intervals: []db.AttentionInterval{{
+			State: "site", Site: "reddit.com",
+			StartedAt: now.Add(-20 * time.Minute).UTC().Format(time.RFC3339),
+			LastSeen:  now.UTC().Format(time.RFC3339),
+		}},
+		decisions:   []db.LockDecision{{Kind: "denial", UserMessage: "just a peek"}},
+		temptations: 3,
+	}
+	hookStore := &fakeHookConfigStore{}
+	runner := NewHookRunner(&State{}, hookStore)
+	runner.UseTasks(&fakeTaskSource{tasks: []dimaist.Task{{Title: "Write API docs", Project: &dimaist.Project{Name: "Work"}}}})
+	runner.Register(NewAIHookDef(ai.NewClient(server.URL, ""), contextStore, "test-model"))
+
+	if err := runner.RunHook("ai_request"); err != nil {
+		t.Fatalf("RunHook: %v", err)
+	}
+	if prompt.Model != "test-model" || len(prompt.Messages) != 2 {
+		t.Fatalf("Unexpected request: %+v", prompt)
+	}
+	user := prompt.Messages[1].Content
+	for _, want := range []string{"Currently focusing: false", "reddit.com", "denials: 1", `"just a peek"`, "Blocked attempts: 3", "1. Write API docs (project: Work)"} {
+		if !strings.Contains(user, want) {
+			t.Errorf("Expected %q in context:\n%s", want, user)
+		}
+	}
+	if len(hookStore.results) != 1 || hookStore.results[0].Content != "Close reddit, start 25 minutes." {
+		t.Errorf("Expected the reply to be delivered, got %+v", hookStore.results)
+	}
+}
+
+func TestCoachContextLatestPleaSkipsTurns(t *testing.T) {
+	store := &fakeCoachContextStore{decisions: []db.LockDecision{
+		{Kind: "denial", UserMessage: "just a peek"},
+		{Kind: "grant", Source: "schedule", AgentMessage: "Lock policy window open"},
+		{Kind: "turn", UserMessage: "but why not"},
+	}}
+	message := buildCoachContext(&State{}, store, TodayTasks{}, time.Now())
+	if !strings.Contains(message, `- Latest (denial): "just a peek"`) {
+		t.Errorf("Expected the denial as the latest plea:\n%s", message)
+	}
+	if !strings.Contains(message, "- Grants: 0, overrides: 0, denials: 1") {
+		t.Errorf("A policy window should not count as a grant:\n%s", message)
+	}
+}
+
+func TestCoachContextSkipsFailingSections(t *testing.T) {
+	tasks := fetchTodayTasks(context.Background(), &fakeTaskSource{err: fmt.Errorf("dimaist down")})
+	message := buildCoachContext(&State{}, &fakeCoachContextStore{failLocks: true}, tasks, time.Now())
+	if strings.Contains(message, "Grants:") || strings.Contains(message, "Today's Tasks") {
+		t.Errorf("A failed read should leave its lines out:\n%s", message)
+	}
+	if !strings.Contains(message, "## Temptations today") {
+		t.Errorf("Other sections should still render:\n%s", message)
+	}
+}
@@ -0,0 +1,214 @@
+package coach
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/charmbracelet/log"
+)
+
+// commandHookEvents are the focus transitions a command hook can run on.
+var commandHookEvents = []string{FocusStarted{}.Name(), FocusExtended{}.Name(), FocusEnded{}.Name()}
+
+// defaultCommandTimeout bounds a command that doesn't set its own timeout.
+const defaultCommandTimeout = 30 * time.Second
+
+// CommandHook runs a local shell command on a focus transition. Commands come
+// from a file on the server, never from the API: the API can only switch them
+// on and off.
+type CommandHook struct {
+	Name           string `json:"name"`
+	On             string `json:"on"`      // focus_started, focus_extended or focus_ended
+	Command        string `json:"command"` // run with sh -c
+	TimeoutSeconds int    `json:"timeout_seconds,omitempty"`
+	Enabled        bool   `json:"enabled"`
+}
+
+// Validate rejects commands the runner can't run.
+func (c CommandHook) Validate() error {
+	if c.Name == "" {
+		return fmt.Errorf("name must not be empty")
+	}
+	known := false
+	for _, e := range commandHookEvents {
+		known = known || e == c.On
+	}
+	if !known {
+		return fmt.Errorf("on must be one of %s", strings.Join(commandHookEvents, ", "))
+	}
+	if strings.TrimSpace(c.Command) == "" {
+		return fmt.Errorf("command must not be empty")
+	}
+	if c.TimeoutSeconds < 0 {
+		return fmt.Errorf("timeout_seconds must not be negative")
+	}
+	return nil
+}
+
+func (c CommandHook) timeout() time.Duration {
+	if c.TimeoutSeconds == 0 {
+		return defaultCommandTimeout
+	}
+	return time.Duration(c.TimeoutSeconds) * time.Second
+}
+
+// CommandHookRunner runs the configured commands on focus transitions,
+// subscribed to the same event bus as DatabaseHook. The commands live in a
+// JSON file (COMMAND_HOOKS_FILE); toggling one rewrites the file.
+type CommandHookRunner struct {
+	state *State
+	path  string
+
+	mu       sync.Mutex
+	commands []CommandHook
+}
+
+// LoadCommandHooks reads the command file. A path that doesn't exist yet
+// gives a runner with no commands.
+func LoadCommandHooks(state *State, path string) (*CommandHookRunner, error) {
+	r := &CommandHookRunner{state: state, path: path}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return r, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	var commands []CommandHook
+	if err := json.Unmarshal(data, &commands); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	seen := map[string]bool{}
+	for _, c := range commands {
+		if err := c.Validate(); err != nil {
+			return nil, fmt.Errorf("command hook %q: %w", c.Name, err)
+		}
+		if seen[c.Name] {
+			return nil, fmt.Errorf("command hook %q is defined twice", c.Name)
+		}
+		seen[c.Name] = true
+	}
+	r.commands = commands
+	log.Info("Loaded command hooks", "path", path, "count", len(commands))
+	return r, nil
+}
+
+// Commands returns the configured commands in file order.
+func (r *CommandHookRunner) Commands() []CommandHook {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]CommandHook{}, r.commands...)
+}
+
+// SetEnabled switches a command on or off and saves the file. Returns false if
+// no command has that name.
+func (r *CommandHookRunner) SetEnabled(name string, enabled bool) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	i := -1
+	for j, c := range r.commands {
+		if c.Name == name {
+			i = j
+		}
+	}
+	if i < 0 {
+		return false, nil
+	}
+	r.commands[i].Enabled = enabled
+	data, err := json.MarshalIndent(r.commands, "", "  ")
+	if err != nil {
+		return true, err
+	}
+	if err := os.WriteFile(r.path, append(data, '\n'), 0o600); err != nil {
+		return true, fmt.Errorf("failed to write %s: %w", r.path, err)
+	}
+	log.Info("Command hook toggled", "name", name, "enabled", enabled)
+	return true, nil
+}
+
+// Handle runs every enabled command for the event's transition, in file order.
+func (r *CommandHookRunner) Handle(e Event) {
+	r.mu.Lock()
+	var matching []CommandHook
+	for _, c := range r.commands {
+		if c.Enabled && c.On == e.Name() {
+			matching = append(matching, c)
+		}
+	}
+	r.mu.Unlock()
+	if len(matching) == 0 {
+		return
+	}
+
+	env := append(os.Environ(), r.commandEnv(e)...)
+	for _, c := range matching {
+		r.run(c, env)
+	}
+}
+
+// commandEnv describes the coach to a command: the event, the focus state and
+// the agent lock.
+func (r *CommandHookRunner) commandEnv(e Event) []string {
+	info := r.state.GetCurrentFocusInfo()
+	lock := r.state.GetAgentLockInfo()
+	env := map[string]string{
+		"COACH_EVENT":             e.Name(),
+		"COACH_FOCUSING":          strconv.FormatBool(info.Focusing),
+		"COACH_TIME_LEFT_SECONDS": strconv.Itoa(int(info.FocusTimeLeft)),
+		"COACH_AGENT_LOCKED":      strconv.FormatBool(lock.TimeLeftSeconds == nil),
+	}
+	if lock.TimeLeftSeconds != nil {
+		env["COACH_AGENT_RELEASE_SECONDS"] = strconv.FormatInt(*lock.TimeLeftSeconds, 10)
+	}
+	switch e := e.(type) {
+	case FocusStarted:
+		env["COACH_DURATION_SECONDS"] = strconv.Itoa(int(e.End.Sub(e.Start) / time.Second))
+		env["COACH_LABEL"], env["COACH_PROJECT"], env["COACH_TASK"] = e.Label, e.Project, e.Task
+	case FocusExtended:
+		env["COACH_DURATION_SECONDS"] = strconv.Itoa(int(e.End.Sub(e.Start) / time.Second))
+		env["COACH_LABEL"], env["COACH_PROJECT"], env["COACH_TASK"] = e.Label, e.Project, e.Task
+	case FocusEnded:
+		env["COACH_END_REASON"] = e.Reason
+		env["COACH_FOCUSED_SECONDS"] = strconv.Itoa(e.FocusedSeconds)
+	}
+
+	out := make([]string, 0, len(env))
+	for k, v := range env {
+		out = append(out, k+"="+v)
+	}
+	return out
+}
+
+// run executes one command under its timeout and logs what it printed.
+func (r *CommandHookRunner) run(c CommandHook, env []string) {
+	ctx, cancel := context.WithTimeout(context.Background(), c.timeout())
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, "sh", "-c", c.Command)
+	cmd.Env = env
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	// A background child of the shell can hold the output pipes open past the timeout
+	cmd.WaitDelay = time.Second
+
+	start := time.Now()
+	err := cmd.Run()
+	fields := []any{"name", c.Name, "took", time.Since(start),
+		"stdout", strings.TrimSpace(stdout.String()), "stderr", strings.TrimSpace(stderr.String())}
+	switch {
+	case ctx.Err() == context.DeadlineExceeded:
+		log.Error("Command hook timed out", append(fields, "timeout", c.timeout())...)
+	case err != nil:
+		log.Error("Command hook failed", append(fields, "error", err)...)
+	default:
+		log.Info("Command hook ran", fields...)
+	}
+}
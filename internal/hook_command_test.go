@@ -0,0 +1,100 @@
+package coach
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writeCommandHooks(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "command-hooks.json")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestCommandHookGetsCoachEnv(t *testing.T) {
+	out := filepath.Join(t.TempDir(), "env.txt")
+	path := writeCommandHooks(t, `[
+		{"name": "dnd", "on": "focus_started", "command": "env | grep ^COACH_ > `+out+`", "enabled": true},
+		{"name": "off", "on": "focus_started", "command": "touch `+out+`.off", "enabled": false}
+	]`)
+	state := &State{}
+	runner, err := LoadCommandHooks(state, path)
+	if err != nil {
+		t.Fatalf("LoadCommandHooks: %v", err)
+	}
+
+	start := time.Now()
+	runner.Handle(FocusStarted{Start: start, End: start.Add(25 * time.Minute), FocusLabel: FocusLabel{Label: "write"}})
+	runner.Handle(FocusEnded{Reason: "stopped"}) // no command for this event
+
+	data, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatalf("Command did not run: %v", err)
+	}
+	for _, want := range []string{
+		"COACH_EVENT=focus_started", "COACH_DURATION_SECONDS=1500", "COACH_LABEL=write", "COACH_AGENT_LOCKED=true",
+	} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("Expected %s in env:\n%s", want, data)
+		}
+	}
+	if _, err := os.Stat(out + ".off"); err == nil {
+		t.Error("A disabled command should not run")
+	}
+}
+
+func TestCommandHookTimesOut(t *testing.T) {
+	path := writeCommandHooks(t, `[{"name": "slow", "on": "focus_ended", "command": "sleep 10", "timeout_seconds": 1, "enabled": true}]`)
+	runner, err := LoadCommandHooks(&State{}, path)
+	if err != nil {
+		t.Fatalf("LoadCommandHooks: %v", err)
+	}
+
+	start := time.Now()
+	runner.Handle(FocusEnded{Reason: "expired"})
+	if took := time.Since(start); took > 5*time.Second {
+		t.Errorf("Timed-out command should be killed, took %v", took)
+	}
+}
+
+func TestCommandHookToggleSavesFile(t *testing.T) {
+	path := writeCommandHooks(t, `[{"name": "music", "on": "focus_started", "command": "true", "enabled": true}]`)
+	runner, err := LoadCommandHooks(&State{}, path)
+	if err != nil {
+		t.Fatalf("LoadCommandHooks: %v", err)
+	}
+
+	if found, err := runner.SetEnabled("music", false); !found || err != nil {
+		t.Fatalf("SetEnabled: found=%v err=%v", found, err)
+	}
+	if found, _ := runner.SetEnabled("nope", true); found {
+		t.Error("Toggling an unknown command should report not found")
+	}
+
+	reloaded, err := LoadCommandHooks(&State{}, path)
+	if err != nil {
+		t.Fatalf("Reload: %v", err)
+	}
+	if c := reloaded.Commands(); len(c) != 1 || c[0].Enabled {
+		t.Errorf("Expected the toggle to persist, got %+v", c)
+	}
+}
+
+func TestLoadCommandHooksRejectsBadFile(t *testing.T) {
+	for name, content := range map[string]string{
+		"unknown event": `[{"name": "x", "on": "lunch", "command": "true"}]`,
+		"no command":    `[{"name": "x", "on": "focus_ended"}]`,
+		"duplicate":     `[{"name": "x", "on": "focus_ended", "command": "true"}, {"name": "x", "on": "focus_started", "command": "true"}]`,
+		"not json":      `nope`,
+	} {
+		if _, err := LoadCommandHooks(&State{}, writeCommandHooks(t, content)); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
@@ -0,0 +1,279 @@
+package coach
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"coach/internal/db"
+)
+
+// fakeHookConfigStore keeps hook configs and results in memory instead of PocketBase.
+type fakeHookConfigStore struct {
+	configs []db.HookConfig
+	results []db.HookResult
+	saves   int
+}
+
+func (f *fakeHookConfigStore) GetHookConfigs() ([]db.HookConfig, error) { return f.configs, nil }
+
+func (f *fakeHookConfigStore) SaveHookConfig(c db.HookConfig) (string, error) {
+	f.saves++
+	if c.RecordID == "" {
+		c.RecordID = fmt.Sprintf("hook%d", f.saves)
+	}
+	return c.RecordID, nil
+}
+
+func (f *fakeHookConfigStore) InsertHookResult(hookID, content string) (db.HookResult, error) {
+	r := db.HookResult{RecordID: fmt.Sprintf("result%d", len(f.results)+1), HookID: hookID, Content: content}
+	f.results = append(f.results, r)
+	return r, nil
+}
+
+func TestNextHookRun(t *testing.T) {
+	config := HookConfig{Trigger: "scheduled", FirstRun: "09:00", LastRun: "21:00", Frequency: "2h"}
+	day := func(h, m int) time.Time { return time.Date(2026, 3, 2, h, m, 0, 0, time.Local) }
+
+	cases := []struct {
+		name string
+		now  time.Time
+		want time.Time
+	}{
+		{"before window", day(7, 30), day(9, 0)},
+		{"between runs", day(10, 15), day(11, 0)},
+		{"exactly on a run", day(11, 0), day(13, 0)},
+		{"last run is inclusive", day(20, 59), day(21, 0)},
+		{"after window rolls to tomorrow", day(21, 0), day(9, 0).AddDate(0, 0, 1)},
+	}
+	for _, c := range cases {
+		if got := nextHookRun(config, c.now); !got.Equal(c.want) {
+			t.Errorf("%s: next run = %v, want %v", c.name, got, c.want)
+		}
+	}
+}
+
+func TestHookConfigValidate(t *testing.T) {
+	valid := HookConfig{Trigger: "scheduled", FirstRun: "09:00", LastRun: "21:00", Frequency: "30m"}
+	if err := valid.Validate(); err != nil {
+		t.Fatalf("Expected valid config, got %v", err)
+	}
+	for name, mutate := range map[string]func(*HookConfig){
+		"unknown trigger":  func(c *HookConfig) { c.Trigger = "cron" },
+		"bad first_run":    func(c *HookConfig) { c.FirstRun = "9am" },
+		"window backwards": func(c *HookConfig) { c.LastRun = "08:00" },
+		"bad frequency":    func(c *HookConfig) { c.Frequency = "often" },
+		"tiny frequency":   func(c *HookConfig) { c.Frequency = "10s" },
+	} {
+		c := valid
+		mutate(&c)
+		if err := c.Validate(); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestHookRunnerMergesParamsAndSchedules(t *testing.T) {
+	store := &fakeHookConfigStore{}
+	runner := NewHookRunner(&State{}, store)
+	var got HookContext
+	runner.Register(HookDef{
+		ID:     "echo",
+		Params: []ParamDef{{Key: "greeting", Default: "hi"}, {Key: "name", Default: "you"}},
+		Run:    func(ctx HookContext) error { got = ctx; return nil },
+	})
+	if err := runner.Load(); err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if info := runner.Hooks(); len(info) != 1 || info[0].NextRun != nil {
+		t.Fatalf("An unconfigured hook should be listed but not scheduled, got %+v", info)
+	}
+
+	if _, err := runner.UpdateConfig(HookConfig{
+		HookID: "echo", Enabled: true, FirstRun: "00:00", LastRun: "23:59", Frequency: "1m",
+		Params: map[string]string{"name": "coach"},
+	}); err != nil {
+		t.Fatalf("UpdateConfig: %v", err)
+	}
+	if info := runner.Hooks(); info[0].NextRun == nil || time.Until(*info[0].NextRun) > 2*time.Minute {
+		t.Errorf("Enabled hook should be rescheduled within a minute, got %+v", info[0].NextRun)
+	}
+
+	if err := runner.RunHook("echo"); err != nil {
+		t.Fatalf("RunHook: %v", err)
+	}
+	if got.Trigger != "manual" || got.Params["greeting"] != "hi" || got.Params["name"] != "coach" {
+		t.Errorf("Unexpected hook context: %+v", got)
+	}
+
+	if _, err := runner.UpdateConfig(HookConfig{HookID: "nope", FirstRun: "09:00", LastRun: "10:00", Frequency: "1h"}); err == nil {
+		t.Error("Configuring an unregistered hook should fail")
+	}
+}
+
+func TestHookRunnerScheduledRunNeedsClients(t *testing.T) {
+	runner := NewHookRunner(&State{}, &fakeHookConfigStore{})
+	ran := false
+	runner.Register(HookDef{ID: "nudge", Run: func(HookContext) error { ran = true; return nil }})
+	runner.mu.Lock()
+	runner.configs["nudge"] = HookConfig{HookID: "nudge", Enabled: true, Trigger: "scheduled", FirstRun: "00:00", LastRun: "23:59", Frequency: "1h"}
+	runner.mu.Unlock()
+
+	runner.fire("nudge")
+	if ran {
+		t.Error("A scheduled run with nobody connected should be skipped")
+	}
+	if info := runner.Hooks(); info[0].NextRun == nil {
+		t.Error("A skipped run should still arm the next one")
+	}
+}
+
+func TestHookRunnerRecoversPanics(t *testing.T) {
+	runner := NewHookRunner(&State{}, &fakeHookConfigStore{})
+	runner.Register(HookDef{ID: "boom", Run: func(HookContext) error { panic("boom") }})
+	if err := runner.RunHook("boom"); err == nil {
+		t.Error("A panicking hook should surface as an error")
+	}
+}
+
+func TestHookDeliverStoresResult(t *testing.T) {
+	store := &fakeHookConfigStore{}
+	runner := NewHookRunner(&State{}, store)
+	runner.Register(HookDef{ID: "nudge", Run: func(ctx HookContext) error { return ctx.Deliver("stretch") }})
+
+	if err := runner.RunHook("nudge"); err != nil {
+		t.Fatalf("RunHook: %v", err)
+	}
+	if len(store.results) != 1 || store.results[0].HookID != "nudge" || store.results[0].Content != "stretch" {
+		t.Errorf("Expected one stored nudge result, got %+v", store.results)
+	}
+}
+
+func TestHookResultsUnseenNeedsClient(t *testing.T) {
+	server := &Server{State: &State{}}
+
+	req := httptest.NewRequest(http.MethodGet, "/hook-results?unseen=true", nil)
+	rr := httptest.NewRecorder()
+	server.HookResultsHandler(rr, req)
+	if rr.Code != http.StatusBadRequest {
+		t.Fatalf("Expected 400 without client, got %d: %s", rr.Code, rr.Body.String())
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/hook-results/seen", strings.NewReader(`{"client":"phone"}`))
+	rr = httptest.NewRecorder()
+	server.HookResultsHandler(rr, req)
+	if rr.Code != http.StatusBadRequest {
+		t.Fatalf("Expected 400 without ids, got %d: %s", rr.Code, rr.Body.String())
+	}
+}
+
+// fakeFocusRecordStore logs DatabaseHook's writes, and fails a close whose
+// record was never inserted, as PB would.
+type fakeFocusRecordStore struct {
+	mu     sync.Mutex
+	writes []string
+	ids    map[string]bool
+}
+
+func (f *fakeFocusRecordStore) AddRecord(data map[string]any) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.ids[data["id"].(string)] = true
+	f.writes = append(f.writes, "add")
+	return nil
+}
+
+func (f *fakeFocusRecordStore) CloseFocusRecord(id string, actual time.Duration, reason, stoppedBy string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if !f.ids[id] {
+		return fmt.Errorf("no record %s", id)
+	}
+	f.writes = append(f.writes, "close:"+reason+":"+stoppedBy)
+	return nil
+}
+
+func TestDatabaseHookClosesTheRecordItInserted(t *testing.T) {
+	store := &fakeFocusRecordStore{ids: map[string]bool{}}
+	state := &State{}
+	state.Events().Subscribe(DatabaseHook(store))
+
+	// Stopped straight after it started: the close must still find its record
+	state.SetFocusing(time.Minute)
+	state.HandleFocusChange(false, 0, FocusLabel{}, "phone")
+
+	deadline := time.Now().Add(time.Second)
+	for {
+		store.mu.Lock()
+		writes := append([]string(nil), store.writes...)
+		store.mu.Unlock()
+		if len(writes) == 2 {
+			if writes[0] != "add" || writes[1] != "close:stopped:phone" {
+				t.Errorf("Expected the insert then the close, got %v", writes)
+			}
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected two writes, got %v", writes)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+// fakeHookResultsReader pages results (newest first) and the seen set from memory.
+type fakeHookResultsReader struct {
+	results []db.HookResult
+	seen    map[string]bool
+}
+
+func (f *fakeHookResultsReader) GetHookResultsBefore(created, id string, limit int) ([]db.HookResult, error) {
+	var out []db.HookResult
+	for _, r := range f.results {
+		if created != "" && (r.Created > created || r.Created == created && r.RecordID >= id) {
+			continue
+		}
+		if len(out) == limit {
+			break
+		}
+		out = append(out, r)
+	}
+	return out, nil
+}
+
+func (f *fakeHookResultsReader) GetSeenHookResults(client string, ids []string) (map[string]bool, error) {
+	seen := map[string]bool{}
+	for _, id := range ids {
+		seen[id] = f.seen[id]
+	}
+	return seen, nil
+}
+
+func TestUnseenHookResultsReachPastTheNewest(t *testing.T) {
+	store := &fakeHookResultsReader{seen: map[string]bool{}}
+	for i := 250; i > 0; i-- {
+		id := fmt.Sprintf("r%03d", i)
+		store.results = append(store.results, db.HookResult{RecordID: id, Created: fmt.Sprintf("2026-03-01 10:00:00.%03dZ", i)})
+		if i > 130 {
+			store.seen[id] = true // the device saw the newest 120 before going away
+		}
+	}
+
+	got, err := unseenHookResults(store, "phone", 20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 20 || got[0].RecordID != "r130" || got[19].RecordID != "r111" {
+		t.Errorf("Expected r130..r111, got %d results starting %v", len(got), got)
+	}
+
+	for _, r := range store.results {
+		store.seen[r.RecordID] = true
+	}
+	if got, _ := unseenHookResults(store, "phone", 20); len(got) != 0 {
+		t.Errorf("Expected nothing unseen, got %d", len(got))
+	}
+}
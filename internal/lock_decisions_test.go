@@ -5,6 +5,9 @@ import (
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
+
+	"coach/internal/db"
 )
 
 // The /release and /lock-decisions handlers journal asynchronously through
@@ -63,6 +66,47 @@ func TestLockStateEmptyWithoutDB(t *testing.T) {
 	}
 }
 
+func TestRecentLockDecisionsSkipsTurnsWindowsAndReleaseEnds(t *testing.T) {
+	var decisions []db.LockDecision
+	for _, kind := range []string{"denial", "grant", "delayed", "turn", "broken_commitment", "override", "expired", "turn", "engage"} {
+		decisions = append(decisions, db.LockDecision{Kind: kind})
+	}
+	decisions = append(decisions, db.LockDecision{Kind: "grant", Source: "schedule"})
+
+	var kinds []string
+	for _, d := range recentLockDecisions(decisions, 4) {
+		kinds = append(kinds, d.Kind)
+	}
+	if got := strings.Join(kinds, ","); got != "override,broken_commitment,delayed,grant" {
+		t.Errorf("Expected the last four decisions, newest first, got %s", got)
+	}
+}
+
+func TestHandedOutSecondsLeavesOutPolicyWindows(t *testing.T) {
+	decisions := []db.LockDecision{
+		{Kind: "grant", DurationSeconds: 300},
+		{Kind: "override", DurationSeconds: 600},
+		{Kind: "delayed", DurationSeconds: 120},
+		{Kind: "denial", DurationSeconds: 900},
+		{Kind: "grant", Source: "schedule", DurationSeconds: 3600},
+	}
+	if got := handedOutSeconds(decisions); got != 1020 {
+		t.Errorf("Expected 1020 granted seconds, got %d", got)
+	}
+}
+
+func TestReleaseDecisionFlagsCooldownBypass(t *testing.T) {
+	if d := releaseDecision("g1", "", "", "", 60, true, time.Minute); d.Kind != "override" || !d.CooldownBypassed {
+		t.Errorf("An override within the cooldown should be flagged, got %+v", d)
+	}
+	if d := releaseDecision("g2", "", "", "", 60, true, 0); d.Kind != "override" || d.CooldownBypassed {
+		t.Errorf("An override outside the cooldown should not be flagged, got %+v", d)
+	}
+	if d := releaseDecision("g3", "", "", "", 60, false, 0); d.Kind != "grant" || d.CooldownBypassed {
+		t.Errorf("A grant is never flagged, got %+v", d)
+	}
+}
+
 func TestLogTemptationNoPanicWithoutDB(t *testing.T) {
 	server := &Server{State: &State{}}
 	// nil DBManager must be a no-op, not a panic.
@@ -95,14 +139,105 @@ func TestLockDecisionsRejectsExplicitKind(t *testing.T) {
 	}
 }
 
-func TestLockDecisionsRejectsGet(t *testing.T) {
+func TestLockDecisionsRejectsDelete(t *testing.T) {
 	server := &Server{State: &State{}}
 
-	req := httptest.NewRequest(http.MethodGet, "/lock-decisions", nil)
+	req := httptest.NewRequest(http.MethodDelete, "/lock-decisions", nil)
 	rr := httptest.NewRecorder()
 	server.LockDecisionsHandler(rr, req)
 
 	if rr.Code != http.StatusMethodNotAllowed {
-		t.Errorf("Expected 405 for GET, got %d", rr.Code)
+		t.Errorf("Expected 405 for DELETE, got %d", rr.Code)
+	}
+}
+
+func postFocusing(server *Server, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/focusing", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	rr := httptest.NewRecorder()
+	server.FocusHandler(rr, req)
+	return rr
+}
+
+func TestCommittedFocusRefusesPlainStop(t *testing.T) {
+	server := &Server{State: &State{}}
+
+	if rr := postFocusing(server, "focusing=true&duration=600&commit=true"); rr.Code != http.StatusOK {
+		t.Fatalf("Expected 200 starting committed focus, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if rr := postFocusing(server, "focusing=false"); rr.Code != http.StatusConflict {
+		t.Fatalf("Expected 409 stopping committed focus, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if rr := postFocusing(server, "focusing=true&duration=60&replace=true"); rr.Code != http.StatusConflict {
+		t.Fatalf("Expected 409 replacing committed focus, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if !server.State.IsCommitted() {
+		t.Error("Refused requests must leave the commitment in place")
+	}
+}
+
+func TestCommittedFocusOverrideNeedsMessage(t *testing.T) {
+	server := &Server{State: &State{}}
+	server.State.SetCommittedFocusing(10*time.Minute, FocusLabel{})
+
+	if rr := postFocusing(server, "focusing=false&is_override=true"); rr.Code != http.StatusBadRequest {
+		t.Fatalf("Expected 400 without user_message, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if !server.State.IsCommitted() {
+		t.Fatal("A rejected override must not stop focus")
+	}
+
+	rr := postFocusing(server, "focusing=false&is_override=true&user_message=fire+alarm")
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected 200 with override, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if remaining(server.State) != 0 {
+		t.Error("Override should stop the committed session")
+	}
+}
+
+func TestCommittedFocusRefusesPlainPause(t *testing.T) {
+	server := &Server{State: &State{}}
+	server.State.SetCommittedFocusing(10*time.Minute, FocusLabel{})
+
+	if rr := postFocusing(server, "action=pause"); rr.Code != http.StatusConflict {
+		t.Fatalf("Expected 409 pausing committed focus, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if server.State.GetCurrentFocusInfo().Paused {
+		t.Fatal("A refused pause must leave the session running")
+	}
+	if rr := postFocusing(server, "action=pause&is_override=true&user_message=doorbell"); rr.Code != http.StatusOK {
+		t.Fatalf("Expected 200 with override, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if !server.State.GetCurrentFocusInfo().Paused {
+		t.Error("Override should pause the committed session")
+	}
+}
+
+func TestFocusCycleChecksBeforeBreakingCommitment(t *testing.T) {
+	server := &Server{State: &State{}}
+	server.State.SetCommittedFocusing(10*time.Minute, FocusLabel{})
+	post := func(path, body string) int {
+		req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		rr := httptest.NewRecorder()
+		server.FocusCycleHandler(rr, req)
+		return rr.Code
+	}
+
+	if code := post("/focus-cycle", "focus=abc&is_override=true&user_message=x"); code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for a bad config, got %d", code)
+	}
+	if code := post("/focus-cycle", "cycles=0"); code != http.StatusBadRequest {
+		t.Errorf("Expected a bad config to be 400 before the commitment check, got %d", code)
+	}
+	if code := post("/focus-cycle", "focus=60"); code != http.StatusConflict {
+		t.Errorf("Expected 409 replacing committed focus, got %d", code)
+	}
+	if code := post("/focus-cycle/stop", "is_override=true&user_message=x"); code != http.StatusOK {
+		t.Errorf("Expected 200 stopping without a cycle, got %d", code)
+	}
+	if !server.State.IsCommitted() {
+		t.Error("Requests that cancelled nothing must leave the commitment in place")
 	}
 }
@@ -0,0 +1,240 @@
+package coach
+
+import (
+	"encoding/base64"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/log"
+
+	"coach/internal/db"
+)
+
+// Page sizes for GET /lock-decisions: the default, the most a caller may ask
+// for, and what a download reads per round trip.
+const (
+	lockHistoryDefaultLimit = 50
+	lockHistoryMaxLimit     = 500
+	lockHistoryExportPage   = 500
+)
+
+// lockDecisionQuerier is the slice of db.Manager the history API needs (kept narrow for tests).
+type lockDecisionQuerier interface {
+	QueryLockDecisions(q db.LockDecisionQuery) ([]db.LockDecision, error)
+}
+
+// LockDecisionPage is one page of GET /lock-decisions. NextCursor is empty on
+// the last page.
+type LockDecisionPage struct {
+	Items      []db.LockDecision `json:"items"`
+	NextCursor string            `json:"next_cursor"`
+}
+
+// parseLockHistoryQuery reads the filters of GET /lock-decisions. from and to
+// take RFC3339 or a local date; a to date includes that whole day.
+func parseLockHistoryQuery(v url.Values) (db.LockDecisionQuery, error) {
+	q := db.LockDecisionQuery{
+		Kinds:   splitList(v["kind"]),
+		Sources: splitList(v["source"]),
+		Search:  strings.TrimSpace(v.Get("q")),
+		Limit:   lockHistoryDefaultLimit,
+	}
+	if s := v.Get("from"); s != "" {
+		t, _, err := parseHistoryTime(s)
+		if err != nil {
+			return q, fmt.Errorf("from must be RFC3339 or YYYY-MM-DD")
+		}
+		q.From = t
+	}
+	if s := v.Get("to"); s != "" {
+		t, isDate, err := parseHistoryTime(s)
+		if err != nil {
+			return q, fmt.Errorf("to must be RFC3339 or YYYY-MM-DD")
+		}
+		if isDate {
+			t = t.AddDate(0, 0, 1)
+		}
+		q.To = t
+	}
+	if s := v.Get("limit"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 1 || n > lockHistoryMaxLimit {
+			return q, fmt.Errorf("limit must be between 1 and %d", lockHistoryMaxLimit)
+		}
+		q.Limit = n
+	}
+	if s := v.Get("cursor"); s != "" {
+		created, id, err := decodeLockCursor(s)
+		if err != nil {
+			return q, err
+		}
+		q.AfterCreated, q.AfterID = created, id
+	}
+	return q, nil
+}
+
+// parseHistoryTime parses RFC3339, or a date as local midnight (isDate).
+func parseHistoryTime(s string) (t time.Time, isDate bool, err error) {
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, false, nil
+	}
+	t, err = time.ParseInLocation("2006-01-02", s, time.Local)
+	return t, err == nil, err
+}
+
+// splitList flattens repeated and comma-separated query values.
+func splitList(values []string) []string {
+	var out []string
+	for _, value := range values {
+		for _, v := range strings.Split(value, ",") {
+			if v = strings.TrimSpace(v); v != "" {
+				out = append(out, v)
+			}
+		}
+	}
+	return out
+}
+
+// encodeLockCursor makes the opaque cursor that continues after d.
+func encodeLockCursor(d db.LockDecision) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(d.Created + "|" + d.ID))
+}
+
+func decodeLockCursor(s string) (created, id string, err error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return "", "", fmt.Errorf("cursor is not valid")
+	}
+	created, id, ok := strings.Cut(string(raw), "|")
+	if !ok || created == "" || id == "" {
+		return "", "", fmt.Errorf("cursor is not valid")
+	}
+	return created, id, nil
+}
+
+// lockHistoryPage reads one page for q, reading one row past it to know
+// whether another page follows.
+func lockHistoryPage(store lockDecisionQuerier, q db.LockDecisionQuery) (LockDecisionPage, error) {
+	limit := q.Limit
+	q.Limit = limit + 1
+	items, err := store.QueryLockDecisions(q)
+	if err != nil {
+		return LockDecisionPage{}, err
+	}
+	page := LockDecisionPage{Items: items}
+	if len(items) > limit {
+		page.Items = items[:limit]
+		page.NextCursor = encodeLockCursor(page.Items[limit-1])
+	}
+	if page.Items == nil {
+		page.Items = []db.LockDecision{}
+	}
+	return page, nil
+}
+
+// allLockDecisions reads every decision matching q, page by page, oldest first.
+// q.Limit and the cursor are its own.
+func allLockDecisions(store lockDecisionQuerier, q db.LockDecisionQuery) ([]db.LockDecision, error) {
+	q.Limit, q.AfterCreated, q.AfterID = lockHistoryExportPage, "", ""
+	var out []db.LockDecision
+	for {
+		page, err := lockHistoryPage(store, q)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, page.Items...)
+		if page.NextCursor == "" {
+			break
+		}
+		last := page.Items[len(page.Items)-1]
+		q.AfterCreated, q.AfterID = last.Created, last.ID
+	}
+	slices.Reverse(out)
+	return out, nil
+}
+
+// writeLockHistory answers GET /lock-decisions: a JSON page, or with
+// format=csv or format=jsonl every matching decision as a download.
+func writeLockHistory(w http.ResponseWriter, r *http.Request, store lockDecisionQuerier) {
+	q, err := parseLockHistoryQuery(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	format := r.URL.Query().Get("format")
+	switch format {
+	case "", "json":
+		page, err := lockHistoryPage(store, q)
+		if err != nil {
+			log.Error("Failed to query lock decisions", "err", err)
+			http.Error(w, "Failed to query lock decisions", http.StatusInternalServerError)
+			return
+		}
+		writeJSON(w, page)
+		return
+	case "csv", "jsonl":
+	default:
+		http.Error(w, "format must be json, csv or jsonl", http.StatusBadRequest)
+		return
+	}
+
+	// Read the first page before committing to a 200, so a PB failure is still a 500
+	q.Limit = lockHistoryExportPage
+	page, err := lockHistoryPage(store, q)
+	if err != nil {
+		log.Error("Failed to query lock decisions", "err", err)
+		http.Error(w, "Failed to query lock decisions", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="lock-decisions.%s"`, format))
+	var writeRow func(d db.LockDecision) error
+	var flush func() error
+	if format == "csv" {
+		w.Header().Set("Content-Type", "text/csv")
+		cw := csv.NewWriter(w)
+		cw.Write([]string{"created", "id", "kind", "source", "user_message", "agent_message", "duration_seconds", "grant_id", "actual_seconds", "thread_id"})
+		writeRow = func(d db.LockDecision) error {
+			return cw.Write([]string{
+				d.Created, d.ID, d.Kind, d.Source, d.UserMessage, d.AgentMessage,
+				strconv.Itoa(d.DurationSeconds), d.GrantID, strconv.Itoa(d.ActualSeconds), d.ThreadID,
+			})
+		}
+		flush = func() error { cw.Flush(); return cw.Error() }
+	} else {
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		enc := json.NewEncoder(w)
+		writeRow = func(d db.LockDecision) error { return enc.Encode(d) }
+		flush = func() error { return nil }
+	}
+
+	for {
+		for _, d := range page.Items {
+			if err := writeRow(d); err != nil {
+				log.Warn("Lock decision download interrupted", "err", err)
+				return
+			}
+		}
+		if page.NextCursor == "" {
+			break
+		}
+		last := page.Items[len(page.Items)-1]
+		q.AfterCreated, q.AfterID = last.Created, last.ID
+		if page, err = lockHistoryPage(store, q); err != nil {
+			// Headers are out; all that's left is to cut the download short
+			log.Error("Failed to query lock decisions mid-download", "err", err)
+			break
+		}
+	}
+	if err := flush(); err != nil {
+		log.Warn("Lock decision download interrupted", "err", err)
+	}
+}
@@ -0,0 +1,153 @@
+package coach
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"coach/internal/db"
+)
+
+// fakeLockDecisionQuerier answers queries from memory, newest first, the way
+// PB would for the kind filter and the cursor.
+type fakeLockDecisionQuerier struct {
+	decisions []db.LockDecision // newest first
+	queries   []db.LockDecisionQuery
+}
+
+func (f *fakeLockDecisionQuerier) QueryLockDecisions(q db.LockDecisionQuery) ([]db.LockDecision, error) {
+	f.queries = append(f.queries, q)
+	var out []db.LockDecision
+	for _, d := range f.decisions {
+		if len(q.Kinds) > 0 && !slices.Contains(q.Kinds, d.Kind) {
+			continue
+		}
+		if q.AfterCreated != "" && (d.Created > q.AfterCreated || d.Created == q.AfterCreated && d.ID >= q.AfterID) {
+			continue
+		}
+		if len(out) == q.Limit {
+			break
+		}
+		out = append(out, d)
+	}
+	return out, nil
+}
+
+func newFakeLockHistory(n int) *fakeLockDecisionQuerier {
+	f := &fakeLockDecisionQuerier{}
+	for i := n; i > 0; i-- {
+		kind := "grant"
+		if i%2 == 0 {
+			kind = "denial"
+		}
+		f.decisions = append(f.decisions, db.LockDecision{
+			ID:          fmt.Sprintf("id%03d", i),
+			Kind:        kind,
+			UserMessage: fmt.Sprintf("plea %d, with a comma", i),
+			Created:     fmt.Sprintf("2026-03-01 10:%02d:00.000Z", i),
+		})
+	}
+	return f
+}
+
+func getLockHistory(store lockDecisionQuerier, query string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, "/lock-decisions?"+query, nil)
+	rr := httptest.NewRecorder()
+	writeLockHistory(rr, req, store)
+	return rr
+}
+
+func TestLockHistoryPagesWithCursor(t *testing.T) {
+	store := newFakeLockHistory(5)
+
+	var ids []string
+	query := "limit=2"
+	for pages := 0; ; pages++ {
+		if pages > 5 {
+			t.Fatal("Cursor never ran out")
+		}
+		rr := getLockHistory(store, query)
+		if rr.Code != http.StatusOK {
+			t.Fatalf("Expected 200, got %d: %s", rr.Code, rr.Body.String())
+		}
+		var page LockDecisionPage
+		if err := json.Unmarshal(rr.Body.Bytes(), &page); err != nil {
+			t.Fatal(err)
+		}
+		for _, d := range page.Items {
+			ids = append(ids, d.ID)
+		}
+		if page.NextCursor == "" {
+			break
+		}
+		query = "limit=2&cursor=" + page.NextCursor
+	}
+	if want := []string{"id005", "id004", "id003", "id002", "id001"}; !slices.Equal(ids, want) {
+		t.Errorf("Expected %v, got %v", want, ids)
+	}
+}
+
+func TestLockHistoryParsesFilters(t *testing.T) {
+	store := newFakeLockHistory(3)
+	rr := getLockHistory(store, "kind=grant,override&source=agent&q=+docs+&from=2026-03-01&to=2026-03-07")
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	q := store.queries[0]
+	if !slices.Equal(q.Kinds, []string{"grant", "override"}) || !slices.Equal(q.Sources, []string{"agent"}) || q.Search != "docs" {
+		t.Errorf("Unexpected filters %+v", q)
+	}
+	if want := time.Date(2026, 3, 8, 0, 0, 0, 0, time.Local); !q.To.Equal(want) {
+		t.Errorf("Expected a to date to include the whole day, got %v", q.To)
+	}
+	if !strings.Contains(rr.Body.String(), `"id003"`) || strings.Contains(rr.Body.String(), `"id002"`) {
+		t.Errorf("Expected only grants, got %s", rr.Body.String())
+	}
+
+	for _, bad := range []string{"from=yesterday", "limit=0", "limit=501", "cursor=%25%25", "format=xml"} {
+		if rr := getLockHistory(store, bad); rr.Code != http.StatusBadRequest {
+			t.Errorf("Expected 400 for %s, got %d", bad, rr.Code)
+		}
+	}
+}
+
+func TestLockHistoryDownloadsEveryMatch(t *testing.T) {
+	store := newFakeLockHistory(lockHistoryExportPage + 3)
+
+	rr := getLockHistory(store, "format=csv")
+	if rr.Code != http.StatusOK || rr.Header().Get("Content-Type") != "text/csv" {
+		t.Fatalf("Expected a CSV download, got %d %q", rr.Code, rr.Header().Get("Content-Type"))
+	}
+	lines := strings.Split(strings.TrimSpace(rr.Body.String()), "\n")
+	if len(lines) != lockHistoryExportPage+4 {
+		t.Errorf("Expected a header and %d rows, got %d lines", lockHistoryExportPage+3, len(lines))
+	}
+	if !strings.HasPrefix(lines[0], "created,id,kind") || !strings.Contains(lines[1], `"plea 503, with a comma"`) {
+		t.Errorf("Unexpected CSV start: %q / %q", lines[0], lines[1])
+	}
+
+	rr = getLockHistory(store, "format=jsonl&kind=denial")
+	lines = strings.Split(strings.TrimSpace(rr.Body.String()), "\n")
+	if len(lines) != (lockHistoryExportPage+3)/2 {
+		t.Errorf("Expected one line per denial, got %d", len(lines))
+	}
+	var d db.LockDecision
+	if err := json.Unmarshal([]byte(lines[0]), &d); err != nil || d.Kind != "denial" {
+		t.Errorf("Expected a JSON denial per line, got %q", lines[0])
+	}
+}
+
+func TestLockHistoryNeedsDB(t *testing.T) {
+	server := &Server{State: &State{}}
+	req := httptest.NewRequest(http.MethodGet, "/lock-decisions", nil)
+	rr := httptest.NewRecorder()
+	server.LockDecisionsHandler(rr, req)
+	if rr.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected 503 without a database, got %d", rr.Code)
+	}
+}
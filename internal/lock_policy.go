@@ -0,0 +1,370 @@
+package coach
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/charmbracelet/log"
+
+	"coach/internal/db"
+)
+
+// lockPolicyStore is the slice of db.Manager the policy scheduler needs (kept narrow for tests).
+type lockPolicyStore interface {
+	GetLockPolicies() ([]db.LockPolicy, error)
+	InsertLockPolicy(p db.LockPolicy) (string, error)
+	UpdateLockPolicy(p db.LockPolicy) error
+	DeleteLockPolicy(recordID string) error
+	InsertLockDecisionEntry(d db.LockDecision) error
+}
+
+// LockPolicy is a weekly window in which the agent lock is released without
+// asking: on each of Weekdays from Start to End (local HH:MM). An End at or
+// before Start runs past midnight; "24:00" is midnight itself.
+type LockPolicy struct {
+	ID       string   `json:"id"`
+	Name     string   `json:"name"`
+	Weekdays []string `json:"weekdays"`
+	Start    string   `json:"start"`
+	End      string   `json:"end"`
+	Enabled  bool     `json:"enabled"`
+}
+
+// Validate rejects policies the scheduler can't evaluate.
+func (p LockPolicy) Validate() error {
+	if len(p.Weekdays) == 0 {
+		return fmt.Errorf("weekdays must not be empty")
+	}
+	for _, d := range p.Weekdays {
+		if _, ok := weekdayNames[d]; !ok {
+			return fmt.Errorf("unknown weekday %q (want sun, mon, tue, wed, thu, fri or sat)", d)
+		}
+	}
+	if _, err := time.Parse("15:04", p.Start); err != nil {
+		return fmt.Errorf("start must be HH:MM")
+	}
+	if _, ok := policyClock(p.End); !ok {
+		return fmt.Errorf("end must be HH:MM or 24:00")
+	}
+	if p.End == p.Start {
+		return fmt.Errorf("end must differ from start")
+	}
+	return nil
+}
+
+// policyClock parses HH:MM into time since midnight, allowing "24:00".
+func policyClock(v string) (time.Duration, bool) {
+	if v == "24:00" {
+		return 24 * time.Hour, true
+	}
+	t, err := time.Parse("15:04", v)
+	if err != nil {
+		return 0, false
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, true
+}
+
+// windowOn returns the window the policy opens on day's date, if any.
+func (p LockPolicy) windowOn(day time.Time) (LockWindow, bool) {
+	if !p.Enabled {
+		return LockWindow{}, false
+	}
+	repeats := false
+	for _, d := range p.Weekdays {
+		repeats = repeats || weekdayNames[d] == day.Weekday()
+	}
+	if !repeats {
+		return LockWindow{}, false
+	}
+	start, ok := policyClock(p.Start)
+	end, ok2 := policyClock(p.End)
+	if !ok || !ok2 {
+		return LockWindow{}, false
+	}
+	at := func(offset time.Duration) time.Time {
+		// Whole days via AddDate so DST days keep their wall-clock times
+		days := int(offset / (24 * time.Hour))
+		rest := offset - time.Duration(days)*24*time.Hour
+		return time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, day.Location()).AddDate(0, 0, days).Add(rest)
+	}
+	if end <= start {
+		end += 24 * time.Hour
+	}
+	return LockWindow{PolicyID: p.ID, Name: p.Name, Start: at(start), End: at(end)}, true
+}
+
+func (p LockPolicy) toRecord() db.LockPolicy {
+	return db.LockPolicy{
+		RecordID: p.ID,
+		Name:     p.Name,
+		Weekdays: strings.Join(p.Weekdays, ","),
+		Start:    p.Start,
+		End:      p.End,
+		Enabled:  p.Enabled,
+	}
+}
+
+func lockPolicyFromRecord(rec db.LockPolicy) LockPolicy {
+	weekdays := []string{}
+	for _, d := range strings.Split(rec.Weekdays, ",") {
+		if d = strings.TrimSpace(d); d != "" {
+			weekdays = append(weekdays, d)
+		}
+	}
+	return LockPolicy{
+		ID:       rec.RecordID,
+		Name:     rec.Name,
+		Weekdays: weekdays,
+		Start:    rec.Start,
+		End:      rec.End,
+		Enabled:  rec.Enabled,
+	}
+}
+
+// LockWindow is one concrete window a policy opens.
+type LockWindow struct {
+	PolicyID string    `json:"policy_id"`
+	Name     string    `json:"name"`
+	Start    time.Time `json:"start"`
+	End      time.Time `json:"end"`
+}
+
+// lockWindows returns the enabled policies' windows that are open at or open
+// after from, within the next week, earliest first.
+func lockWindows(policies []LockPolicy, from time.Time) []LockWindow {
+	var out []LockWindow
+	// Start a day early: last night's window may still be open
+	day := time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, from.Location()).AddDate(0, 0, -1)
+	for i := 0; i <= 8; i++ {
+		for _, p := range policies {
+			if w, ok := p.windowOn(day.AddDate(0, 0, i)); ok && w.End.After(from) {
+				out = append(out, w)
+			}
+		}
+	}
+	sort.Slice(out, func(a, b int) bool { return out[a].Start.Before(out[b].Start) })
+	return out
+}
+
+// openUntil returns when the windows covering now stop covering it, following
+// windows that overlap or touch (so "all weekend" is one stretch), and whether
+// any window covers now. windows must be sorted by start.
+func openUntil(windows []LockWindow, now time.Time) (time.Time, bool) {
+	var end time.Time
+	open := false
+	for _, w := range windows {
+		if w.Start.After(now) && (!open || w.Start.After(end)) {
+			break
+		}
+		if !w.Start.After(now) && !w.End.After(now) {
+			continue
+		}
+		if w.End.After(end) {
+			end = w.End
+		}
+		open = true
+	}
+	return end, open
+}
+
+// LockPolicyScheduler releases the agent lock while a policy window is open
+// and lets it snap back when the window closes. It keeps a single timer for
+// the next change across all policies and re-evaluates whenever it fires or
+// the policies change. Releases it makes are journaled with source "schedule".
+type LockPolicyScheduler struct {
+	state *State
+	store lockPolicyStore
+
+	mu       sync.Mutex
+	policies []LockPolicy
+	timer    *time.Timer
+	released *time.Time // end of the release this scheduler last made
+}
+
+func NewLockPolicyScheduler(state *State, store lockPolicyStore) *LockPolicyScheduler {
+	return &LockPolicyScheduler{state: state, store: store}
+}
+
+// Load reads the policies from the store and starts evaluating them. A window
+// that is already open (e.g. after a restart) releases the lock right away.
+func (l *LockPolicyScheduler) Load() error {
+	records, err := l.store.GetLockPolicies()
+	if err != nil {
+		return err
+	}
+	policies := make([]LockPolicy, 0, len(records))
+	for _, rec := range records {
+		p := lockPolicyFromRecord(rec)
+		if err := p.Validate(); err != nil {
+			log.Warn("Skipping invalid lock policy", "id", rec.RecordID, "error", err)
+			continue
+		}
+		policies = append(policies, p)
+	}
+
+	l.mu.Lock()
+	l.policies = policies
+	l.mu.Unlock()
+	log.Info("Loaded lock policies", "count", len(policies))
+	l.evaluate()
+	return nil
+}
+
+// Policies returns the current policies, in creation order.
+func (l *LockPolicyScheduler) Policies() []LockPolicy {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return append([]LockPolicy{}, l.policies...)
+}
+
+// Save creates a policy (empty ID) or replaces an existing one, then re-evaluates.
+func (l *LockPolicyScheduler) Save(p LockPolicy) (LockPolicy, error) {
+	if err := p.Validate(); err != nil {
+		return LockPolicy{}, err
+	}
+
+	if p.ID == "" {
+		id, err := l.store.InsertLockPolicy(p.toRecord())
+		if err != nil {
+			return LockPolicy{}, err
+		}
+		p.ID = id
+		l.mu.Lock()
+		l.policies = append(l.policies, p)
+		l.mu.Unlock()
+	} else {
+		l.mu.Lock()
+		i := l.indexLocked(p.ID)
+		l.mu.Unlock()
+		if i < 0 {
+			return LockPolicy{}, fmt.Errorf("no lock policy with id %q", p.ID)
+		}
+		if err := l.store.UpdateLockPolicy(p.toRecord()); err != nil {
+			return LockPolicy{}, err
+		}
+		l.mu.Lock()
+		if i := l.indexLocked(p.ID); i >= 0 {
+			l.policies[i] = p
+		}
+		l.mu.Unlock()
+	}
+
+	log.Info("Lock policy saved", "id", p.ID, "weekdays", p.Weekdays, "start", p.Start, "end", p.End)
+	l.evaluate()
+	return p, nil
+}
+
+// Delete removes a policy. Returns false if no policy has that ID.
+func (l *LockPolicyScheduler) Delete(id string) (bool, error) {
+	l.mu.Lock()
+	found := l.indexLocked(id) >= 0
+	l.mu.Unlock()
+	if !found {
+		return false, nil
+	}
+	if err := l.store.DeleteLockPolicy(id); err != nil {
+		return true, err
+	}
+
+	l.mu.Lock()
+	if i := l.indexLocked(id); i >= 0 {
+		l.policies = append(l.policies[:i], l.policies[i+1:]...)
+	}
+	l.mu.Unlock()
+
+	log.Info("Lock policy deleted", "id", id)
+	l.evaluate()
+	return true, nil
+}
+
+// Windows returns the open and upcoming windows of the next week.
+func (l *LockPolicyScheduler) Windows() []LockWindow {
+	windows := lockWindows(l.Policies(), time.Now())
+	if windows == nil {
+		windows = []LockWindow{}
+	}
+	return windows
+}
+
+// indexLocked returns the position of the policy with id, or -1. Must be called with l.mu held.
+func (l *LockPolicyScheduler) indexLocked(id string) int {
+	for i, p := range l.policies {
+		if p.ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+// evaluate releases the lock until the open windows close, or engages it if
+// the window this scheduler released for is gone (its policy was deleted,
+// disabled or moved), then arms the timer for the next window.
+func (l *LockPolicyScheduler) evaluate() {
+	now := time.Now()
+
+	l.mu.Lock()
+	if l.timer != nil {
+		l.timer.Stop()
+		l.timer = nil
+	}
+	windows := lockWindows(l.policies, now)
+	end, open := openUntil(windows, now)
+	var next *time.Time
+	for _, w := range windows {
+		if w.Start.After(now) && (!open || w.Start.After(end)) {
+			next = &w.Start
+			break
+		}
+	}
+	if next != nil {
+		l.timer = time.AfterFunc(time.Until(*next), l.evaluate)
+	}
+	released := l.released
+	if open {
+		l.released = &end
+	} else {
+		l.released = nil
+	}
+	l.mu.Unlock()
+
+	if open {
+		names := []string{}
+		for _, w := range windows {
+			if !w.Start.After(now) && w.Name != "" {
+				names = append(names, w.Name)
+			}
+		}
+		id := db.NewRecordID()
+		if l.state.releaseAgentLockUntil(end, nil, db.ReleaseGrant{ID: id, Scheduled: true}) {
+			log.Info("Lock policy window open", "until", end)
+			msg := fmt.Sprintf("Lock policy window open until %s", end.Format("Mon 15:04"))
+			if len(names) > 0 {
+				msg += " (" + strings.Join(names, ", ") + ")"
+			}
+			l.journal(db.LockDecision{ID: id, Kind: "grant", AgentMessage: msg, DurationSeconds: int(end.Sub(now) / time.Second)})
+		}
+		return
+	}
+
+	// Only take back a release this scheduler made, and only while it still
+	// ends where the window did; a longer grant on top of it stays.
+	if released != nil && released.After(now) {
+		if current := l.state.agentReleaseEnd(); current != nil && current.Equal(*released) {
+			log.Info("Lock policy window withdrawn, engaging agent lock")
+			l.state.engageAgentLock("schedule")
+		}
+	}
+}
+
+// journal records a scheduled lock decision, best-effort and asynchronous.
+func (l *LockPolicyScheduler) journal(d db.LockDecision) {
+	d.Source = "schedule"
+	go func() {
+		if err := l.store.InsertLockDecisionEntry(d); err != nil {
+			log.Error("Failed to journal lock policy decision", "kind", d.Kind, "error", err)
+		}
+	}()
+}
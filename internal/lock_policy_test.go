@@ -0,0 +1,145 @@
+package coach
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"coach/internal/db"
+)
+
+// fakeLockPolicyStore keeps policies and the journal in memory instead of PocketBase.
+type fakeLockPolicyStore struct {
+	mu        sync.Mutex
+	policies  []db.LockPolicy
+	decisions []db.LockDecision
+	nextID    int
+}
+
+func (f *fakeLockPolicyStore) GetLockPolicies() ([]db.LockPolicy, error) { return f.policies, nil }
+
+func (f *fakeLockPolicyStore) InsertLockPolicy(p db.LockPolicy) (string, error) {
+	f.nextID++
+	p.RecordID = fmt.Sprintf("policy%d", f.nextID)
+	f.policies = append(f.policies, p)
+	return p.RecordID, nil
+}
+
+func (f *fakeLockPolicyStore) UpdateLockPolicy(p db.LockPolicy) error { return nil }
+func (f *fakeLockPolicyStore) DeleteLockPolicy(recordID string) error { return nil }
+
+func (f *fakeLockPolicyStore) InsertLockDecisionEntry(d db.LockDecision) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.decisions = append(f.decisions, d)
+	return nil
+}
+
+func (f *fakeLockPolicyStore) journal() []db.LockDecision {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]db.LockDecision{}, f.decisions...)
+}
+
+var everyDay = []string{"sun", "mon", "tue", "wed", "thu", "fri", "sat"}
+
+func TestLockPolicyValidate(t *testing.T) {
+	valid := LockPolicy{Weekdays: []string{"mon"}, Start: "20:00", End: "24:00"}
+	if err := valid.Validate(); err != nil {
+		t.Errorf("Expected valid, got %v", err)
+	}
+	for _, p := range []LockPolicy{
+		{Start: "20:00", End: "22:00"},
+		{Weekdays: []string{"monday"}, Start: "20:00", End: "22:00"},
+		{Weekdays: []string{"mon"}, Start: "24:00", End: "22:00"},
+		{Weekdays: []string{"mon"}, Start: "20:00", End: "20:00"},
+	} {
+		if err := p.Validate(); err == nil {
+			t.Errorf("Expected %+v to be invalid", p)
+		}
+	}
+}
+
+func TestLockWindowsJoinAcrossMidnight(t *testing.T) {
+	weekend := LockPolicy{ID: "w", Weekdays: []string{"sat", "sun"}, Start: "00:00", End: "24:00", Enabled: true}
+	evenings := LockPolicy{ID: "e", Weekdays: []string{"fri"}, Start: "20:00", End: "02:00", Enabled: true}
+
+	// Friday 2026-03-06 21:00: the evening window runs into Saturday, which runs into Sunday
+	now := time.Date(2026, 3, 6, 21, 0, 0, 0, time.Local)
+	end, open := openUntil(lockWindows([]LockPolicy{weekend, evenings}, now), now)
+	if want := time.Date(2026, 3, 9, 0, 0, 0, 0, time.Local); !open || !end.Equal(want) {
+		t.Errorf("Expected open until %v, got %v (open=%t)", want, end, open)
+	}
+
+	// Friday 19:00: nothing open yet
+	now = time.Date(2026, 3, 6, 19, 0, 0, 0, time.Local)
+	if _, open := openUntil(lockWindows([]LockPolicy{weekend, evenings}, now), now); open {
+		t.Error("Expected no window open before 20:00 on Friday")
+	}
+}
+
+func TestLockPolicySchedulerReleasesAndJournals(t *testing.T) {
+	now := time.Now()
+	store := &fakeLockPolicyStore{}
+	state := &State{}
+	scheduler := NewLockPolicyScheduler(state, store)
+
+	saved, err := scheduler.Save(LockPolicy{
+		Name:     "now-ish",
+		Weekdays: everyDay,
+		Start:    now.Add(-time.Hour).Format("15:04"),
+		End:      now.Add(time.Hour).Format("15:04"),
+		Enabled:  true,
+	})
+	if err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	if agentLocked(state) {
+		t.Fatal("An open window should release the agent lock")
+	}
+	time.Sleep(20 * time.Millisecond)
+	journal := store.journal()
+	if len(journal) != 1 || journal[0].Source != "schedule" || journal[0].Kind != "grant" {
+		t.Fatalf("Expected one schedule grant journaled, got %+v", journal)
+	}
+	state.mu.Lock()
+	grant := state.agentReleaseGrants[db.FullReleaseKey]
+	state.mu.Unlock()
+	if grant.ID == "" || grant.ID != journal[0].ID {
+		t.Errorf("Expected the release linked to its journal entry %q, got %q", journal[0].ID, grant.ID)
+	}
+
+	// Re-evaluating the same window doesn't journal it twice
+	scheduler.Load()
+	time.Sleep(20 * time.Millisecond)
+	if journal := store.journal(); len(journal) != 1 {
+		t.Errorf("Expected the window journaled once, got %+v", journal)
+	}
+
+	// Deleting the policy takes back its release
+	if _, err := scheduler.Delete(saved.ID); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if !agentLocked(state) {
+		t.Error("Deleting the open policy should engage the agent lock")
+	}
+}
+
+func TestLockPolicySchedulerKeepsLongerGrant(t *testing.T) {
+	now := time.Now()
+	state := &State{}
+	scheduler := NewLockPolicyScheduler(state, &fakeLockPolicyStore{})
+	saved, _ := scheduler.Save(LockPolicy{
+		Weekdays: everyDay,
+		Start:    now.Add(-time.Hour).Format("15:04"),
+		End:      now.Add(time.Hour).Format("15:04"),
+		Enabled:  true,
+	})
+
+	state.ReleaseAgentLock(3 * time.Hour)
+	scheduler.Delete(saved.ID)
+	if agentLocked(state) {
+		t.Error("A grant reaching past the window should survive the policy's removal")
+	}
+}
@@ -0,0 +1,79 @@
+package coach
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/charmbracelet/log"
+
+	"coach/internal/db"
+)
+
+// maxThreadIDLen bounds a client-chosen thread id.
+const maxThreadIDLen = 64
+
+var errInvalidThreadID = errors.New("thread_id must be 1-64 letters, digits, '-' or '_'")
+
+// lockThreadStore is the slice of db.Manager the thread API needs (kept narrow for tests).
+type lockThreadStore interface {
+	GetLockThread(threadID string) ([]db.LockDecision, error)
+}
+
+// LockThread is one plea conversation with the judge: every turn and decision
+// filed under its id, oldest first. Outcome is the kind of the latest decision
+// ("" while it is all turns); Denials counts the refusals along the way, so an
+// override shows what it overrode.
+type LockThread struct {
+	ID      string            `json:"id"`
+	Entries []db.LockDecision `json:"entries"`
+	Outcome string            `json:"outcome"`
+	Denials int               `json:"denials"`
+}
+
+// validThreadID reports whether id is a usable thread id. The judge picks
+// them, so they are kept to characters that are safe in a URL path.
+func validThreadID(id string) bool {
+	if id == "" || len(id) > maxThreadIDLen {
+		return false
+	}
+	for _, c := range id {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-' || c == '_') {
+			return false
+		}
+	}
+	return true
+}
+
+// buildLockThread summarises the entries of thread id.
+func buildLockThread(id string, entries []db.LockDecision) LockThread {
+	thread := LockThread{ID: id, Entries: entries}
+	for _, d := range entries {
+		switch d.Kind {
+		case "denial":
+			thread.Denials++
+			thread.Outcome = d.Kind
+		case "grant", "override", "delayed":
+			thread.Outcome = d.Kind
+		}
+	}
+	return thread
+}
+
+// writeLockThread answers GET /lock-decisions/threads/{id}.
+func writeLockThread(w http.ResponseWriter, id string, store lockThreadStore) {
+	if !validThreadID(id) {
+		http.Error(w, errInvalidThreadID.Error(), http.StatusBadRequest)
+		return
+	}
+	entries, err := store.GetLockThread(id)
+	if err != nil {
+		log.Error("Failed to read lock thread", "thread_id", id, "err", err)
+		http.Error(w, "Failed to read lock thread", http.StatusInternalServerError)
+		return
+	}
+	if len(entries) == 0 {
+		http.Error(w, "No lock decisions in that thread", http.StatusNotFound)
+		return
+	}
+	writeJSON(w, buildLockThread(id, entries))
+}
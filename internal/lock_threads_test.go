@@ -0,0 +1,108 @@
+package coach
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"coach/internal/db"
+)
+
+// fakeLockThreadStore answers GetLockThread from memory.
+type fakeLockThreadStore map[string][]db.LockDecision
+
+func (f fakeLockThreadStore) GetLockThread(threadID string) ([]db.LockDecision, error) {
+	return f[threadID], nil
+}
+
+func TestLockThreadTracesOverrideToDenials(t *testing.T) {
+	store := fakeLockThreadStore{"plea-1": {
+		{Kind: "turn", UserMessage: "can I check reddit", AgentMessage: "what for?", ThreadID: "plea-1"},
+		{Kind: "denial", UserMessage: "just browsing", AgentMessage: "no", ThreadID: "plea-1"},
+		{Kind: "denial", UserMessage: "5 minutes", AgentMessage: "still no", ThreadID: "plea-1"},
+		{Kind: "override", UserMessage: "doing it anyway", DurationSeconds: 300, ThreadID: "plea-1"},
+	}}
+
+	rr := httptest.NewRecorder()
+	writeLockThread(rr, "plea-1", store)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	var thread LockThread
+	if err := json.Unmarshal(rr.Body.Bytes(), &thread); err != nil {
+		t.Fatal(err)
+	}
+	if thread.ID != "plea-1" || len(thread.Entries) != 4 || thread.Outcome != "override" || thread.Denials != 2 {
+		t.Errorf("Unexpected thread %+v", thread)
+	}
+
+	if outcome := buildLockThread("t", store["plea-1"][:1]).Outcome; outcome != "" {
+		t.Errorf("A thread of turns has no outcome yet, got %q", outcome)
+	}
+
+	rr = httptest.NewRecorder()
+	writeLockThread(rr, "unknown", store)
+	if rr.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for an empty thread, got %d", rr.Code)
+	}
+	rr = httptest.NewRecorder()
+	writeLockThread(rr, "bad'id", store)
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for a bad id, got %d", rr.Code)
+	}
+}
+
+func TestReleaseFilesGrantUnderThread(t *testing.T) {
+	store := &fakeLockDecisionStore{}
+	server := &Server{State: &State{}, ReleaseBudget: NewReleaseBudget(time.Hour, store)}
+
+	if rr := postRelease(server, "duration=60&thread_id=plea-1"); rr.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if len(store.decisions) != 1 || store.decisions[0].ThreadID != "plea-1" {
+		t.Errorf("Expected the grant journaled under plea-1, got %+v", store.decisions)
+	}
+	if rr := postRelease(server, "duration=60&thread_id="+strings.Repeat("x", maxThreadIDLen+1)); rr.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for a long thread id, got %d", rr.Code)
+	}
+}
+
+func TestLockDecisionsRecordsTurns(t *testing.T) {
+	server := &Server{State: &State{}}
+	post := func(body string) int {
+		req := httptest.NewRequest(http.MethodPost, "/lock-decisions", strings.NewReader(body))
+		rr := httptest.NewRecorder()
+		server.LockDecisionsHandler(rr, req)
+		return rr.Code
+	}
+
+	if code := post(`{"kind":"turn","thread_id":"plea-1","user_message":"hi","agent_message":"why?"}`); code != http.StatusOK {
+		t.Errorf("Expected 200 for a turn, got %d", code)
+	}
+	if code := post(`{"kind":"turn","user_message":"hi"}`); code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for a turn without a thread, got %d", code)
+	}
+	if code := post(`{"thread_id":"plea 1","user_message":"hi"}`); code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for a bad thread id, got %d", code)
+	}
+}
+
+func TestLockThreadRoute(t *testing.T) {
+	server := &Server{State: &State{}}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/lock-decisions/threads/{id}", server.LockThreadHandler)
+
+	rr := httptest.NewRecorder()
+	mux.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/lock-decisions/threads/plea-1", nil))
+	if rr.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected 503 without a database, got %d", rr.Code)
+	}
+	rr = httptest.NewRecorder()
+	mux.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/lock-decisions/threads/plea-1", nil))
+	if rr.Code != http.StatusMethodNotAllowed {
+		t.Errorf("Expected 405 for POST, got %d", rr.Code)
+	}
+}
@@ -0,0 +1,171 @@
+package coach
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/charmbracelet/log"
+
+	"coach/internal/db"
+)
+
+// Window sizes for GET /agent-lock/trends, in days.
+const (
+	lockTrendsDefaultDays = 7
+	lockTrendsMaxDays     = 90
+)
+
+// lockTrendsStore is the slice of db.Manager the trends read (kept narrow for tests).
+type lockTrendsStore interface {
+	lockDecisionQuerier
+	GetTemptationTimes(from, to time.Time) ([]time.Time, error)
+}
+
+// LockTrendDay is one local day of the ledger (Date is empty on the totals).
+// DenialOverrideRate is the share of the day's denials the user then overrode
+// before the next grant; it is null on a day without denials.
+type LockTrendDay struct {
+	Date               string   `json:"date,omitempty"`
+	Grants             int      `json:"grants"`
+	Overrides          int      `json:"overrides"`
+	Denials            int      `json:"denials"`
+	ReleasedSeconds    int      `json:"released_seconds"`
+	Temptations        int      `json:"temptations"`
+	DenialOverrideRate *float64 `json:"denial_override_rate"`
+}
+
+// LockTrends is the answer of GET /agent-lock/trends: one entry per day, oldest
+// first and ending today, and the same numbers over the whole window.
+type LockTrends struct {
+	Days   []LockTrendDay `json:"days"`
+	Totals LockTrendDay   `json:"totals"`
+}
+
+// buildLockTrends aggregates decisions (oldest first) and temptations (when
+// each was recorded) over the days days ending on now's date. runningSeconds
+// is what the release still running has used so far; it counts for today.
+// Grants of lock policy windows are the user's own schedule, not a plea, so
+// they are left out.
+func buildLockTrends(decisions []db.LockDecision, temptations []time.Time, days int, runningSeconds int, now time.Time) LockTrends {
+	first := time.Date(now.Year(), now.Month(), now.Day()-days+1, 0, 0, 0, 0, now.Location())
+	out := LockTrends{Days: make([]LockTrendDay, days)}
+	index := map[string]int{}
+	for i := range out.Days {
+		date := first.AddDate(0, 0, i).Format("2006-01-02")
+		out.Days[i].Date = date
+		index[date] = i
+	}
+	for _, at := range temptations {
+		if i, ok := index[at.In(now.Location()).Format("2006-01-02")]; ok {
+			out.Days[i].Temptations++
+		}
+	}
+
+	// Denials since the last grant, by day, waiting to see if an override follows
+	var pending []int
+	overridden := make([]int, days)
+	for _, d := range decisions {
+		at, err := d.CreatedAt()
+		if err != nil {
+			continue
+		}
+		i, ok := index[at.In(now.Location()).Format("2006-01-02")]
+		if !ok {
+			continue
+		}
+		day := &out.Days[i]
+		switch d.Kind {
+		case "grant":
+			if d.Source == "schedule" {
+				continue
+			}
+			day.Grants++
+			pending = pending[:0]
+		case "delayed":
+			pending = pending[:0]
+		case "override":
+			day.Overrides++
+			for _, j := range pending {
+				overridden[j]++
+			}
+			pending = pending[:0]
+		case "denial":
+			day.Denials++
+			pending = append(pending, i)
+		case "engage", "expired":
+			day.ReleasedSeconds += d.ActualSeconds
+		}
+	}
+	out.Days[days-1].ReleasedSeconds += runningSeconds
+
+	totalOverridden := 0
+	for i := range out.Days {
+		day := &out.Days[i]
+		day.DenialOverrideRate = denialOverrideRate(overridden[i], day.Denials)
+		out.Totals.Grants += day.Grants
+		out.Totals.Overrides += day.Overrides
+		out.Totals.Denials += day.Denials
+		out.Totals.ReleasedSeconds += day.ReleasedSeconds
+		out.Totals.Temptations += day.Temptations
+		totalOverridden += overridden[i]
+	}
+	out.Totals.DenialOverrideRate = denialOverrideRate(totalOverridden, out.Totals.Denials)
+	return out
+}
+
+func denialOverrideRate(overridden, denials int) *float64 {
+	if denials == 0 {
+		return nil
+	}
+	rate := float64(overridden) / float64(denials)
+	return &rate
+}
+
+// parseTrendDays reads days=N, defaulting to a week.
+func parseTrendDays(s string) (int, error) {
+	if s == "" {
+		return lockTrendsDefaultDays, nil
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil || n < 1 || n > lockTrendsMaxDays {
+		return 0, fmt.Errorf("days must be between 1 and %d", lockTrendsMaxDays)
+	}
+	return n, nil
+}
+
+// readLockTrends reads the journal and the temptations for the days days
+// ending on now's date and aggregates them.
+func readLockTrends(store lockTrendsStore, days int, runningSeconds int, now time.Time) (LockTrends, error) {
+	first := time.Date(now.Year(), now.Month(), now.Day()-days+1, 0, 0, 0, 0, now.Location())
+	decisions, err := allLockDecisions(store, db.LockDecisionQuery{From: first})
+	if err != nil {
+		return LockTrends{}, fmt.Errorf("failed to read lock decisions: %w", err)
+	}
+	temptations, err := store.GetTemptationTimes(first, first.AddDate(0, 0, days))
+	if err != nil {
+		return LockTrends{}, fmt.Errorf("failed to read temptations: %w", err)
+	}
+	return buildLockTrends(decisions, temptations, days, runningSeconds, now), nil
+}
+
+// writeLockTrends answers GET /agent-lock/trends.
+func (s *Server) writeLockTrends(w http.ResponseWriter, r *http.Request) {
+	days, err := parseTrendDays(r.URL.Query().Get("days"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if s.DBManager == nil {
+		http.Error(w, "Lock trends are not available without the database", http.StatusServiceUnavailable)
+		return
+	}
+	trends, err := readLockTrends(s.DBManager, days, s.State.RunningReleaseSeconds(), time.Now())
+	if err != nil {
+		log.Error("Failed to read lock trends", "err", err)
+		http.Error(w, "Failed to read lock trends", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, trends)
+}
@@ -0,0 +1,150 @@
+package coach
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"coach/internal/db"
+)
+
+// fakeLockTrendsStore serves decisions like fakeLockDecisionQuerier and the
+// temptations in a window, recording each window asked for.
+type fakeLockTrendsStore struct {
+	fakeLockDecisionQuerier
+	temptations []time.Time
+	windows     [][2]time.Time
+}
+
+func (f *fakeLockTrendsStore) GetTemptationTimes(from, to time.Time) ([]time.Time, error) {
+	f.windows = append(f.windows, [2]time.Time{from, to})
+	var out []time.Time
+	for _, at := range f.temptations {
+		if !at.Before(from) && at.Before(to) {
+			out = append(out, at)
+		}
+	}
+	return out, nil
+}
+
+// trendDecision is a journal entry at local time at, stored the way PB writes it.
+func trendDecision(id, kind string, at time.Time, actual int) db.LockDecision {
+	return db.LockDecision{
+		ID: id, Kind: kind, ActualSeconds: actual,
+		Created: at.UTC().Format("2006-01-02 15:04:05.000Z"),
+	}
+}
+
+func TestBuildLockTrendsPerDay(t *testing.T) {
+	now := time.Date(2026, 3, 3, 15, 0, 0, 0, time.Local)
+	yesterday := now.AddDate(0, 0, -1)
+	decisions := []db.LockDecision{
+		trendDecision("a", "denial", yesterday.Add(-2*time.Hour), 0),
+		trendDecision("b", "denial", yesterday.Add(-time.Hour), 0),
+		{ID: "s", Kind: "grant", Source: "schedule", Created: yesterday.Add(-30 * time.Minute).UTC().Format("2006-01-02 15:04:05.000Z")},
+		trendDecision("c", "override", yesterday, 0),
+		trendDecision("d", "expired", yesterday.Add(time.Hour), 600),
+		trendDecision("e", "denial", now.Add(-3*time.Hour), 0),
+		trendDecision("f", "grant", now.Add(-2*time.Hour), 0),
+		trendDecision("g", "engage", now.Add(-time.Hour), 120),
+		trendDecision("h", "denial", now.Add(-30*time.Minute), 0),
+	}
+
+	var temptations []time.Time
+	for i := 0; i < 4; i++ {
+		temptations = append(temptations, yesterday.Add(time.Duration(i)*time.Minute))
+	}
+	temptations = append(temptations, now.Add(-time.Minute), now.AddDate(0, 0, -5))
+
+	trends := buildLockTrends(decisions, temptations, 3, 30, now)
+	if len(trends.Days) != 3 || trends.Days[0].Date != "2026-03-01" || trends.Days[2].Date != "2026-03-03" {
+		t.Fatalf("Expected the three days ending today, got %+v", trends.Days)
+	}
+	if first := trends.Days[0]; first.Denials != 0 || first.DenialOverrideRate != nil {
+		t.Errorf("A day without denials has no rate, got %+v", first)
+	}
+
+	prev := trends.Days[1]
+	if prev.Denials != 2 || prev.Overrides != 1 || prev.Grants != 0 || prev.ReleasedSeconds != 600 || prev.Temptations != 4 {
+		t.Errorf("Unexpected yesterday %+v (a policy window is no grant)", prev)
+	}
+	if prev.DenialOverrideRate == nil || *prev.DenialOverrideRate != 1 {
+		t.Errorf("Both denials ended in the override despite the policy window, got %v", prev.DenialOverrideRate)
+	}
+
+	today := trends.Days[2]
+	if today.Denials != 2 || today.Grants != 1 || today.ReleasedSeconds != 150 {
+		t.Errorf("Unexpected today %+v (released should include the running 30s)", today)
+	}
+	if today.DenialOverrideRate == nil || *today.DenialOverrideRate != 0 {
+		t.Errorf("No denial today was overridden, got %v", today.DenialOverrideRate)
+	}
+
+	totals := trends.Totals
+	if totals.Denials != 4 || totals.Overrides != 1 || totals.ReleasedSeconds != 750 || totals.Temptations != 5 {
+		t.Errorf("Unexpected totals %+v", totals)
+	}
+	if totals.DenialOverrideRate == nil || *totals.DenialOverrideRate != 0.5 {
+		t.Errorf("Expected half the denials overridden, got %v", totals.DenialOverrideRate)
+	}
+}
+
+func TestReadLockTrendsReadsTheWindowOnce(t *testing.T) {
+	now := time.Date(2026, 3, 3, 15, 0, 0, 0, time.Local)
+	store := &fakeLockTrendsStore{}
+	for day := 0; day < 7; day++ {
+		at := time.Date(2026, 2, 25+day, 23, 30, 0, 0, time.Local)
+		store.temptations = append(store.temptations, at, at.Add(-12*time.Hour))
+	}
+	store.decisions = []db.LockDecision{
+		trendDecision("b", "grant", now.Add(-time.Hour), 0),
+		trendDecision("a", "denial", now.AddDate(0, 0, -1), 0),
+	}
+
+	trends, err := readLockTrends(store, 7, 0, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	first := time.Date(2026, 2, 25, 0, 0, 0, 0, time.Local)
+	if len(store.windows) != 1 || !store.windows[0][0].Equal(first) || !store.windows[0][1].Equal(first.AddDate(0, 0, 7)) {
+		t.Errorf("Expected one temptation read from Feb 25 to Mar 4, got %v", store.windows)
+	}
+	if !store.queries[0].From.Equal(first) {
+		t.Errorf("Expected decisions from the first day, got %v", store.queries[0].From)
+	}
+	for _, day := range trends.Days {
+		if day.Temptations != 2 {
+			t.Errorf("Expected 2 temptations on %s, got %d", day.Date, day.Temptations)
+		}
+	}
+	if trends.Totals.Temptations != 14 || trends.Days[5].Denials != 1 || trends.Days[6].Grants != 1 {
+		t.Errorf("Unexpected trends %+v", trends)
+	}
+}
+
+func TestLockTrendsEndpoint(t *testing.T) {
+	server := &Server{State: &State{}}
+	get := func(query string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/agent-lock/trends"+query, nil)
+		rr := httptest.NewRecorder()
+		server.AgentLockHandler(rr, req)
+		return rr
+	}
+
+	for _, bad := range []string{"?days=0", "?days=91", "?days=week"} {
+		if rr := get(bad); rr.Code != http.StatusBadRequest {
+			t.Errorf("Expected 400 for %s, got %d", bad, rr.Code)
+		}
+	}
+	if rr := get(""); rr.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected 503 without a database, got %d", rr.Code)
+	}
+
+	var decoded LockTrends
+	data, _ := json.Marshal(buildLockTrends(nil, nil, 1, 0, time.Now()))
+	if err := json.Unmarshal(data, &decoded); err != nil || len(decoded.Days) != 1 || decoded.Totals.Date != "" {
+		t.Errorf("Unexpected JSON %s", data)
+	}
+}
@@ -0,0 +1,346 @@
+package coach
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/charmbracelet/log"
+
+	"coach/internal/db"
+)
+
+// focusRuleStore is the slice of db.Manager the recurring scheduler needs (kept narrow for tests).
+type focusRuleStore interface {
+	GetFocusRules() ([]db.FocusRule, error)
+	InsertFocusRule(r db.FocusRule) (string, error)
+	UpdateFocusRule(r db.FocusRule) error
+	DeleteFocusRule(recordID string) error
+}
+
+// weekdayNames maps the short names rules use to time.Weekday.
+var weekdayNames = map[string]time.Weekday{
+	"sun": time.Sunday,
+	"mon": time.Monday,
+	"tue": time.Tuesday,
+	"wed": time.Wednesday,
+	"thu": time.Thursday,
+	"fri": time.Friday,
+	"sat": time.Saturday,
+}
+
+// FocusRule is a weekly recurring focus window: on each of Weekdays, focus from
+// Start (local HH:MM) for DurationSeconds, except on the dates in Exceptions.
+type FocusRule struct {
+	ID              string   `json:"id"`
+	Weekdays        []string `json:"weekdays"`
+	Start           string   `json:"start"`
+	DurationSeconds int      `json:"duration_seconds"`
+	Exceptions      []string `json:"exceptions"`
+	Enabled         bool     `json:"enabled"`
+	FocusLabel
+}
+
+// Validate rejects rules the scheduler can't evaluate.
+func (r FocusRule) Validate() error {
+	if len(r.Weekdays) == 0 {
+		return fmt.Errorf("weekdays must not be empty")
+	}
+	for _, d := range r.Weekdays {
+		if _, ok := weekdayNames[d]; !ok {
+			return fmt.Errorf("unknown weekday %q (want sun, mon, tue, wed, thu, fri or sat)", d)
+		}
+	}
+	if _, err := time.Parse("15:04", r.Start); err != nil {
+		return fmt.Errorf("start must be HH:MM")
+	}
+	if r.DurationSeconds <= 0 {
+		return fmt.Errorf("duration_seconds must be positive")
+	}
+	for _, e := range r.Exceptions {
+		if _, err := time.Parse("2006-01-02", e); err != nil {
+			return fmt.Errorf("exception %q must be YYYY-MM-DD", e)
+		}
+	}
+	return nil
+}
+
+// openingOn returns the window the rule opens on day's date, if any.
+func (r FocusRule) openingOn(day time.Time) (FocusOccurrence, bool) {
+	if !r.Enabled {
+		return FocusOccurrence{}, false
+	}
+	repeats := false
+	for _, d := range r.Weekdays {
+		if weekdayNames[d] == day.Weekday() {
+			repeats = true
+			break
+		}
+	}
+	if !repeats {
+		return FocusOccurrence{}, false
+	}
+	date := day.Format("2006-01-02")
+	for _, e := range r.Exceptions {
+		if e == date {
+			return FocusOccurrence{}, false
+		}
+	}
+	clock, err := time.Parse("15:04", r.Start)
+	if err != nil {
+		return FocusOccurrence{}, false
+	}
+	start := time.Date(day.Year(), day.Month(), day.Day(), clock.Hour(), clock.Minute(), 0, 0, day.Location())
+	return FocusOccurrence{
+		RuleID:     r.ID,
+		Start:      start,
+		End:        start.Add(time.Duration(r.DurationSeconds) * time.Second),
+		FocusLabel: r.FocusLabel,
+	}, true
+}
+
+func (r FocusRule) toRecord() db.FocusRule {
+	return db.FocusRule{
+		RecordID:        r.ID,
+		Weekdays:        strings.Join(r.Weekdays, ","),
+		Start:           r.Start,
+		DurationSeconds: r.DurationSeconds,
+		Exceptions:      strings.Join(r.Exceptions, ","),
+		Label:           r.Label,
+		Project:         r.Project,
+		Task:            r.Task,
+		Enabled:         r.Enabled,
+	}
+}
+
+func focusRuleFromRecord(rec db.FocusRule) FocusRule {
+	split := func(s string) []string {
+		out := []string{}
+		for _, part := range strings.Split(s, ",") {
+			if part = strings.TrimSpace(part); part != "" {
+				out = append(out, part)
+			}
+		}
+		return out
+	}
+	return FocusRule{
+		ID:              rec.RecordID,
+		Weekdays:        split(rec.Weekdays),
+		Start:           rec.Start,
+		DurationSeconds: rec.DurationSeconds,
+		Exceptions:      split(rec.Exceptions),
+		Enabled:         rec.Enabled,
+		FocusLabel:      FocusLabel{Label: rec.Label, Project: rec.Project, Task: rec.Task},
+	}
+}
+
+// FocusOccurrence is one concrete window a rule opens.
+type FocusOccurrence struct {
+	RuleID string    `json:"rule_id"`
+	Start  time.Time `json:"start"`
+	End    time.Time `json:"end"`
+	FocusLabel
+}
+
+// occurrenceHorizon bounds how far ahead occurrences are searched, so rules that
+// can never open (all disabled, every date excepted) don't loop forever.
+const occurrenceHorizon = 366
+
+// nextOccurrences returns up to n windows of the enabled rules that are still
+// open or yet to open at from, earliest first.
+func nextOccurrences(rules []FocusRule, from time.Time, n int) []FocusOccurrence {
+	out := []FocusOccurrence{}
+	// Start a day early: a late-evening window may still be open past midnight.
+	day := time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, from.Location()).AddDate(0, 0, -1)
+	for i := 0; i <= occurrenceHorizon && len(out) < n; i++ {
+		var today []FocusOccurrence
+		for _, r := range rules {
+			if occ, ok := r.openingOn(day); ok && occ.End.After(from) {
+				today = append(today, occ)
+			}
+		}
+		sort.Slice(today, func(a, b int) bool { return today[a].Start.Before(today[b].Start) })
+		out = append(out, today...)
+		day = day.AddDate(0, 0, 1)
+	}
+	if len(out) > n {
+		out = out[:n]
+	}
+	return out
+}
+
+// RecurringScheduler opens focus windows from weekly rules. It keeps a single
+// timer for the next opening across all rules and re-evaluates whenever it
+// fires or the rules change.
+type RecurringScheduler struct {
+	state *State
+	store focusRuleStore
+
+	mu     sync.Mutex
+	rules  []FocusRule
+	timer  *time.Timer
+	opened map[openedWindow]time.Time // windows opened and still running, by their end, so none opens twice
+}
+
+// openedWindow identifies one occurrence of one rule.
+type openedWindow struct {
+	ruleID string
+	start  time.Time
+}
+
+func NewRecurringScheduler(state *State, store focusRuleStore) *RecurringScheduler {
+	return &RecurringScheduler{state: state, store: store}
+}
+
+// Load reads the rules from the store and starts evaluating them. A window that
+// is already open (e.g. after a restart) starts right away.
+func (r *RecurringScheduler) Load() error {
+	records, err := r.store.GetFocusRules()
+	if err != nil {
+		return err
+	}
+	rules := make([]FocusRule, 0, len(records))
+	for _, rec := range records {
+		rule := focusRuleFromRecord(rec)
+		if err := rule.Validate(); err != nil {
+			log.Warn("Skipping invalid focus rule", "id", rec.RecordID, "error", err)
+			continue
+		}
+		rules = append(rules, rule)
+	}
+
+	r.mu.Lock()
+	r.rules = rules
+	r.mu.Unlock()
+	log.Info("Loaded focus rules", "count", len(rules))
+	r.evaluate()
+	return nil
+}
+
+// Rules returns the current rules, in creation order.
+func (r *RecurringScheduler) Rules() []FocusRule {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]FocusRule{}, r.rules...)
+}
+
+// Save creates a rule (empty ID) or replaces an existing one, then reschedules.
+func (r *RecurringScheduler) Save(rule FocusRule) (FocusRule, error) {
+	if err := rule.Validate(); err != nil {
+		return FocusRule{}, err
+	}
+
+	if rule.ID == "" {
+		id, err := r.store.InsertFocusRule(rule.toRecord())
+		if err != nil {
+			return FocusRule{}, err
+		}
+		rule.ID = id
+		r.mu.Lock()
+		r.rules = append(r.rules, rule)
+		r.mu.Unlock()
+	} else {
+		r.mu.Lock()
+		i := r.indexLocked(rule.ID)
+		r.mu.Unlock()
+		if i < 0 {
+			return FocusRule{}, fmt.Errorf("no focus rule with id %q", rule.ID)
+		}
+		if err := r.store.UpdateFocusRule(rule.toRecord()); err != nil {
+			return FocusRule{}, err
+		}
+		r.mu.Lock()
+		if i := r.indexLocked(rule.ID); i >= 0 {
+			r.rules[i] = rule
+		}
+		r.mu.Unlock()
+	}
+
+	log.Info("Focus rule saved", "id", rule.ID, "weekdays", rule.Weekdays, "start", rule.Start)
+	r.evaluate()
+	return rule, nil
+}
+
+// Delete removes a rule. Returns false if no rule has that ID.
+func (r *RecurringScheduler) Delete(id string) (bool, error) {
+	r.mu.Lock()
+	found := r.indexLocked(id) >= 0
+	r.mu.Unlock()
+	if !found {
+		return false, nil
+	}
+	if err := r.store.DeleteFocusRule(id); err != nil {
+		return true, err
+	}
+
+	r.mu.Lock()
+	if i := r.indexLocked(id); i >= 0 {
+		r.rules = append(r.rules[:i], r.rules[i+1:]...)
+	}
+	r.mu.Unlock()
+
+	log.Info("Focus rule deleted", "id", id)
+	r.evaluate()
+	return true, nil
+}
+
+// Preview returns the next n windows across all enabled rules.
+func (r *RecurringScheduler) Preview(n int) []FocusOccurrence {
+	return nextOccurrences(r.Rules(), time.Now(), n)
+}
+
+// indexLocked returns the position of the rule with id, or -1. Must be called with r.mu held.
+func (r *RecurringScheduler) indexLocked(id string) int {
+	for i, rule := range r.rules {
+		if rule.ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+// evaluate opens every window that has started but not yet been opened, then
+// arms the timer for the next opening.
+func (r *RecurringScheduler) evaluate() {
+	now := time.Now()
+
+	r.mu.Lock()
+	if r.timer != nil {
+		r.timer.Stop()
+		r.timer = nil
+	}
+	for w, end := range r.opened {
+		if !end.After(now) {
+			delete(r.opened, w)
+		}
+	}
+	if r.opened == nil {
+		r.opened = map[openedWindow]time.Time{}
+	}
+	upcoming := nextOccurrences(r.rules, now, 16)
+	var opening []FocusOccurrence
+	var next *FocusOccurrence
+	for i := range upcoming {
+		occ := upcoming[i]
+		if occ.Start.After(now) {
+			next = &occ
+			break
+		}
+		w := openedWindow{ruleID: occ.RuleID, start: occ.Start}
+		if _, ok := r.opened[w]; !ok {
+			opening = append(opening, occ)
+			r.opened[w] = occ.End
+		}
+	}
+	if next != nil {
+		r.timer = time.AfterFunc(time.Until(next.Start), r.evaluate)
+	}
+	r.mu.Unlock()
+
+	for _, occ := range opening {
+		log.Info("Recurring focus window opened", "rule", occ.RuleID, "end", occ.End)
+		r.state.SetFocusingUntil(occ.End, occ.FocusLabel)
+	}
+}
@@ -0,0 +1,169 @@
+package coach
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"coach/internal/db"
+)
+
+// fakeFocusRuleStore keeps rules in memory instead of PocketBase.
+type fakeFocusRuleStore struct {
+	rules  []db.FocusRule
+	nextID int
+}
+
+func (f *fakeFocusRuleStore) GetFocusRules() ([]db.FocusRule, error) { return f.rules, nil }
+
+func (f *fakeFocusRuleStore) InsertFocusRule(r db.FocusRule) (string, error) {
+	f.nextID++
+	r.RecordID = fmt.Sprintf("rule%d", f.nextID)
+	f.rules = append(f.rules, r)
+	return r.RecordID, nil
+}
+
+func (f *fakeFocusRuleStore) UpdateFocusRule(r db.FocusRule) error { return nil }
+
+func (f *fakeFocusRuleStore) DeleteFocusRule(recordID string) error { return nil }
+
+func TestNextOccurrencesWeekdaysAndExceptions(t *testing.T) {
+	// Wednesday 2026-06-10, 12:00
+	from := time.Date(2026, 6, 10, 12, 0, 0, 0, time.UTC)
+	rules := []FocusRule{{
+		ID:              "r",
+		Weekdays:        []string{"mon", "wed", "fri"},
+		Start:           "14:00",
+		DurationSeconds: 90 * 60,
+		Exceptions:      []string{"2026-06-12"},
+		Enabled:         true,
+	}}
+
+	got := nextOccurrences(rules, from, 3)
+
+	want := []time.Time{
+		time.Date(2026, 6, 10, 14, 0, 0, 0, time.UTC),
+		time.Date(2026, 6, 15, 14, 0, 0, 0, time.UTC), // Friday the 12th is an exception
+		time.Date(2026, 6, 17, 14, 0, 0, 0, time.UTC),
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %d occurrences, want %d: %+v", len(got), len(want), got)
+	}
+	for i := range want {
+		if !got[i].Start.Equal(want[i]) {
+			t.Errorf("occurrence %d starts %v, want %v", i, got[i].Start, want[i])
+		}
+		if got[i].End.Sub(got[i].Start) != 90*time.Minute {
+			t.Errorf("occurrence %d lasts %v, want 1h30m", i, got[i].End.Sub(got[i].Start))
+		}
+	}
+}
+
+func TestNextOccurrencesIncludesOpenWindow(t *testing.T) {
+	// 00:30 on a Thursday, inside Wednesday's 23:00–01:00 window.
+	from := time.Date(2026, 6, 11, 0, 30, 0, 0, time.UTC)
+	rules := []FocusRule{{ID: "late", Weekdays: []string{"wed"}, Start: "23:00", DurationSeconds: 2 * 3600, Enabled: true}}
+
+	got := nextOccurrences(rules, from, 1)
+
+	if len(got) != 1 || !got[0].Start.Equal(time.Date(2026, 6, 10, 23, 0, 0, 0, time.UTC)) {
+		t.Errorf("Expected the still-open Wednesday window, got %+v", got)
+	}
+}
+
+func TestNextOccurrencesSkipsDisabled(t *testing.T) {
+	rules := []FocusRule{{ID: "off", Weekdays: []string{"mon"}, Start: "09:00", DurationSeconds: 60, Enabled: false}}
+
+	if got := nextOccurrences(rules, time.Now(), 5); len(got) != 0 {
+		t.Errorf("Disabled rules should never open, got %+v", got)
+	}
+}
+
+func TestFocusRuleValidate(t *testing.T) {
+	valid := FocusRule{Weekdays: []string{"mon"}, Start: "09:00", DurationSeconds: 60}
+	if err := valid.Validate(); err != nil {
+		t.Fatalf("valid rule rejected: %v", err)
+	}
+
+	for name, mutate := range map[string]func(*FocusRule){
+		"no weekdays":   func(r *FocusRule) { r.Weekdays = nil },
+		"bad weekday":   func(r *FocusRule) { r.Weekdays = []string{"monday"} },
+		"bad start":     func(r *FocusRule) { r.Start = "9am" },
+		"zero duration": func(r *FocusRule) { r.DurationSeconds = 0 },
+		"bad exception": func(r *FocusRule) { r.Exceptions = []string{"June 10"} },
+	} {
+		rule := valid
+		mutate(&rule)
+		if err := rule.Validate(); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestRecurringSchedulerOpensCurrentWindow(t *testing.T) {
+	state := &State{}
+	now := time.Now()
+	weekday := map[time.Weekday]string{
+		time.Sunday: "sun", time.Monday: "mon", time.Tuesday: "tue", time.Wednesday: "wed",
+		time.Thursday: "thu", time.Friday: "fri", time.Saturday: "sat",
+	}[now.Weekday()]
+	store := &fakeFocusRuleStore{rules: []db.FocusRule{{
+		RecordID:        "now",
+		Weekdays:        weekday,
+		Start:           now.Add(-time.Minute).Format("15:04"),
+		DurationSeconds: 3600,
+		Enabled:         true,
+	}}}
+	if now.Add(-time.Minute).Day() != now.Day() {
+		t.Skip("window would start yesterday; too close to midnight")
+	}
+
+	scheduler := NewRecurringScheduler(state, store)
+	if err := scheduler.Load(); err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+
+	if !isFocusing(state) {
+		t.Fatal("A window that is already open should start on load")
+	}
+
+	// Saving another rule re-evaluates, but must not reopen the same window.
+	state.clearFocus("stopped", "test")
+	if _, err := scheduler.Save(FocusRule{Weekdays: []string{"sun"}, Start: "03:00", DurationSeconds: 60, Enabled: true}); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	if isFocusing(state) {
+		t.Error("An already opened window should not open again")
+	}
+	if len(scheduler.Rules()) != 2 {
+		t.Errorf("Expected 2 rules after save, got %d", len(scheduler.Rules()))
+	}
+}
+
+func TestRecurringSchedulerOpensWindowsSharingAStart(t *testing.T) {
+	state := &State{}
+	now := time.Now()
+	start := now.Add(-time.Minute)
+	if start.Day() != now.Day() {
+		t.Skip("window would start yesterday; too close to midnight")
+	}
+	weekday := strings.ToLower(now.Weekday().String()[:3])
+	store := &fakeFocusRuleStore{rules: []db.FocusRule{
+		{RecordID: "short", Weekdays: weekday, Start: start.Format("15:04"), DurationSeconds: 1800, Enabled: true},
+		{RecordID: "long", Weekdays: weekday, Start: start.Format("15:04"), DurationSeconds: 7200, Enabled: true},
+	}}
+
+	scheduler := NewRecurringScheduler(state, store)
+	if err := scheduler.Load(); err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+
+	// The longer window must open too, running focus out to its end
+	if left := state.GetCurrentFocusInfo().FocusTimeLeft; left < 7000 {
+		t.Errorf("Expected focus to run to the longer window's end, %ds left", left)
+	}
+	if len(scheduler.opened) != 2 {
+		t.Errorf("Expected both windows opened, got %v", scheduler.opened)
+	}
+}
@@ -0,0 +1,104 @@
+package coach
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"coach/internal/db"
+)
+
+// errBudgetSpent is returned for a grant once today's release budget is gone.
+var errBudgetSpent = errors.New("today's release budget is spent; only an override can release the lock")
+
+// lockDecisionStore is the slice of db.Manager the release budget needs (kept narrow for tests).
+type lockDecisionStore interface {
+	lockDecisionQuerier
+	InsertLockDecisionEntry(d db.LockDecision) error
+}
+
+// ReleaseBudget caps how long grants may release the agent lock per day. Used
+// time is read back from today's grants in lock_decisions, so it survives
+// restarts; overrides don't count and are never refused.
+type ReleaseBudget struct {
+	Limit time.Duration
+	store lockDecisionStore
+
+	// mu serializes grants so two pleas can't both spend the same remainder.
+	mu sync.Mutex
+}
+
+func NewReleaseBudget(limit time.Duration, store lockDecisionStore) *ReleaseBudget {
+	return &ReleaseBudget{Limit: limit, store: store}
+}
+
+// BudgetStatus is how much of today's release budget the grants have used.
+type BudgetStatus struct {
+	LimitSeconds     int `json:"limit_seconds"`
+	UsedSeconds      int `json:"used_seconds"`
+	RemainingSeconds int `json:"remaining_seconds"`
+}
+
+// Status computes the budget from today's decisions.
+func (b *ReleaseBudget) Status(decisions []db.LockDecision) BudgetStatus {
+	limit := int(b.Limit / time.Second)
+	used := grantedSeconds(decisions)
+	return BudgetStatus{LimitSeconds: limit, UsedSeconds: used, RemainingSeconds: max(limit-used, 0)}
+}
+
+// Grant journals entry, a grant of up to entry.DurationSeconds, clipped to what
+// is left of today's budget, and returns the seconds granted. It journals
+// before the caller releases the lock so the next grant sees this one.
+func (b *ReleaseBudget) Grant(entry db.LockDecision) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	decisions, err := b.todayGrants()
+	if err != nil {
+		return 0, err
+	}
+	granted := min(entry.DurationSeconds, b.Status(decisions).RemainingSeconds)
+	if granted <= 0 {
+		return 0, errBudgetSpent
+	}
+	entry.DurationSeconds = granted
+	if err := b.store.InsertLockDecisionEntry(entry); err != nil {
+		return 0, fmt.Errorf("failed to journal grant: %w", err)
+	}
+	return granted, nil
+}
+
+// Today reads today's grants and computes the budget from them.
+func (b *ReleaseBudget) Today() (BudgetStatus, error) {
+	grants, err := b.todayGrants()
+	if err != nil {
+		return BudgetStatus{}, err
+	}
+	return b.Status(grants), nil
+}
+
+// todayGrants reads every grant journaled since local midnight. Only grants are
+// asked for, and every page of them, so the rest of a busy day's journal can't
+// crowd any out of the count.
+func (b *ReleaseBudget) todayGrants() ([]db.LockDecision, error) {
+	now := time.Now()
+	midnight := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	grants, err := allLockDecisions(b.store, db.LockDecisionQuery{From: midnight, Kinds: []string{"grant"}})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read today's grants: %w", err)
+	}
+	return grants, nil
+}
+
+// grantedSeconds totals the release time of today's grants. Lock policy
+// windows (source "schedule") are the user's own rules and don't count.
+func grantedSeconds(decisions []db.LockDecision) int {
+	total := 0
+	for _, d := range decisions {
+		if d.Kind == "grant" && d.Source != "schedule" {
+			total += d.DurationSeconds
+		}
+	}
+	return total
+}
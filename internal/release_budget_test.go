@@ -0,0 +1,131 @@
+package coach
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"coach/internal/db"
+)
+
+// fakeLockDecisionStore keeps the journal in memory instead of PocketBase.
+type fakeLockDecisionStore struct {
+	decisions []db.LockDecision
+	fail      bool
+}
+
+// QueryLockDecisions answers newest first, honouring the kinds, the cursor and
+// the limit; everything in the fake is from today.
+func (f *fakeLockDecisionStore) QueryLockDecisions(q db.LockDecisionQuery) ([]db.LockDecision, error) {
+	if f.fail {
+		return nil, fmt.Errorf("pb down")
+	}
+	var out []db.LockDecision
+	for i := len(f.decisions) - 1; i >= 0 && len(out) < q.Limit; i-- {
+		d := f.decisions[i]
+		if len(q.Kinds) > 0 && !slices.Contains(q.Kinds, d.Kind) {
+			continue
+		}
+		if q.AfterCreated != "" && (d.Created > q.AfterCreated || d.Created == q.AfterCreated && d.ID >= q.AfterID) {
+			continue
+		}
+		out = append(out, d)
+	}
+	return out, nil
+}
+
+func (f *fakeLockDecisionStore) InsertLockDecisionEntry(d db.LockDecision) error {
+	f.decisions = append(f.decisions, d)
+	return nil
+}
+
+func postRelease(server *Server, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/agent-lock/release", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	rr := httptest.NewRecorder()
+	server.AgentLockHandler(rr, req)
+	return rr
+}
+
+func TestReleaseBudgetClipsThenRefusesGrants(t *testing.T) {
+	store := &fakeLockDecisionStore{decisions: []db.LockDecision{
+		{Kind: "grant", DurationSeconds: 600},
+		{Kind: "override", DurationSeconds: 3600}, // overrides don't spend the budget
+	}}
+	server := &Server{State: &State{}, ReleaseBudget: NewReleaseBudget(15*time.Minute, store)}
+
+	if rr := postRelease(server, "duration=600&user_message=docs"); rr.Code != http.StatusOK {
+		t.Fatalf("Expected the grant to be clipped, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if last := store.decisions[len(store.decisions)-1]; last.Kind != "grant" || last.DurationSeconds != 300 {
+		t.Errorf("Expected a 300s grant journaled, got %+v", last)
+	}
+	if left := server.State.GetAgentLockInfo().TimeLeftSeconds; left == nil || *left > 300 {
+		t.Errorf("Expected the release clipped to 300s, got %v", left)
+	}
+
+	if rr := postRelease(server, "duration=60"); rr.Code != http.StatusConflict {
+		t.Errorf("Expected 409 once the budget is spent, got %d", rr.Code)
+	}
+	if rr := postRelease(server, "duration=60&is_override=true"); rr.Code != http.StatusOK {
+		t.Errorf("Overrides should pass a spent budget, got %d", rr.Code)
+	}
+}
+
+func TestReleaseBudgetFailsClosedWhenJournalIsUnreadable(t *testing.T) {
+	server := &Server{State: &State{}, ReleaseBudget: NewReleaseBudget(time.Hour, &fakeLockDecisionStore{fail: true})}
+
+	if rr := postRelease(server, "duration=60"); rr.Code != http.StatusInternalServerError {
+		t.Errorf("Expected 500, got %d", rr.Code)
+	}
+	if !agentLocked(server.State) {
+		t.Error("A grant that couldn't be checked should not release the lock")
+	}
+}
+
+func TestLockStateReportsBudget(t *testing.T) {
+	store := &fakeLockDecisionStore{decisions: []db.LockDecision{{Kind: "grant", DurationSeconds: 1200}}}
+	budget := NewReleaseBudget(time.Hour, store)
+	if got := budget.Status(store.decisions); got != (BudgetStatus{LimitSeconds: 3600, UsedSeconds: 1200, RemainingSeconds: 2400}) {
+		t.Errorf("Unexpected status %+v", got)
+	}
+
+	server := &Server{State: &State{}, ReleaseBudget: budget}
+	req := httptest.NewRequest(http.MethodGet, "/agent-lock/state", nil)
+	rr := httptest.NewRecorder()
+	server.AgentLockHandler(rr, req)
+	var out struct {
+		Budget *BudgetStatus `json:"budget"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &out); err != nil || out.Budget == nil || out.Budget.LimitSeconds != 3600 {
+		t.Errorf("Expected the budget in lock state, got %s", rr.Body.String())
+	}
+}
+
+func TestReleaseBudgetCountsGrantsPastTheFirstPage(t *testing.T) {
+	store := &fakeLockDecisionStore{}
+	for i := range 600 {
+		at := fmt.Sprintf("2026-03-01 08:%02d:%02d.000Z", i/60, i%60)
+		store.decisions = append(store.decisions,
+			db.LockDecision{ID: fmt.Sprintf("g%03d", i), Kind: "grant", DurationSeconds: 1, Created: at},
+			db.LockDecision{ID: fmt.Sprintf("t%03d", i), Kind: "turn", Created: at},
+		)
+	}
+	budget := NewReleaseBudget(700*time.Second, store)
+
+	status, err := budget.Today()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status.UsedSeconds != 600 {
+		t.Errorf("Expected all 600 grants counted, got %d", status.UsedSeconds)
+	}
+	if granted, err := budget.Grant(db.LockDecision{Kind: "grant", DurationSeconds: 300}); err != nil || granted != 100 {
+		t.Errorf("Expected the grant clipped to 100s, got %d (%v)", granted, err)
+	}
+}
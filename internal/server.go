@@ -3,13 +3,19 @@ package coach
 import (
 	"io/fs"
 	"net/http"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gorilla/websocket"
 
 	"github.com/charmbracelet/log"
 
+	"coach/internal/ai"
 	"coach/internal/db"
+	"coach/internal/dimaist"
 	"coach/internal/stats"
 )
 
@@ -18,8 +24,20 @@ type Server struct {
 	State            *State
 	DBManager        *db.Manager
 	AttentionTracker *AttentionTracker
-	AdminFS          fs.FS
-	upgrader         websocket.Upgrader
+	FocusScheduler   *FocusScheduler
+	FocusRules       *RecurringScheduler
+	LockPolicies     *LockPolicyScheduler
+	Hooks            *HookRunner
+	Webhooks         *WebhookDispatcher
+	CommandHooks     *CommandHookRunner
+	Tasks            taskSource
+	Calendar         *CalendarSync
+	ReleaseBudget    *ReleaseBudget
+	// UnlockDelay and UnlockDuration time a delayed unlock; zero means the default.
+	UnlockDelay    time.Duration
+	UnlockDuration time.Duration
+	AdminFS        fs.FS
+	upgrader       websocket.Upgrader
 }
 
 // NewServer creates and initializes a new server instance
@@ -50,6 +68,21 @@ func NewServer(adminFS fs.FS) (*Server, error) {
 	} else if created {
 		log.Info("Created agent_lock collection")
 	}
+	if added, err := dbManager.EnsureAgentLockFields(); err != nil {
+		log.Warn("Failed to add fields to agent_lock collection — scoped releases and delayed unlocks won't persist", "error", err)
+	} else if len(added) > 0 {
+		log.Info("Added fields to agent_lock collection", "fields", added)
+	}
+	if added, err := dbManager.EnsureFocusRecordFields(); err != nil {
+		log.Warn("Failed to add label fields to coach collection — focus labels and outcomes won't persist", "error", err)
+	} else if len(added) > 0 {
+		log.Info("Added fields to coach collection", "fields", added)
+	}
+	if created, err := dbManager.EnsureFocusPauseCollection(); err != nil {
+		log.Warn("Failed to ensure focus_pause collection — paused sessions won't survive restarts", "error", err)
+	} else if created {
+		log.Info("Created focus_pause collection")
+	}
 	if created, err := dbManager.EnsureAttentionCollection(); err != nil {
 		log.Warn("Failed to ensure attention collection — attention beacons won't persist", "error", err)
 	} else if created {
@@ -60,12 +93,47 @@ func NewServer(adminFS fs.FS) (*Server, error) {
 	} else if created {
 		log.Info("Created lock_decisions collection")
 	}
+	if added, err := dbManager.EnsureLockDecisionFields(); err != nil {
+		log.Warn("Failed to add fields to lock_decisions collection — release ends won't be journaled", "error", err)
+	} else if len(added) > 0 {
+		log.Info("Added fields to lock_decisions collection", "fields", added)
+	}
 	if created, err := dbManager.EnsureTemptationsCollection(); err != nil {
 		log.Warn("Failed to ensure temptations collection — temptations won't be recorded", "error", err)
 	} else if created {
 		log.Info("Created temptations collection")
 	}
-	server.AttentionTracker = NewAttentionTracker(dbManager)
+	if created, err := dbManager.EnsureFocusBlocksCollection(); err != nil {
+		log.Warn("Failed to ensure focus_blocks collection — scheduled focus blocks won't persist", "error", err)
+	} else if created {
+		log.Info("Created focus_blocks collection")
+	}
+	if created, err := dbManager.EnsureFocusRulesCollection(); err != nil {
+		log.Warn("Failed to ensure focus_rules collection — recurring focus windows won't persist", "error", err)
+	} else if created {
+		log.Info("Created focus_rules collection")
+	}
+	if created, err := dbManager.EnsureLockPoliciesCollection(); err != nil {
+		log.Warn("Failed to ensure lock_policies collection — lock policies won't persist", "error", err)
+	} else if created {
+		log.Info("Created lock_policies collection")
+	}
+	if created, err := dbManager.EnsureHooksCollection(); err != nil {
+		log.Warn("Failed to ensure hooks collection — hook configs won't persist", "error", err)
+	} else if created {
+		log.Info("Created hooks collection")
+	}
+	if created, err := dbManager.EnsureHookResultsCollections(); err != nil {
+		log.Warn("Failed to ensure hook result collections — hook results won't be stored", "error", err)
+	} else if len(created) > 0 {
+		log.Info("Created hook result collections", "collections", created)
+	}
+	if created, err := dbManager.EnsureWebhookCollections(); err != nil {
+		log.Warn("Failed to ensure webhook collections — webhooks won't be sent", "error", err)
+	} else if len(created) > 0 {
+		log.Info("Created webhook collections", "collections", created)
+	}
+	server.AttentionTracker = NewAttentionTracker(dbManager, server.State.Events())
 
 	stats, err := stats.New(dbManager)
 	if err != nil {
@@ -74,26 +142,184 @@ func NewServer(adminFS fs.FS) (*Server, error) {
 
 	server.State.stats = stats
 	server.State.dbManager = dbManager
-	server.State.AddHook(DatabaseHook(dbManager))
+	server.State.Events().Subscribe(DatabaseHook(dbManager))
 
-	// Restore active focus session from DB (if any)
-	if remaining, err := dbManager.GetActiveFocus(); err != nil {
+	// Restore active focus session from DB (if any): every record queued or
+	// extended into it that wasn't closed out. A paused session comes back
+	// paused; a resumed one keeps the end its pause pushed out, which the coach
+	// records alone don't know about. Records that ran out meanwhile are closed.
+	pause, err := dbManager.GetFocusPause()
+	if err != nil {
+		log.Warn("Failed to load focus pause state", "error", err)
+	}
+	if latest, err := dbManager.GetLatestFocusRecords(restoreFocusRecords); err != nil {
 		log.Warn("Failed to check for active focus session", "error", err)
-	} else if remaining > 0 {
-		server.State.RestoreFocus(remaining)
+	} else if session := openFocusSession(latest); len(session) > 0 {
+		last := session[len(session)-1]
+		remaining := time.Until(last.Timestamp.Add(time.Duration(last.Duration) * time.Second))
+		pausedAt := pause.PausedSince()
+		if pausedAt != nil {
+			remaining = time.Duration(pause.RemainingSeconds) * time.Second
+		} else if end := pause.ResumedEnd(); end != nil && time.Until(*end) > remaining {
+			remaining = time.Until(*end)
+		}
+		server.State.RestoreFocus(session, remaining, pausedAt)
 	}
 
 	// Restore active agent-lock release window from DB (if any)
-	if releaseUntil, err := dbManager.GetAgentReleaseUntil(); err != nil {
+	if releaseUntil, scope, err := dbManager.GetAgentRelease(); err != nil {
 		log.Warn("Failed to load agent lock state", "error", err)
 	} else {
-		server.State.RestoreAgentLock(releaseUntil)
+		server.State.RestoreAgentLock(releaseUntil, scope)
+	}
+	if grants, err := dbManager.GetReleaseGrants(); err != nil {
+		log.Warn("Failed to load release grants — restored releases won't link to their grants", "error", err)
+	} else {
+		server.State.RestoreReleaseGrants(grants)
+	}
+	if endedAt, err := dbManager.GetReleaseEndedAt(); err != nil {
+		log.Warn("Failed to load when the last release ended — the grant cooldown starts over", "error", err)
+	} else {
+		server.State.RestoreReleaseEndedAt(endedAt)
+	}
+	if pending, err := dbManager.GetPendingUnlock(); err != nil {
+		log.Warn("Failed to load delayed unlock", "error", err)
+	} else if pending != nil {
+		server.State.RestorePendingUnlock(*pending)
 	}
 	server.DBManager = dbManager
 
+	// Daily cap on grant releases, only if the operator set one
+	if v := os.Getenv("AGENT_RELEASE_BUDGET"); v != "" {
+		if limit, err := time.ParseDuration(v); err != nil || limit <= 0 {
+			log.Warn("Invalid AGENT_RELEASE_BUDGET — grants are unlimited", "value", v)
+		} else {
+			server.ReleaseBudget = NewReleaseBudget(limit, dbManager)
+			log.Info("Agent release budget enabled", "per_day", limit)
+		}
+	}
+
+	// Keep the agent lock engaged through focus sessions, only if the operator asked
+	if v := os.Getenv("AGENT_LOCK_DURING_FOCUS"); v != "" {
+		if on, err := strconv.ParseBool(v); err != nil {
+			log.Warn("Invalid AGENT_LOCK_DURING_FOCUS — focus doesn't hold the agent lock", "value", v)
+		} else {
+			server.State.SetLockDuringFocus(on)
+			log.Info("Agent lock during focus", "enabled", on)
+		}
+	}
+
+	// Delayed unlock timings, each falling back to its default
+	server.UnlockDelay, server.UnlockDuration = DefaultUnlockDelay, DefaultUnlockDuration
+	if v := os.Getenv("AGENT_UNLOCK_DELAY"); v != "" {
+		if d, err := time.ParseDuration(v); err != nil || d <= 0 {
+			log.Warn("Invalid AGENT_UNLOCK_DELAY — using the default", "value", v, "default", DefaultUnlockDelay)
+		} else {
+			server.UnlockDelay = d
+		}
+	}
+	if v := os.Getenv("AGENT_UNLOCK_DURATION"); v != "" {
+		if d, err := time.ParseDuration(v); err != nil || d <= 0 {
+			log.Warn("Invalid AGENT_UNLOCK_DURATION — using the default", "value", v, "default", DefaultUnlockDuration)
+		} else {
+			server.UnlockDuration = d
+		}
+	}
+
+	// Minimum gap between one release ending and the next grant, only if the operator set one
+	if v := os.Getenv("AGENT_GRANT_COOLDOWN"); v != "" {
+		if d, err := time.ParseDuration(v); err != nil || d <= 0 {
+			log.Warn("Invalid AGENT_GRANT_COOLDOWN — grants have no cooldown", "value", v)
+		} else {
+			server.State.SetAgentGrantCooldown(d)
+			log.Info("Agent grant cooldown enabled", "cooldown", d)
+		}
+	}
+
+	// Schedule focus blocks booked before the restart
+	server.FocusScheduler = NewFocusScheduler(server.State, dbManager)
+	if err := server.FocusScheduler.Load(); err != nil {
+		log.Warn("Failed to load focus blocks", "error", err)
+	}
+	server.FocusRules = NewRecurringScheduler(server.State, dbManager)
+	if err := server.FocusRules.Load(); err != nil {
+		log.Warn("Failed to load focus rules", "error", err)
+	}
+	server.LockPolicies = NewLockPolicyScheduler(server.State, dbManager)
+	if err := server.LockPolicies.Load(); err != nil {
+		log.Warn("Failed to load lock policies", "error", err)
+	}
+	if tasks := dimaist.NewClientFromEnv(); tasks != nil {
+		server.Tasks = tasks
+	} else {
+		log.Info("DIMAIST_URL not set — today's tasks unavailable")
+	}
+	server.Hooks = NewHookRunner(server.State, dbManager)
+	server.Hooks.UseTasks(server.Tasks)
+	if aiClient := ai.NewClientFromEnv(); aiClient != nil {
+		server.Hooks.Register(NewAIHookDef(aiClient, dbManager, os.Getenv("AI_MODEL")))
+	} else {
+		log.Info("AI_URL not set — AI coaching hook disabled")
+	}
+	if err := server.Hooks.Load(); err != nil {
+		log.Warn("Failed to load hook configs", "error", err)
+	}
+	server.Webhooks = NewWebhookDispatcher(dbManager)
+	if err := server.Webhooks.Load(); err != nil {
+		log.Warn("Failed to load webhooks", "error", err)
+	}
+	server.Webhooks.Start(server.State.Events())
+
+	// Calendar-driven focus and meetings, only if the operator listed calendars
+	if sources := os.Getenv("CALENDAR_SOURCES"); sources != "" {
+		server.Calendar = newCalendarSyncFromEnv(server.State, sources)
+		server.Calendar.Start()
+	}
+
+	// Local commands on focus transitions, only if the operator configured a file
+	if path := os.Getenv("COMMAND_HOOKS_FILE"); path != "" {
+		if runner, err := LoadCommandHooks(server.State, path); err != nil {
+			log.Warn("Failed to load command hooks — none will run", "error", err)
+		} else {
+			server.CommandHooks = runner
+			server.State.Events().Subscribe(runner.Handle)
+		}
+	}
+
 	return server, nil
 }
 
+// newCalendarSyncFromEnv builds the sync for a comma-separated list of
+// calendar files and URLs. It reads the focus pattern (CALENDAR_FOCUS_PATTERN) and
+// poll interval (CALENDAR_POLL_INTERVAL), falling back to the defaults when
+// either is unset or malformed.
+func newCalendarSyncFromEnv(state *State, list string) *CalendarSync {
+	var sources []string
+	for _, source := range strings.Split(list, ",") {
+		if source = strings.TrimSpace(source); source != "" {
+			sources = append(sources, source)
+		}
+	}
+	focus := regexp.MustCompile(defaultCalendarFocusPattern)
+	if v := os.Getenv("CALENDAR_FOCUS_PATTERN"); v != "" {
+		if re, err := regexp.Compile(v); err != nil {
+			log.Warn("Invalid CALENDAR_FOCUS_PATTERN — using the default", "error", err)
+		} else {
+			focus = re
+		}
+	}
+	interval := 5 * time.Minute
+	if v := os.Getenv("CALENDAR_POLL_INTERVAL"); v != "" {
+		if d, err := time.ParseDuration(v); err != nil || d < time.Minute {
+			log.Warn("Invalid CALENDAR_POLL_INTERVAL — using the default", "value", v, "default", interval)
+		} else {
+			interval = d
+		}
+	}
+	log.Info("Calendar sync enabled", "sources", len(sources), "focus_pattern", focus.String(), "interval", interval)
+	return NewCalendarSync(state, sources, focus, interval)
+}
+
 // corsMiddleware adds CORS headers to all responses
 func corsMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -115,6 +341,23 @@ func (s *Server) SetupRoutes() http.Handler {
 	mux := http.NewServeMux()
 	mux.HandleFunc("/health", s.HealthHandler)
 	mux.HandleFunc("/focusing", s.FocusHandler)
+	mux.HandleFunc("/focus-cycle", s.FocusCycleHandler)
+	mux.HandleFunc("/focus-cycle/stop", s.FocusCycleHandler)
+	mux.HandleFunc("/focus-schedule", s.FocusScheduleHandler)
+	mux.HandleFunc("/focus-schedule/cancel", s.FocusScheduleHandler)
+	mux.HandleFunc("/focus-rules", s.FocusRulesHandler)
+	mux.HandleFunc("/focus-rules/delete", s.FocusRulesHandler)
+	mux.HandleFunc("/focus-rules/preview", s.FocusRulesHandler)
+	mux.HandleFunc("/hooks", s.HooksHandler)
+	mux.HandleFunc("/hooks/trigger", s.HooksHandler)
+	mux.HandleFunc("/hook-results", s.HookResultsHandler)
+	mux.HandleFunc("/hook-results/seen", s.HookResultsHandler)
+	mux.HandleFunc("/webhooks", s.WebhooksHandler)
+	mux.HandleFunc("/webhooks/delete", s.WebhooksHandler)
+	mux.HandleFunc("/webhooks/deliveries", s.WebhooksHandler)
+	mux.HandleFunc("/command-hooks", s.CommandHooksHandler)
+	mux.HandleFunc("/command-hooks/toggle", s.CommandHooksHandler)
+	mux.HandleFunc("/tasks/today", s.TodayTasksHandler)
 	mux.HandleFunc("/history", s.HistoryHandler)
 	mux.HandleFunc("/attention", s.AttentionHandler)
 	mux.HandleFunc("/attention/summary", s.AttentionSummaryHandler)
@@ -122,8 +365,15 @@ func (s *Server) SetupRoutes() http.Handler {
 	mux.HandleFunc("/agent-lock", s.AgentLockHandler)
 	mux.HandleFunc("/agent-lock/release", s.AgentLockHandler)
 	mux.HandleFunc("/agent-lock/engage", s.AgentLockHandler)
+	mux.HandleFunc("/agent-lock/request-delayed", s.AgentLockHandler)
+	mux.HandleFunc("/agent-lock/cancel-delayed", s.AgentLockHandler)
 	mux.HandleFunc("/agent-lock/state", s.AgentLockHandler)
+	mux.HandleFunc("/agent-lock/trends", s.AgentLockHandler)
 	mux.HandleFunc("/lock-decisions", s.LockDecisionsHandler)
+	mux.HandleFunc("/lock-decisions/threads/{id}", s.LockThreadHandler)
+	mux.HandleFunc("/lock-policies", s.LockPoliciesHandler)
+	mux.HandleFunc("/lock-policies/delete", s.LockPoliciesHandler)
+	mux.HandleFunc("/lock-policies/windows", s.LockPoliciesHandler)
 	mux.Handle("/admin/", s.AdminHandler())
 
 	return corsMiddleware(mux)
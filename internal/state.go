@@ -4,6 +4,7 @@ import (
 	"coach/internal/db"
 	"coach/internal/stats"
 	"encoding/json"
+	"sort"
 	"sync"
 	"time"
 
@@ -13,9 +14,42 @@ import (
 	"slices"
 )
 
+// FocusLabel says what a focus session is for. Every field is optional.
+type FocusLabel struct {
+	Label   string `json:"label,omitempty"`
+	Project string `json:"project,omitempty"`
+	Task    string `json:"task,omitempty"`
+}
+
 type FocusRequest struct {
 	StartTime time.Time
 	EndTime   time.Time
+	FocusLabel
+	// Committed requests can only be cancelled with a journaled override
+	Committed bool
+
+	// recordID is the coach record this period is saved under and planned is
+	// its recorded duration. Pauses shift StartTime/EndTime but never these.
+	recordID string
+	planned  time.Duration
+}
+
+// focusedAt returns how much of the request had been focused by clock: all of it
+// once ended, none before it started.
+func (r FocusRequest) focusedAt(clock time.Time) time.Duration {
+	from := r.StartTime
+	if clock.After(from) {
+		from = clock
+	}
+	left := r.EndTime.Sub(from)
+	if left < 0 {
+		left = 0
+	}
+	focused := r.planned - left
+	if focused < 0 {
+		focused = 0
+	}
+	return focused
 }
 
 type State struct {
@@ -23,27 +57,85 @@ type State struct {
 
 	clients           map[*websocket.Conn]bool
 	focusRequests     []FocusRequest
-	hooks             []Hook
+	events            *EventBus
+	eventsOnce        sync.Once
 	mu                sync.Mutex
 	stats             *stats.Stats
 	expiryTimer       *time.Timer
 	dbManager         *db.Manager
+	pausedAt          *time.Time
+	cycle             *cycleState
 	agentReleaseUntil *time.Time
 	agentLockTimer    *time.Timer
+	// agentScopedReleases maps a released target (hostname or app package) to
+	// when its release ends, alongside the full release in agentReleaseUntil.
+	agentScopedReleases map[string]time.Time
+	// agentReleaseGrants maps each running release (db.FullReleaseKey or a
+	// target) to the grant that opened it, so its end can be journaled.
+	agentReleaseGrants map[string]db.ReleaseGrant
+	meetings           []Meeting
+	// lockDuringFocus holds the agent lock engaged while focus is queued.
+	// focusHoldsLock is true while it does, masking every release window;
+	// focusHoldBroken is set by an override and lasts until focus ends.
+	lockDuringFocus bool
+	focusHoldsLock  bool
+	focusHoldBroken bool
+	// agentGrantCooldown is the minimum gap between the end of a release and
+	// the next grant; agentReleaseEndedAt is when a grant's release last ended.
+	agentGrantCooldown  time.Duration
+	agentReleaseEndedAt time.Time
+	pendingUnlock       *pendingUnlock
+	pendingUnlockWrites pendingUnlockWriter
 }
 
 type FocusInfo struct {
-	Type                 string        `json:"type"`
-	Focusing             bool          `json:"focusing"`
-	SinceLastChange      time.Duration `json:"since_last_change"`
-	FocusTimeLeft        time.Duration `json:"focus_time_left"`
-	NumFocuses           int           `json:"num_focuses"`
-	AgentReleaseTimeLeft *int64        `json:"agent_release_time_left"`
+	Type                 string             `json:"type"`
+	Focusing             bool               `json:"focusing"`
+	SinceLastChange      time.Duration      `json:"since_last_change"`
+	FocusTimeLeft        time.Duration      `json:"focus_time_left"`
+	NumFocuses           int                `json:"num_focuses"`
+	Committed            bool               `json:"committed"`
+	Paused               bool               `json:"paused"`
+	PausedFor            time.Duration      `json:"paused_for"`
+	Phase                string             `json:"phase"`
+	CycleIndex           int                `json:"cycle_index"`
+	PhaseTimeLeft        time.Duration      `json:"phase_time_left"`
+	AgentReleaseTimeLeft *int64             `json:"agent_release_time_left"`
+	AgentReleaseTargets  []ScopedRelease    `json:"agent_release_targets"`
+	AgentLockHeldByFocus bool               `json:"agent_lock_held_by_focus"`
+	AgentCooldownLeft    int64              `json:"agent_cooldown_left"`
+	AgentPendingUnlock   *PendingUnlockInfo `json:"agent_pending_unlock"`
+	UpcomingMeetings     []Meeting          `json:"upcoming_meetings"`
+}
+
+// Meeting is a calendar event that isn't focus time, so a focus session running
+// into it will be cut short.
+type Meeting struct {
+	Title string    `json:"title"`
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
 }
 
-// AgentLockInfo is the public shape of agent-lock state. TimeLeftSeconds is nil when locked.
+// maxUpcomingMeetings caps how many meetings FocusInfo carries.
+const maxUpcomingMeetings = 5
+
+// AgentLockInfo is the public shape of agent-lock state. TimeLeftSeconds is nil when
+// locked; Targets lists what is released on its own while the rest stays locked.
+// HeldByFocus is true while a focus session keeps the lock engaged, and
+// CooldownSeconds is how long until the next grant is accepted (0 if it would be).
+// PendingUnlock is the delayed unlock counting down, if any.
 type AgentLockInfo struct {
-	TimeLeftSeconds *int64 `json:"time_left_seconds"`
+	TimeLeftSeconds *int64             `json:"time_left_seconds"`
+	Targets         []ScopedRelease    `json:"targets"`
+	HeldByFocus     bool               `json:"held_by_focus"`
+	CooldownSeconds int64              `json:"cooldown_seconds"`
+	PendingUnlock   *PendingUnlockInfo `json:"pending_unlock"`
+}
+
+// ScopedRelease is a release of a single target: a hostname or an app package.
+type ScopedRelease struct {
+	Target          string `json:"target"`
+	TimeLeftSeconds int64  `json:"time_left_seconds"`
 }
 
 func (s *State) GetCurrentFocusInfo() FocusInfo {
@@ -55,82 +147,539 @@ func (s *State) GetCurrentFocusInfo() FocusInfo {
 	if s.stats != nil {
 		numFocuses = s.stats.GetTodayFocusCount()
 	}
+	var pausedFor time.Duration
+	if s.pausedAt != nil {
+		pausedFor = time.Since(*s.pausedAt)
+	}
+	phase, cycleIndex, phaseTimeLeft := s.cyclePhaseLocked()
 	return FocusInfo{
 		Type:                 "focusing",
-		Focusing:             s.getTimeLeftLocked() > 0,
+		Focusing:             focusTimeLeft > 0 && s.pausedAt == nil,
 		SinceLastChange:      sinceLastChange / time.Second,
 		FocusTimeLeft:        focusTimeLeft / time.Second,
 		NumFocuses:           numFocuses,
+		Committed:            s.isCommittedLocked(),
+		Paused:               s.pausedAt != nil,
+		PausedFor:            pausedFor / time.Second,
+		Phase:                phase,
+		CycleIndex:           cycleIndex,
+		PhaseTimeLeft:        phaseTimeLeft / time.Second,
 		AgentReleaseTimeLeft: s.agentReleaseTimeLeftLocked(),
+		AgentReleaseTargets:  s.scopedReleasesLocked(),
+		AgentLockHeldByFocus: s.focusHoldsLock,
+		AgentCooldownLeft:    s.agentCooldownSecondsLocked(),
+		AgentPendingUnlock:   s.pendingUnlockInfoLocked(),
+		UpcomingMeetings:     s.upcomingMeetingsLocked(time.Now()),
 	}
 }
 
+// SetMeetings replaces the known meetings with the calendar's latest view,
+// sorted by start. Clients are told when it changes.
+func (s *State) SetMeetings(meetings []Meeting) {
+	s.mu.Lock()
+	changed := len(meetings) != len(s.meetings)
+	for i := 0; !changed && i < len(meetings); i++ {
+		changed = meetings[i] != s.meetings[i]
+	}
+	s.meetings = meetings
+	s.mu.Unlock()
+	if changed {
+		go s.NotifyAllClients(s.GetCurrentFocusInfo())
+	}
+}
+
+// upcomingMeetingsLocked returns the meetings that haven't ended, soonest first.
+// Must be called with mutex held.
+func (s *State) upcomingMeetingsLocked(now time.Time) []Meeting {
+	out := []Meeting{}
+	for _, m := range s.meetings {
+		if m.End.After(now) && len(out) < maxUpcomingMeetings {
+			out = append(out, m)
+		}
+	}
+	return out
+}
+
 // GetAgentLockInfo returns the current agent-lock state.
 func (s *State) GetAgentLockInfo() AgentLockInfo {
 	s.mu.Lock()
 	defer s.mu.Unlock()
-	return AgentLockInfo{TimeLeftSeconds: s.agentReleaseTimeLeftLocked()}
+	return AgentLockInfo{
+		TimeLeftSeconds: s.agentReleaseTimeLeftLocked(),
+		Targets:         s.scopedReleasesLocked(),
+		HeldByFocus:     s.focusHoldsLock,
+		CooldownSeconds: s.agentCooldownSecondsLocked(),
+		PendingUnlock:   s.pendingUnlockInfoLocked(),
+	}
 }
 
 // ReleaseAgentLock unlocks the agent lock for d. If a release is already active and ends
-// after now+d, this is a no-op (we never shorten an existing release here).
-func (s *State) ReleaseAgentLock(d time.Duration) {
+// after now+d, this is a no-op (we never shorten an existing release here). Reports
+// whether the release changed.
+func (s *State) ReleaseAgentLock(d time.Duration) bool {
+	return s.ReleaseAgentLockFor(d, nil)
+}
+
+// ReleaseAgentLockFor unlocks only targets (hostnames or app packages) for d;
+// with no targets it unlocks everything, like ReleaseAgentLock. Each target's
+// release extends but never shortens, independently of the others and of a
+// full release. While focus holds the lock the release is kept but only opens
+// anything once focus ends. Reports whether any release changed.
+func (s *State) ReleaseAgentLockFor(d time.Duration, targets []string) bool {
+	return s.ReleaseAgentLockGrant(d, targets, "")
+}
+
+// ReleaseAgentLockGrant is ReleaseAgentLockFor for a journaled grant: grantID
+// is the lock_decisions id of the entry, which the entry journaling the end of
+// the release links back to.
+func (s *State) ReleaseAgentLockGrant(d time.Duration, targets []string, grantID string) bool {
 	if d <= 0 {
-		return
+		return false
+	}
+	return s.releaseAgentLockUntil(time.Now().Add(d), targets, db.ReleaseGrant{ID: grantID})
+}
+
+// ReleaseAgentLockUntil unlocks the agent lock until end, like ReleaseAgentLock
+// with an exact end. Reports whether the release changed.
+func (s *State) ReleaseAgentLockUntil(end time.Time) bool {
+	if !end.After(time.Now()) {
+		return false
 	}
+	return s.releaseAgentLockUntil(end, nil, db.ReleaseGrant{})
+}
+
+// releaseAgentLockUntil releases targets (everything with none) until candidate.
+// grant is the grant behind it, its Since left for the release to fill in.
+func (s *State) releaseAgentLockUntil(candidate time.Time, targets []string, grant db.ReleaseGrant) bool {
 	s.mu.Lock()
-	candidate := time.Now().Add(d)
+	now := time.Now()
+	full := len(targets) == 0
 	changed := false
-	if s.agentReleaseUntil == nil || s.agentReleaseUntil.Before(candidate) {
-		until := candidate
-		s.agentReleaseUntil = &until
+	var extended []string
+	if full {
+		if s.agentReleaseUntil == nil || s.agentReleaseUntil.Before(candidate) {
+			s.noteReleaseGrantLocked(db.FullReleaseKey, grant, !s.isAgentLockedLocked(), now)
+			until := candidate
+			s.agentReleaseUntil = &until
+			changed = true
+		}
+	} else {
+		if s.agentScopedReleases == nil {
+			s.agentScopedReleases = make(map[string]time.Time)
+		}
+		for _, target := range targets {
+			if until, ok := s.agentScopedReleases[target]; !ok || until.Before(candidate) {
+				s.noteReleaseGrantLocked(target, grant, ok && now.Before(until), now)
+				s.agentScopedReleases[target] = candidate
+				extended = append(extended, target)
+				changed = true
+			}
+		}
+	}
+	if changed {
 		s.scheduleAgentLockTimerLocked()
-		s.persistAgentReleaseUntilLocked()
-		changed = true
+		s.persistAgentReleaseLocked()
 	}
+	held := s.focusHoldsLock
 	s.mu.Unlock()
 
-	if changed {
-		log.Info("Agent lock released", "until", candidate)
+	// Under the focus hold the release only takes effect once focus ends,
+	// which is when it is announced
+	if changed && held {
+		log.Info("Agent lock release deferred until focus ends", "until", candidate, "targets", extended)
+	} else if changed {
+		if full {
+			log.Info("Agent lock released", "until", candidate)
+		} else {
+			log.Info("Agent lock released for targets", "until", candidate, "targets", extended)
+		}
+		s.Events().Publish(LockReleased{At: time.Now(), Until: candidate, Targets: extended})
 		go s.NotifyAllClients(s.GetCurrentFocusInfo())
 	}
+	return changed
 }
 
-// EngageAgentLock cancels any active release window.
+// agentReleaseEnd returns when the full release ends, or nil if locked.
+func (s *State) agentReleaseEnd() *time.Time {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.isAgentLockedLocked() {
+		return nil
+	}
+	until := *s.agentReleaseUntil
+	return &until
+}
+
+// EngageAgentLock cancels any active release window, full or scoped.
 func (s *State) EngageAgentLock() {
+	s.engageAgentLock("agent")
+}
+
+// engageAgentLock is EngageAgentLock, journaling the releases it cuts short as
+// engaged by source.
+func (s *State) engageAgentLock(source string) {
 	s.mu.Lock()
-	changed := s.agentReleaseUntil != nil
+	changed := s.agentReleaseUntil != nil || len(s.agentScopedReleases) > 0
+	ends := make(map[string]time.Time, len(s.agentScopedReleases)+1)
+	if s.agentReleaseUntil != nil {
+		ends[db.FullReleaseKey] = *s.agentReleaseUntil
+	}
+	for target, until := range s.agentScopedReleases {
+		ends[target] = until
+	}
+	cool := s.grantEndsLocked(ends)
+	s.journalLocked(s.releaseEndsLocked(ends, "engage", source, time.Now()))
 	s.agentReleaseUntil = nil
+	s.agentScopedReleases = nil
 	if s.agentLockTimer != nil {
 		s.agentLockTimer.Stop()
 		s.agentLockTimer = nil
 	}
+	if cool {
+		s.agentReleaseEndedAt = time.Now()
+	}
 	if changed {
-		s.persistAgentReleaseUntilLocked()
+		s.persistAgentReleaseLocked()
 	}
+	held := s.focusHoldsLock
 	s.mu.Unlock()
 
-	if changed {
+	if changed && !held {
 		log.Info("Agent lock engaged")
+		s.Events().Publish(LockEngaged{At: time.Now()})
 		go s.NotifyAllClients(s.GetCurrentFocusInfo())
 	}
 }
 
-// RestoreAgentLock seeds agentReleaseUntil from persisted state on startup and schedules
-// the snap-back timer for the remainder. Past values are ignored (lock stays engaged).
-func (s *State) RestoreAgentLock(t *time.Time) {
-	if t == nil || !time.Now().Before(*t) {
+// RestoreAgentLock seeds the full release (until) and the target releases
+// (scope) from persisted state on startup and schedules the snap-back timer.
+// Past values are ignored (the lock stays engaged for them).
+func (s *State) RestoreAgentLock(until *time.Time, scope map[string]time.Time) {
+	now := time.Now()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if until != nil && now.Before(*until) {
+		t := *until
+		s.agentReleaseUntil = &t
+		log.Info("Restored agent lock release", "until", t)
+	}
+	for target, t := range scope {
+		if !now.Before(t) {
+			continue
+		}
+		if s.agentScopedReleases == nil {
+			s.agentScopedReleases = make(map[string]time.Time)
+		}
+		s.agentScopedReleases[target] = t
+		log.Info("Restored agent lock release for target", "target", target, "until", t)
+	}
+	s.scheduleAgentLockTimerLocked()
+}
+
+// RestoreReleaseGrants seeds which grant opened each release restored by
+// RestoreAgentLock, so their ends link back after a restart. Grants of
+// releases that weren't restored are dropped.
+func (s *State) RestoreReleaseGrants(grants map[string]db.ReleaseGrant) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for key, g := range grants {
+		_, scoped := s.agentScopedReleases[key]
+		if (key == db.FullReleaseKey && s.agentReleaseUntil != nil) || scoped {
+			if s.agentReleaseGrants == nil {
+				s.agentReleaseGrants = make(map[string]db.ReleaseGrant)
+			}
+			s.agentReleaseGrants[key] = g
+		}
+	}
+}
+
+// RestoreReleaseEndedAt seeds when a grant's release last ended, so the grant
+// cooldown survives a restart.
+func (s *State) RestoreReleaseEndedAt(t time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.agentReleaseEndedAt = t
+}
+
+// RunningReleaseSeconds returns how long the releases still running have been
+// open so far today, counted once per grant like the journal's end entries.
+// Like them it goes by the clock, so time focus held a release back counts too.
+func (s *State) RunningReleaseSeconds() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	midnight := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	seen := map[releaseGrantKey]bool{}
+	total := 0
+	span := func(since, until time.Time) int {
+		if since.Before(midnight) {
+			since = midnight
+		}
+		return max(int(until.Sub(since)/time.Second), 0)
+	}
+	count := func(key string) {
+		g, ok := s.agentReleaseGrants[key]
+		if !ok {
+			return
+		}
+		for _, e := range g.Superseded {
+			if k := (releaseGrantKey{e.ID, e.Since}); !seen[k] {
+				seen[k] = true
+				total += span(e.Since, e.Until)
+			}
+		}
+		if k := (releaseGrantKey{g.ID, g.Since}); !seen[k] {
+			seen[k] = true
+			total += span(g.Since, now)
+		}
+	}
+	if !s.isAgentLockedLocked() {
+		count(db.FullReleaseKey)
+	}
+	for target, until := range s.agentScopedReleases {
+		if now.Before(until) {
+			count(target)
+		}
+	}
+	return total
+}
+
+// releaseGrantKey tells grants apart for journaling, so releases sharing one
+// end it once.
+type releaseGrantKey struct {
+	id    string
+	since time.Time
+}
+
+// noteReleaseGrantLocked records grant as the grant behind the release under
+// key. A release that was already running keeps its grant when extended
+// without one; extended with one, the earlier grant is kept as superseded so
+// its end is still journaled. Must be called with s.mu held.
+func (s *State) noteReleaseGrantLocked(key string, grant db.ReleaseGrant, running bool, now time.Time) {
+	if s.agentReleaseGrants == nil {
+		s.agentReleaseGrants = make(map[string]db.ReleaseGrant)
+	}
+	g, ok := s.agentReleaseGrants[key]
+	if !running || !ok {
+		g = db.ReleaseGrant{ID: grant.ID, Scheduled: grant.Scheduled, Since: now}
+	} else if grant.ID != "" && grant.ID != g.ID {
+		superseded := append(slices.Clone(g.Superseded), db.SupersededGrant{ID: g.ID, Since: g.Since, Until: now})
+		g = db.ReleaseGrant{ID: grant.ID, Scheduled: grant.Scheduled, Since: now, Superseded: superseded}
+	}
+	s.agentReleaseGrants[key] = g
+}
+
+// releaseEndsLocked forgets the grants of the releases in ends (key to when
+// the release was due to end) and returns the journal entries for their end:
+// kind "engage" or "expired", one per grant behind the release, each with how
+// long the release ran under it. Releases sharing a grant get one entry.
+// Must be called with s.mu held.
+func (s *State) releaseEndsLocked(ends map[string]time.Time, kind, source string, now time.Time) []db.LockDecision {
+	seen := map[releaseGrantKey]bool{}
+	var entries []db.LockDecision
+	add := func(id string, since, end time.Time) {
+		k := releaseGrantKey{id, since}
+		if seen[k] {
+			return
+		}
+		seen[k] = true
+		entries = append(entries, db.LockDecision{
+			Kind:          kind,
+			Source:        source,
+			GrantID:       id,
+			ActualSeconds: max(int(end.Sub(since)/time.Second), 0),
+		})
+	}
+	for key, until := range ends {
+		g, ok := s.agentReleaseGrants[key]
+		delete(s.agentReleaseGrants, key)
+		if !ok {
+			continue
+		}
+		for _, e := range g.Superseded {
+			add(e.ID, e.Since, e.Until)
+		}
+		end := now
+		if until.Before(end) {
+			end = until
+		}
+		add(g.ID, g.Since, end)
+	}
+	return entries
+}
+
+// grantEndsLocked reports whether ending the releases in ends starts the grant
+// cooldown: one of them was opened by a grant rather than a lock policy window,
+// and the user had it, focus not holding it back. Must be called with s.mu held.
+func (s *State) grantEndsLocked(ends map[string]time.Time) bool {
+	if s.focusHoldsLock {
+		return false
+	}
+	for key := range ends {
+		if g, ok := s.agentReleaseGrants[key]; ok && !g.Scheduled {
+			return true
+		}
+	}
+	return false
+}
+
+// journalLocked writes entries to lock_decisions. Best-effort, async.
+// Must be called with s.mu held.
+func (s *State) journalLocked(entries []db.LockDecision) {
+	if s.dbManager == nil || len(entries) == 0 {
 		return
 	}
+	go func() {
+		for _, e := range entries {
+			if err := s.dbManager.InsertLockDecisionEntry(e); err != nil {
+				log.Error("Failed to journal lock decision", "kind", e.Kind, "grant", e.GrantID, "error", err)
+			}
+		}
+	}()
+}
+
+// SetLockDuringFocus turns the focus hold on or off: while on, queued focus
+// keeps the agent lock engaged whatever the release windows say, and when the
+// last focus period ends the lock goes back to them. It applies to a session
+// already running.
+func (s *State) SetLockDuringFocus(on bool) {
+	s.mu.Lock()
+	s.lockDuringFocus = on
+	changed := s.syncFocusHoldLocked()
+	s.mu.Unlock()
+	if changed {
+		s.announceFocusHold()
+	}
+}
+
+// FocusHoldsAgentLock reports whether a focus session is keeping the agent lock engaged.
+func (s *State) FocusHoldsAgentLock() bool {
 	s.mu.Lock()
 	defer s.mu.Unlock()
-	until := *t
-	s.agentReleaseUntil = &until
-	s.scheduleAgentLockTimerLocked()
-	log.Info("Restored agent lock release", "until", until)
+	return s.focusHoldsLock
+}
+
+// BreakFocusHold lets the release windows through for the rest of the current
+// focus session, for an override. Returns false if focus wasn't holding the lock.
+func (s *State) BreakFocusHold() bool {
+	s.mu.Lock()
+	if !s.focusHoldsLock {
+		s.mu.Unlock()
+		return false
+	}
+	s.focusHoldBroken = true
+	s.syncFocusHoldLocked()
+	s.mu.Unlock()
+
+	log.Info("Focus hold on the agent lock broken by override")
+	s.announceFocusHold()
+	return true
+}
+
+// syncFocusHold brings the focus hold in line with the focus queue, announcing
+// any change.
+func (s *State) syncFocusHold() {
+	s.mu.Lock()
+	changed := s.syncFocusHoldLocked()
+	s.mu.Unlock()
+	if changed {
+		s.announceFocusHold()
+	}
+}
+
+// syncFocusHoldLocked takes the focus hold while focus is queued (paused
+// included) and the option is on, unless an override broke it, and drops it
+// otherwise. Reports whether the hold changed. Must be called with s.mu held.
+func (s *State) syncFocusHoldLocked() bool {
+	focusing := s.getTimeLeftLocked() > 0
+	if !focusing {
+		s.focusHoldBroken = false
+	}
+	hold := s.lockDuringFocus && focusing && !s.focusHoldBroken
+	if hold == s.focusHoldsLock {
+		return false
+	}
+	s.focusHoldsLock = hold
+	return true
+}
+
+// announceFocusHold publishes what the hold changed for the agent lock and
+// notifies clients. Taking it engages whatever was released; dropping it
+// releases again whatever the release windows still cover.
+func (s *State) announceFocusHold() {
+	s.mu.Lock()
+	held := s.focusHoldsLock
+	var until *time.Time
+	if !s.isAgentLockedLocked() {
+		t := *s.agentReleaseUntil
+		until = &t
+	}
+	now := time.Now()
+	var targets []string
+	var targetsUntil time.Time
+	for target, t := range s.agentScopedReleases {
+		if now.Before(t) {
+			targets = append(targets, target)
+			if t.After(targetsUntil) {
+				targetsUntil = t
+			}
+		}
+	}
+	sort.Strings(targets)
+	s.mu.Unlock()
+
+	released := until != nil || len(targets) > 0
+	if held {
+		log.Info("Agent lock held for focus", "was_released", released)
+		if released {
+			s.Events().Publish(LockEngaged{At: now, Reason: "focus"})
+		}
+	} else {
+		log.Info("Agent lock back to its release window", "until", until, "targets", targets)
+		if until != nil {
+			s.Events().Publish(LockReleased{At: now, Until: *until, Reason: "focus_ended"})
+		}
+		if len(targets) > 0 {
+			s.Events().Publish(LockReleased{At: now, Until: targetsUntil, Targets: targets, Reason: "focus_ended"})
+		}
+	}
+	go s.NotifyAllClients(s.GetCurrentFocusInfo())
+}
+
+// SetAgentGrantCooldown sets the minimum gap between the end of a release
+// opened by a grant, by expiry or engage, and the next grant. Zero turns the cooldown off.
+func (s *State) SetAgentGrantCooldown(d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.agentGrantCooldown = d
 }
 
-// isAgentLockedLocked must be called with s.mu held.
+// AgentGrantCooldownLeft returns how long until a grant is accepted again, or 0.
+func (s *State) AgentGrantCooldownLeft() time.Duration {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.agentCooldownLeftLocked()
+}
+
+// agentCooldownLeftLocked returns the rest of the grant cooldown, or 0. While
+// the full release is still running, extending it is never held back.
+// Must be called with s.mu held.
+func (s *State) agentCooldownLeftLocked() time.Duration {
+	if s.agentGrantCooldown <= 0 || s.agentReleaseEndedAt.IsZero() || !s.isAgentLockedLocked() {
+		return 0
+	}
+	return max(time.Until(s.agentReleaseEndedAt.Add(s.agentGrantCooldown)), 0)
+}
+
+// agentCooldownSecondsLocked is agentCooldownLeftLocked in whole seconds,
+// rounded up so a cooldown still running never reads as 0. Must be called with s.mu held.
+func (s *State) agentCooldownSecondsLocked() int64 {
+	left := s.agentCooldownLeftLocked()
+	return int64((left + time.Second - 1) / time.Second)
+}
+
+// isAgentLockedLocked reports whether the full lock is engaged; targets may
+// still be released. Must be called with s.mu held.
 func (s *State) isAgentLockedLocked() bool {
 	if s.agentReleaseUntil == nil {
 		return true
@@ -141,7 +690,7 @@ func (s *State) isAgentLockedLocked() bool {
 // agentReleaseTimeLeftLocked returns seconds until release expiry, or nil if locked.
 // Must be called with s.mu held.
 func (s *State) agentReleaseTimeLeftLocked() *int64 {
-	if s.isAgentLockedLocked() {
+	if s.focusHoldsLock || s.isAgentLockedLocked() {
 		return nil
 	}
 	secs := int64(time.Until(*s.agentReleaseUntil) / time.Second)
@@ -151,20 +700,49 @@ func (s *State) agentReleaseTimeLeftLocked() *int64 {
 	return &secs
 }
 
-// scheduleAgentLockTimerLocked replaces the snap-back timer to fire at agentReleaseUntil.
-// If the value is in the past, no timer is scheduled. Must be called with s.mu held.
+// scopedReleasesLocked lists the active target releases, by target. Must be
+// called with s.mu held.
+func (s *State) scopedReleasesLocked() []ScopedRelease {
+	now := time.Now()
+	out := []ScopedRelease{}
+	if s.focusHoldsLock {
+		return out
+	}
+	for target, until := range s.agentScopedReleases {
+		if now.Before(until) {
+			out = append(out, ScopedRelease{Target: target, TimeLeftSeconds: int64(until.Sub(now) / time.Second)})
+		}
+	}
+	sort.Slice(out, func(a, b int) bool { return out[a].Target < out[b].Target })
+	return out
+}
+
+// scheduleAgentLockTimerLocked replaces the snap-back timer to fire at the
+// earliest release end, full or scoped. If nothing is released, no timer is
+// scheduled. Must be called with s.mu held.
 func (s *State) scheduleAgentLockTimerLocked() {
 	if s.agentLockTimer != nil {
 		s.agentLockTimer.Stop()
 		s.agentLockTimer = nil
 	}
-	if s.agentReleaseUntil == nil {
-		return
+	var next *time.Time
+	if s.agentReleaseUntil != nil {
+		t := *s.agentReleaseUntil
+		next = &t
 	}
-	d := time.Until(*s.agentReleaseUntil)
-	if d <= 0 {
+	for _, until := range s.agentScopedReleases {
+		if next == nil || until.Before(*next) {
+			t := until
+			next = &t
+		}
+	}
+	if next == nil {
 		return
 	}
+	d := time.Until(*next)
+	if d < 0 {
+		d = 0
+	}
 	s.agentLockTimer = time.AfterFunc(d, s.onAgentLockTimerFire)
 }
 
@@ -172,23 +750,53 @@ func (s *State) scheduleAgentLockTimerLocked() {
 // race with a longer ReleaseAgentLock call doesn't wipe a freshly extended release.
 func (s *State) onAgentLockTimerFire() {
 	s.mu.Lock()
-	expired := s.agentReleaseUntil != nil && !time.Now().Before(*s.agentReleaseUntil)
+	now := time.Now()
+	ends := map[string]time.Time{}
+	expired := s.agentReleaseUntil != nil && !now.Before(*s.agentReleaseUntil)
 	if expired {
+		ends[db.FullReleaseKey] = *s.agentReleaseUntil
 		s.agentReleaseUntil = nil
-		s.agentLockTimer = nil
-		s.persistAgentReleaseUntilLocked()
+	}
+	var expiredTargets []string
+	for target, until := range s.agentScopedReleases {
+		if !now.Before(until) {
+			ends[target] = until
+			delete(s.agentScopedReleases, target)
+			expiredTargets = append(expiredTargets, target)
+		}
+	}
+	sort.Strings(expiredTargets)
+	if s.grantEndsLocked(ends) {
+		s.agentReleaseEndedAt = now
+	}
+	s.journalLocked(s.releaseEndsLocked(ends, "expired", "timer", now))
+	s.agentLockTimer = nil
+	s.scheduleAgentLockTimerLocked()
+	if expired || len(expiredTargets) > 0 {
+		s.persistAgentReleaseLocked()
+	}
+	if s.focusHoldsLock {
+		// Nothing the clients see changes while focus holds the lock
+		expired, expiredTargets = false, nil
 	}
 	s.mu.Unlock()
 
 	if expired {
 		log.Info("Agent lock release expired")
+		s.Events().Publish(LockExpired{At: now})
+	}
+	if len(expiredTargets) > 0 {
+		log.Info("Agent lock release expired for targets", "targets", expiredTargets)
+		s.Events().Publish(LockExpired{At: now, Targets: expiredTargets})
+	}
+	if expired || len(expiredTargets) > 0 {
 		go s.NotifyAllClients(s.GetCurrentFocusInfo())
 	}
 }
 
-// persistAgentReleaseUntilLocked writes the current value to the DB. Best-effort, async.
-// Must be called with s.mu held (only reads s.agentReleaseUntil).
-func (s *State) persistAgentReleaseUntilLocked() {
+// persistAgentReleaseLocked writes the current releases to the DB. Best-effort, async.
+// Must be called with s.mu held (only reads the release fields).
+func (s *State) persistAgentReleaseLocked() {
 	if s.dbManager == nil {
 		return
 	}
@@ -197,48 +805,233 @@ func (s *State) persistAgentReleaseUntilLocked() {
 		copy := *s.agentReleaseUntil
 		t = &copy
 	}
+	scope := make(map[string]time.Time, len(s.agentScopedReleases))
+	for target, until := range s.agentScopedReleases {
+		scope[target] = until
+	}
+	grants := make(map[string]db.ReleaseGrant, len(s.agentReleaseGrants))
+	for key, g := range s.agentReleaseGrants {
+		grants[key] = g
+	}
+	endedAt := s.agentReleaseEndedAt
 	go func() {
-		if err := s.dbManager.SetAgentReleaseUntil(t); err != nil {
+		if err := s.dbManager.SetAgentRelease(t, scope, grants, endedAt); err != nil {
 			log.Error("Failed to persist agent lock state", "error", err)
 		}
 	}()
 }
 
-// AddHook registers a new hook function to be called when focus state changes
-func (s *State) AddHook(hook Hook) {
-	s.mu.Lock()
-	defer s.mu.Unlock()
-	s.hooks = append(s.hooks, hook)
+// Events returns the bus that focus, lock and attention changes are published on.
+func (s *State) Events() *EventBus {
+	s.eventsOnce.Do(func() { s.events = NewEventBus() })
+	return s.events
 }
 
-// RestoreFocus restores an active focus session from DB on startup.
-// Unlike SetFocusing, it does not trigger hooks or bump stats (those were already recorded).
-func (s *State) RestoreFocus(remaining time.Duration) {
+// RestoreFocus restores an active focus session from DB on startup: records are
+// its open coach records, oldest first, and remaining is what is left of all of
+// them together. Each record becomes a request again, shifted by whatever
+// pauses pushed the session's end out. Records that already ran out are closed
+// as expired; otherwise, unlike SetFocusing, it does not publish events or bump
+// stats (those were already recorded). A non-nil pausedAt brings the session
+// back paused since that moment, with remaining frozen.
+func (s *State) RestoreFocus(records []db.FocusRecord, remaining time.Duration, pausedAt *time.Time) {
+	if len(records) == 0 {
+		return
+	}
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
 	now := time.Now()
-	s.focusRequests = append(s.focusRequests, FocusRequest{
-		StartTime: now,
-		EndTime:   now.Add(remaining),
-	})
+	clock := now
+	if pausedAt != nil {
+		at := *pausedAt
+		s.pausedAt = &at
+		clock = at
+	}
+	last := records[len(records)-1]
+	shift := clock.Add(remaining).Sub(last.Timestamp.Add(time.Duration(last.Duration) * time.Second))
+	var over []FocusRequest
+	for _, record := range records {
+		planned := time.Duration(record.Duration) * time.Second
+		start := record.Timestamp.Add(shift)
+		request := FocusRequest{
+			StartTime:  start,
+			EndTime:    start.Add(planned),
+			FocusLabel: FocusLabel{Label: record.Label, Project: record.Project, Task: record.Task},
+			Committed:  record.Committed,
+			recordID:   record.ID,
+			planned:    planned,
+		}
+		if request.EndTime.After(clock) {
+			s.focusRequests = append(s.focusRequests, request)
+		} else {
+			over = append(over, request)
+		}
+	}
+	s.closeFocusRequestsLocked(over, "expired", "")
+	if len(s.focusRequests) == 0 {
+		s.pausedAt = nil
+		return
+	}
 	s.LastChange = now
 	s.scheduleExpiryTimer()
+	s.syncFocusHoldLocked()
 
-	log.Info("Restored focus session from database", "remaining", remaining)
+	log.Info("Restored focus session from database", "records", len(s.focusRequests), "remaining", remaining, "paused", pausedAt != nil)
+}
+
+// restoreFocusRecords is how many of the latest coach records startup looks
+// through for the session still open.
+const restoreFocusRecords = 50
+
+// openFocusSession picks the records of the session still open out of the
+// latest coach records (newest first): the newest ones not yet closed out,
+// as long as each was queued right behind the one before. It returns them
+// oldest first.
+func openFocusSession(latest []db.FocusRecord) []db.FocusRecord {
+	var session []db.FocusRecord
+	for _, record := range latest {
+		if record.EndReason != "" {
+			break
+		}
+		if len(session) > 0 {
+			next := session[len(session)-1]
+			// Timestamps are stored to the second
+			if end := record.Timestamp.Add(time.Duration(record.Duration) * time.Second); end.Before(next.Timestamp.Add(-time.Second)) {
+				break
+			}
+		}
+		session = append(session, record)
+	}
+	slices.Reverse(session)
+	return session
+}
+
+// PauseFocus freezes the remaining time of every queued focus request and stops the
+// expiry timer. Returns false if there is nothing to pause or the session is already paused.
+func (s *State) PauseFocus() bool {
+	s.mu.Lock()
+	if s.pausedAt != nil || s.getTimeLeftLocked() <= 0 {
+		s.mu.Unlock()
+		return false
+	}
+	now := time.Now()
+	s.pausedAt = &now
+	if s.expiryTimer != nil {
+		s.expiryTimer.Stop()
+		s.expiryTimer = nil
+	}
+	remaining := s.getTimeLeftLocked()
+	s.persistFocusPauseLocked()
+	s.mu.Unlock()
+
+	log.Info("Focus paused", "remaining", remaining)
+	go s.NotifyAllClients(s.GetCurrentFocusInfo())
+	return true
+}
+
+// ResumeFocus shifts every queued focus request forward by the time spent paused and
+// restarts the expiry timer. Returns false if the session is not paused.
+func (s *State) ResumeFocus() bool {
+	s.mu.Lock()
+	if s.pausedAt == nil {
+		s.mu.Unlock()
+		return false
+	}
+	pausedFor := time.Since(*s.pausedAt)
+	for i := range s.focusRequests {
+		s.focusRequests[i].StartTime = s.focusRequests[i].StartTime.Add(pausedFor)
+		s.focusRequests[i].EndTime = s.focusRequests[i].EndTime.Add(pausedFor)
+	}
+	s.pausedAt = nil
+	s.scheduleExpiryTimer()
+	s.persistFocusPauseLocked()
+	s.mu.Unlock()
+
+	log.Info("Focus resumed", "paused_for", pausedFor)
+	go s.NotifyAllClients(s.GetCurrentFocusInfo())
+	return true
+}
+
+// IsCommitted reports whether ending the current session needs an override.
+func (s *State) IsCommitted() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.isCommittedLocked()
+}
+
+// isCommittedLocked is true while any committed request still has time left.
+// Must be called with s.mu held.
+func (s *State) isCommittedLocked() bool {
+	clock := s.focusClockLocked()
+	for _, r := range s.focusRequests {
+		if r.Committed && r.EndTime.After(clock) {
+			return true
+		}
+	}
+	return false
+}
+
+// focusClockLocked is "now" for focus bookkeeping. While paused it stays at the
+// moment of the pause, which is what keeps the remaining time frozen.
+// Must be called with s.mu held.
+func (s *State) focusClockLocked() time.Time {
+	if s.pausedAt != nil {
+		return *s.pausedAt
+	}
+	return time.Now()
+}
+
+// persistFocusPauseLocked writes the pause state to the DB. Best-effort, async.
+// Must be called with s.mu held.
+func (s *State) persistFocusPauseLocked() {
+	if s.dbManager == nil {
+		return
+	}
+	var pausedAt, endsAt *time.Time
+	remaining := s.getTimeLeftLocked()
+	if s.pausedAt != nil {
+		at := *s.pausedAt
+		pausedAt = &at
+	} else if remaining > 0 {
+		end := time.Now().Add(remaining)
+		endsAt = &end
+	}
+	go func() {
+		if err := s.dbManager.SetFocusPause(pausedAt, remaining, endsAt); err != nil {
+			log.Error("Failed to persist focus pause state", "error", err)
+		}
+	}()
 }
 
 func (s *State) SetFocusing(duration time.Duration) {
+	s.SetLabeledFocusing(duration, FocusLabel{})
+}
+
+// SetLabeledFocusing queues a focus period like SetFocusing, tagged with what it is for.
+func (s *State) SetLabeledFocusing(duration time.Duration, label FocusLabel) {
+	s.queueFocus(duration, label, false)
+}
+
+// SetCommittedFocusing queues a labeled focus period that can't be cancelled
+// without an override.
+func (s *State) SetCommittedFocusing(duration time.Duration, label FocusLabel) {
+	s.queueFocus(duration, label, true)
+}
+
+func (s *State) queueFocus(duration time.Duration, label FocusLabel, committed bool) {
 	s.mu.Lock()
+	s.yieldCycleBreakLocked()
 
 	// Only update LastChange if we're starting a new focus session
 	// (not already focusing)
-	if s.getTimeLeftLocked() <= 0 {
+	starting := s.getTimeLeftLocked() <= 0
+	if starting {
 		s.LastChange = time.Now()
 	}
 
 	// Find the latest EndTime from existing focus requests
-	now := time.Now()
+	now := s.focusClockLocked()
 	latestEndTime := now
 	for _, req := range s.focusRequests {
 		if req.EndTime.After(latestEndTime) {
@@ -247,10 +1040,15 @@ func (s *State) SetFocusing(duration time.Duration) {
 	}
 
 	// Add new focus period starting from the latest end time
-	s.focusRequests = append(s.focusRequests, FocusRequest{
-		StartTime: latestEndTime,
-		EndTime:   latestEndTime.Add(duration),
-	})
+	request := FocusRequest{
+		StartTime:  latestEndTime,
+		EndTime:    latestEndTime.Add(duration),
+		FocusLabel: label,
+		Committed:  committed,
+		recordID:   db.NewRecordID(),
+		planned:    duration,
+	}
+	s.focusRequests = append(s.focusRequests, request)
 
 	if s.stats != nil {
 		s.stats.BumpTodaysFocusCount()
@@ -259,15 +1057,45 @@ func (s *State) SetFocusing(duration time.Duration) {
 	// Schedule expiry timer while still holding the lock
 	s.scheduleExpiryTimer()
 
-	// Get a copy of hooks to execute outside the lock
-	hooks := make([]Hook, len(s.hooks))
-	copy(hooks, s.hooks)
+	// A paused session grew; keep the persisted remaining time in step
+	if s.pausedAt != nil {
+		s.persistFocusPauseLocked()
+	}
+
+	holdChanged := s.syncFocusHoldLocked()
 	s.mu.Unlock()
 
-	// Execute hooks outside the lock to prevent deadlocks
-	for _, hook := range hooks {
-		hook(s)
+	if starting {
+		s.Events().Publish(FocusStarted{
+			At: time.Now(), Start: request.StartTime, End: request.EndTime,
+			Committed: committed, RecordID: request.recordID, FocusLabel: label,
+		})
+	} else {
+		s.Events().Publish(FocusExtended{
+			At: time.Now(), Start: request.StartTime, End: request.EndTime,
+			Committed: committed, RecordID: request.recordID, FocusLabel: label,
+		})
+	}
+	if holdChanged {
+		s.announceFocusHold()
+	}
+}
+
+// SetFocusingUntil queues enough focus for the session to run until end, counting
+// whatever is already queued, then publishes and notifies clients like any other
+// start. Returns false if the queue already reaches end.
+func (s *State) SetFocusingUntil(end time.Time, label FocusLabel) bool {
+	s.mu.Lock()
+	queuedUntil := s.focusClockLocked().Add(s.getTimeLeftLocked())
+	s.mu.Unlock()
+
+	d := end.Sub(queuedUntil)
+	if d <= 0 {
+		return false
 	}
+	s.SetLabeledFocusing(d, label)
+	go s.NotifyAllClients(s.GetCurrentFocusInfo())
+	return true
 }
 
 // scheduleExpiryTimer schedules a single timer for when focus ends. Must be called with mutex held.
@@ -278,6 +1106,11 @@ func (s *State) scheduleExpiryTimer() {
 		s.expiryTimer = nil
 	}
 
+	// A paused session never expires
+	if s.pausedAt != nil {
+		return
+	}
+
 	// Find the latest end time
 	timeLeft := s.getTimeLeftLocked()
 	if timeLeft <= 0 {
@@ -286,22 +1119,35 @@ func (s *State) scheduleExpiryTimer() {
 
 	s.expiryTimer = time.AfterFunc(timeLeft, func() {
 		s.mu.Lock()
+		// Paused between scheduling and firing; ResumeFocus reschedules
+		if s.pausedAt != nil {
+			s.mu.Unlock()
+			return
+		}
 		// Remove all expired focus requests
 		now := time.Now()
+		var expired []FocusRequest
 		for i := 0; i < len(s.focusRequests); i++ {
 			if s.focusRequests[i].EndTime.Before(now) {
+				expired = append(expired, s.focusRequests[i])
 				s.focusRequests = slices.Delete(s.focusRequests, i, i+1)
 				i--
 			}
 		}
+		s.closeFocusRequestsLocked(expired, "expired", "")
 
 		// Check if all focus periods have ended
 		if len(s.focusRequests) == 0 {
 			s.LastChange = now
 			s.expiryTimer = nil
+			s.advanceCycleLocked()
+			holdChanged := s.syncFocusHoldLocked()
 			s.mu.Unlock()
 
 			log.Info("All focus periods expired")
+			if holdChanged {
+				s.announceFocusHold()
+			}
 			message := s.GetCurrentFocusInfo()
 			go s.NotifyAllClients(message)
 		} else {
@@ -312,23 +1158,60 @@ func (s *State) scheduleExpiryTimer() {
 	})
 }
 
-// clearFocus cancels all focus requests and the expiry timer
-func (s *State) clearFocus() {
+// clearFocus cancels all focus requests and the expiry timer. The dropped requests are
+// closed out with reason ("stopped" or "replaced") and by, who asked for it.
+func (s *State) clearFocus(reason, by string) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 	if s.expiryTimer != nil {
 		s.expiryTimer.Stop()
 		s.expiryTimer = nil
 	}
+	s.closeFocusRequestsLocked(s.focusRequests, reason, by)
 	s.focusRequests = nil
+	s.endCycleLocked()
+	s.pausedAt = nil
 	s.LastChange = time.Now()
+	// Forget any pause or resumed end so a restart doesn't bring the session back
+	s.persistFocusPauseLocked()
+}
+
+// ReplaceFocusing drops the queued focus periods, closing them out as replaced, and
+// starts a fresh one in their place, committed if asked.
+func (s *State) ReplaceFocusing(duration time.Duration, label FocusLabel, committed bool, by string) {
+	s.clearFocus("replaced", by)
+	s.queueFocus(duration, label, committed)
 }
 
-func (s *State) HandleFocusChange(focusing bool, durationSeconds int) {
+// closeFocusRequestsLocked publishes the end of requests with how long each one
+// actually ran; DatabaseHook closes out their records from it, after the inserts
+// it queued earlier. Must be called with s.mu held, before the requests are dropped.
+func (s *State) closeFocusRequestsLocked(requests []FocusRequest, reason, by string) {
+	if len(requests) == 0 {
+		return
+	}
+	clock := s.focusClockLocked()
+	var focused time.Duration
+	records := make([]FocusRecordEnd, 0, len(requests))
+	for _, r := range requests {
+		focused += r.focusedAt(clock)
+		records = append(records, FocusRecordEnd{RecordID: r.recordID, Focused: r.focusedAt(clock)})
+	}
+	s.Events().Publish(FocusEnded{
+		At: time.Now(), Reason: reason, By: by,
+		Periods: len(requests), FocusedSeconds: int(focused / time.Second),
+		Records: records,
+	})
+}
+
+// HandleFocusChange starts or stops focus. by names who asked; it is recorded when a
+// session is stopped.
+func (s *State) HandleFocusChange(focusing bool, durationSeconds int, label FocusLabel, by string) {
 	if focusing {
-		s.SetFocusing(time.Duration(durationSeconds) * time.Second)
+		s.SetLabeledFocusing(time.Duration(durationSeconds)*time.Second, label)
 	} else {
-		s.clearFocus()
+		s.clearFocus("stopped", by)
+		s.syncFocusHold()
 	}
 
 	message := s.GetCurrentFocusInfo()
@@ -337,7 +1220,7 @@ func (s *State) HandleFocusChange(focusing bool, durationSeconds int) {
 
 // getTimeLeftLocked calculates remaining focus time. Must be called with mutex held.
 func (s *State) getTimeLeftLocked() time.Duration {
-	now := time.Now()
+	now := s.focusClockLocked()
 	latestEndTime := now
 	for _, req := range s.focusRequests {
 		if req.EndTime.After(latestEndTime) {
@@ -347,6 +1230,13 @@ func (s *State) getTimeLeftLocked() time.Duration {
 	return latestEndTime.Sub(now)
 }
 
+// HasClients reports whether any WebSocket client is connected.
+func (s *State) HasClients() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.clients) > 0
+}
+
 func (s *State) AddClient(client *websocket.Conn) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
@@ -3,6 +3,8 @@ package coach
 import (
 	"testing"
 	"time"
+
+	"coach/internal/db"
 )
 
 // Test-only probes into State. Production code reads state through
@@ -175,3 +177,237 @@ func TestIsFocusingDerived(t *testing.T) {
 		t.Errorf("Expected 0 time left after expiration, got %v", remaining(state))
 	}
 }
+
+// TestPauseFreezesRemaining tests that a paused session neither counts down nor expires
+func TestPauseFreezesRemaining(t *testing.T) {
+	state := &State{}
+
+	state.SetFocusing(300 * time.Millisecond)
+	if !state.PauseFocus() {
+		t.Fatal("Expected PauseFocus to pause an active session")
+	}
+	frozen := remaining(state)
+
+	time.Sleep(400 * time.Millisecond)
+
+	if got := remaining(state); got != frozen {
+		t.Errorf("Remaining time should stay frozen while paused: was %v, now %v", frozen, got)
+	}
+	info := state.GetCurrentFocusInfo()
+	if !info.Paused || info.Focusing {
+		t.Errorf("Paused session should report paused and not focusing, got %+v", info)
+	}
+}
+
+// TestResumeShiftsQueue tests that resume continues every queued request from where it paused
+func TestResumeShiftsQueue(t *testing.T) {
+	state := &State{}
+
+	state.SetFocusing(30 * time.Second)
+	state.SetFocusing(30 * time.Second)
+	state.PauseFocus()
+	time.Sleep(200 * time.Millisecond)
+
+	if !state.ResumeFocus() {
+		t.Fatal("Expected ResumeFocus to resume a paused session")
+	}
+
+	timeLeft := remaining(state)
+	if timeLeft < 59*time.Second || timeLeft > 61*time.Second {
+		t.Errorf("After resume, expected ~60s, got %v", timeLeft)
+	}
+	if !isFocusing(state) {
+		t.Error("Expected focusing after resume")
+	}
+
+	state.mu.Lock()
+	hasTimer := state.expiryTimer != nil
+	state.mu.Unlock()
+	if !hasTimer {
+		t.Error("Resume should reschedule the expiry timer")
+	}
+}
+
+// TestPauseWithoutFocusIsNoOp tests that there is nothing to pause or resume when idle
+func TestPauseWithoutFocusIsNoOp(t *testing.T) {
+	state := &State{}
+
+	if state.PauseFocus() {
+		t.Error("PauseFocus should be a no-op without an active session")
+	}
+	if state.ResumeFocus() {
+		t.Error("ResumeFocus should be a no-op when not paused")
+	}
+}
+
+// TestClearFocusDropsPause tests that stopping a paused session leaves nothing behind
+func TestClearFocusDropsPause(t *testing.T) {
+	state := &State{}
+
+	state.SetFocusing(30 * time.Second)
+	state.PauseFocus()
+	state.clearFocus("stopped", "test")
+
+	if info := state.GetCurrentFocusInfo(); info.Paused || info.FocusTimeLeft != 0 {
+		t.Errorf("Cleared session should be neither paused nor have time left, got %+v", info)
+	}
+}
+
+// TestRestorePausedFocus tests that a restored paused session stays frozen
+func TestRestorePausedFocus(t *testing.T) {
+	state := &State{}
+
+	pausedAt := time.Now().Add(-time.Minute)
+	record := db.FocusRecord{Timestamp: pausedAt.Add(-5 * time.Minute), Duration: 900}
+	state.RestoreFocus([]db.FocusRecord{record}, 10*time.Minute, &pausedAt)
+
+	info := state.GetCurrentFocusInfo()
+	if !info.Paused {
+		t.Fatal("Restored session should be paused")
+	}
+	if info.FocusTimeLeft != 600 {
+		t.Errorf("Expected 600s left, got %d", info.FocusTimeLeft)
+	}
+	if info.PausedFor < 59 || info.PausedFor > 61 {
+		t.Errorf("Expected ~60s paused, got %d", info.PausedFor)
+	}
+}
+
+// TestRestoreFocusBringsBackEveryOpenRecord tests that a queued session comes
+// back as one request per record, each closing out its own record
+func TestRestoreFocusBringsBackEveryOpenRecord(t *testing.T) {
+	now := time.Now()
+	latest := []db.FocusRecord{
+		{ID: "b", Timestamp: now.Add(5 * time.Minute), Duration: 600},
+		{ID: "a", Timestamp: now.Add(-10 * time.Minute), Duration: 900},
+		{ID: "old", Timestamp: now.Add(-3 * time.Hour), Duration: 900},
+		{ID: "closed", Timestamp: now.Add(-4 * time.Hour), Duration: 900, EndReason: "expired"},
+	}
+	session := openFocusSession(latest)
+	if len(session) != 2 || session[0].ID != "a" || session[1].ID != "b" {
+		t.Fatalf("Expected records a and b, oldest first, got %+v", session)
+	}
+
+	state := &State{}
+	ended := make(chan FocusEnded, 1)
+	On(state.Events(), func(e FocusEnded) { ended <- e })
+	state.RestoreFocus(session, time.Until(now.Add(15*time.Minute)), nil)
+	if left := state.GetCurrentFocusInfo().FocusTimeLeft; left < 899 || left > 900 {
+		t.Errorf("Expected ~900s left, got %d", left)
+	}
+
+	state.clearFocus("stopped", "test")
+	e := waitEvent(t, ended)
+	if len(e.Records) != 2 || e.Records[0].RecordID != "a" || e.Records[1].RecordID != "b" {
+		t.Fatalf("Expected both records closed, got %+v", e.Records)
+	}
+	if focused := e.Records[0].Focused; focused < 10*time.Minute || focused > 10*time.Minute+time.Second {
+		t.Errorf("Expected a to have run 10m, got %v", focused)
+	}
+	if e.Records[1].Focused != 0 {
+		t.Errorf("Expected b not to have started, got %v", e.Records[1].Focused)
+	}
+}
+
+// TestRestoreFocusClosesRecordsThatRanOut tests that a session that ended
+// while the server was down is closed out rather than left open
+func TestRestoreFocusClosesRecordsThatRanOut(t *testing.T) {
+	state := &State{}
+	ended := make(chan FocusEnded, 1)
+	On(state.Events(), func(e FocusEnded) { ended <- e })
+
+	record := db.FocusRecord{ID: "a", Timestamp: time.Now().Add(-time.Hour), Duration: 900}
+	state.RestoreFocus([]db.FocusRecord{record}, -45*time.Minute, nil)
+	if e := waitEvent(t, ended); e.Reason != "expired" || len(e.Records) != 1 || e.Records[0].Focused != 15*time.Minute {
+		t.Errorf("Expected the record closed as expired after its full 15m, got %+v", e)
+	}
+	if info := state.GetCurrentFocusInfo(); info.Focusing {
+		t.Error("Nothing should be running")
+	}
+}
+
+// TestLabeledFocusKeepsLabel tests that each queued request carries its own label
+func TestLabeledFocusKeepsLabel(t *testing.T) {
+	state := &State{}
+
+	state.SetLabeledFocusing(30*time.Second, FocusLabel{Label: "spec", Project: "coach", Task: "42"})
+	state.SetFocusing(30 * time.Second)
+
+	state.mu.Lock()
+	first, second := state.focusRequests[0], state.focusRequests[1]
+	state.mu.Unlock()
+
+	if first.Label != "spec" || first.Project != "coach" || first.Task != "42" {
+		t.Errorf("First request lost its label, got %+v", first.FocusLabel)
+	}
+	if second.FocusLabel != (FocusLabel{}) {
+		t.Errorf("Unlabeled request should have an empty label, got %+v", second.FocusLabel)
+	}
+}
+
+// TestFocusedAt tests how much of a request counts as focused at different moments
+func TestFocusedAt(t *testing.T) {
+	start := time.Now()
+	req := FocusRequest{StartTime: start, EndTime: start.Add(30 * time.Minute), planned: 30 * time.Minute}
+
+	cases := []struct {
+		name  string
+		clock time.Time
+		want  time.Duration
+	}{
+		{"before start", start.Add(-time.Minute), 0},
+		{"midway", start.Add(5 * time.Minute), 5 * time.Minute},
+		{"after end", start.Add(time.Hour), 30 * time.Minute},
+	}
+	for _, c := range cases {
+		if got := req.focusedAt(c.clock); got != c.want {
+			t.Errorf("%s: focusedAt = %v, want %v", c.name, got, c.want)
+		}
+	}
+
+	// A restored request only knows what was left; the part before the restart counts too.
+	restored := FocusRequest{StartTime: start, EndTime: start.Add(10 * time.Minute), planned: 30 * time.Minute}
+	if got := restored.focusedAt(start.Add(4 * time.Minute)); got != 24*time.Minute {
+		t.Errorf("restored: focusedAt = %v, want 24m", got)
+	}
+}
+
+// TestReplaceFocusingDropsQueue tests that replacing starts over instead of appending
+func TestReplaceFocusingDropsQueue(t *testing.T) {
+	state := &State{}
+
+	state.SetFocusing(30 * time.Second)
+	state.SetFocusing(30 * time.Second)
+	state.ReplaceFocusing(10*time.Second, FocusLabel{Label: "new"}, false, "test")
+
+	state.mu.Lock()
+	requests := append([]FocusRequest(nil), state.focusRequests...)
+	state.mu.Unlock()
+
+	if len(requests) != 1 || requests[0].Label != "new" {
+		t.Fatalf("Expected only the replacement request, got %+v", requests)
+	}
+	if timeLeft := remaining(state); timeLeft < 9*time.Second || timeLeft > 11*time.Second {
+		t.Errorf("After replace, expected ~10s, got %v", timeLeft)
+	}
+}
+
+// TestCommittedFocusEndsWithItsRequest tests that the commitment lasts only as long as the committed time
+func TestCommittedFocusEndsWithItsRequest(t *testing.T) {
+	state := &State{}
+
+	state.SetFocusing(30 * time.Second)
+	if state.IsCommitted() {
+		t.Fatal("Plain focus should not be committed")
+	}
+
+	state.SetCommittedFocusing(30*time.Second, FocusLabel{Label: "deep work"})
+	if !state.IsCommitted() || !state.GetCurrentFocusInfo().Committed {
+		t.Fatal("Expected committed focus after SetCommittedFocusing")
+	}
+
+	state.HandleFocusChange(false, 0, FocusLabel{}, "test")
+	if state.IsCommitted() {
+		t.Error("Clearing focus should drop the commitment")
+	}
+}
@@ -0,0 +1,60 @@
+package stats
+
+import (
+	"sort"
+
+	"coach/internal/db"
+)
+
+// FocusGroup is the focus time that went into one label, project or task.
+type FocusGroup struct {
+	Key             string `json:"key"`
+	Sessions        int    `json:"sessions"`
+	DurationSeconds int    `json:"duration_seconds"`
+}
+
+// focusGroupKeys maps a group_by name to the record field it groups on.
+var focusGroupKeys = map[string]func(db.FocusRecord) string{
+	"label":   func(r db.FocusRecord) string { return r.Label },
+	"project": func(r db.FocusRecord) string { return r.Project },
+	"task":    func(r db.FocusRecord) string { return r.Task },
+}
+
+// IsFocusGroupKey reports whether by names a field GroupFocusRecords can group on.
+func IsFocusGroupKey(by string) bool {
+	_, ok := focusGroupKeys[by]
+	return ok
+}
+
+// GroupFocusRecords totals records by label, project or task, most time first.
+// Sessions without a value group under the empty key, so the totals still add
+// up to the whole history. Returns nil for an unknown field.
+func GroupFocusRecords(records []db.FocusRecord, by string) []FocusGroup {
+	key, ok := focusGroupKeys[by]
+	if !ok {
+		return nil
+	}
+
+	index := map[string]int{}
+	groups := []FocusGroup{}
+	for _, r := range records {
+		k := key(r)
+		i, seen := index[k]
+		if !seen {
+			i = len(groups)
+			index[k] = i
+			groups = append(groups, FocusGroup{Key: k})
+		}
+		groups[i].Sessions++
+		groups[i].DurationSeconds += r.FocusedSeconds()
+	}
+
+	sort.Slice(groups, func(a, b int) bool {
+		x, y := groups[a], groups[b]
+		if x.DurationSeconds != y.DurationSeconds {
+			return x.DurationSeconds > y.DurationSeconds
+		}
+		return x.Key < y.Key
+	})
+	return groups
+}
@@ -0,0 +1,41 @@
+package stats
+
+import (
+	"testing"
+
+	"coach/internal/db"
+)
+
+func TestGroupFocusRecordsByProject(t *testing.T) {
+	records := []db.FocusRecord{
+		{Duration: 1800, Project: "coach"},
+		{Duration: 600, Project: "dimaist"},
+		{Duration: 1200, Project: "coach"},
+		{Duration: 300},
+	}
+
+	got := GroupFocusRecords(records, "project")
+
+	want := []FocusGroup{
+		{Key: "coach", Sessions: 2, DurationSeconds: 3000},
+		{Key: "dimaist", Sessions: 1, DurationSeconds: 600},
+		{Key: "", Sessions: 1, DurationSeconds: 300},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("GroupFocusRecords = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("group[%d] = %v, want %v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestGroupFocusRecordsUnknownKey(t *testing.T) {
+	if IsFocusGroupKey("timestamp") {
+		t.Error("timestamp should not be a group key")
+	}
+	if got := GroupFocusRecords([]db.FocusRecord{{Duration: 60}}, "timestamp"); got != nil {
+		t.Errorf("GroupFocusRecords with unknown key = %v, want nil", got)
+	}
+}
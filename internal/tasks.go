@@ -0,0 +1,41 @@
+package coach
+
+import (
+	"context"
+
+	"github.com/charmbracelet/log"
+
+	"coach/internal/dimaist"
+)
+
+// taskSource is where today's tasks come from: a *dimaist.Client in
+// production (kept narrow for tests).
+type taskSource interface {
+	GetTodayTasks(ctx context.Context) ([]dimaist.Task, error)
+}
+
+// TodayTasks is today's task list as the coach knows it. Configured is false
+// when no task source is set up; Error says why the source couldn't be read.
+// Either way Tasks is then empty, so callers can go on without tasks.
+type TodayTasks struct {
+	Configured bool           `json:"configured"`
+	Tasks      []dimaist.Task `json:"tasks"`
+	Error      string         `json:"error,omitempty"`
+}
+
+// fetchTodayTasks reads today's tasks from src, which may be nil. A failing
+// source is logged and reported, never fatal.
+func fetchTodayTasks(ctx context.Context, src taskSource) TodayTasks {
+	if src == nil {
+		return TodayTasks{Tasks: []dimaist.Task{}}
+	}
+	tasks, err := src.GetTodayTasks(ctx)
+	if err != nil {
+		log.Warn("Failed to fetch today's tasks", "error", err)
+		return TodayTasks{Configured: true, Tasks: []dimaist.Task{}, Error: err.Error()}
+	}
+	if tasks == nil {
+		tasks = []dimaist.Task{}
+	}
+	return TodayTasks{Configured: true, Tasks: tasks}
+}
@@ -0,0 +1,40 @@
+package coach
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http/httptest"
+	"testing"
+
+	"coach/internal/dimaist"
+)
+
+func getTodayTasks(t *testing.T, s *Server) TodayTasks {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	s.TodayTasksHandler(rec, httptest.NewRequest("GET", "/tasks/today", nil))
+	if rec.Code != 200 {
+		t.Fatalf("Expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var got TodayTasks
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+		t.Fatalf("Bad JSON %q: %v", rec.Body.String(), err)
+	}
+	return got
+}
+
+func TestTodayTasksDegradesGracefully(t *testing.T) {
+	if got := getTodayTasks(t, &Server{State: &State{}}); got.Configured || len(got.Tasks) != 0 {
+		t.Errorf("Without a task source expected nothing configured, got %+v", got)
+	}
+
+	failing := &Server{State: &State{}, Tasks: &fakeTaskSource{err: fmt.Errorf("dimaist down")}}
+	if got := getTodayTasks(t, failing); !got.Configured || got.Error == "" || got.Tasks == nil {
+		t.Errorf("A failing source should report its error with empty tasks, got %+v", got)
+	}
+
+	ok := &Server{State: &State{}, Tasks: &fakeTaskSource{tasks: []dimaist.Task{{ID: 7, Title: "Ship it"}}}}
+	if got := getTodayTasks(t, ok); len(got.Tasks) != 1 || got.Tasks[0].Title != "Ship it" {
+		t.Errorf("Expected the source's tasks, got %+v", got)
+	}
+}
@@ -0,0 +1,442 @@
+package coach
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/charmbracelet/log"
+
+	"coach/internal/db"
+)
+
+// webhookStore is the slice of db.Manager the dispatcher needs (kept narrow for tests).
+type webhookStore interface {
+	GetWebhooks() ([]db.Webhook, error)
+	InsertWebhook(w db.Webhook) (string, error)
+	UpdateWebhook(w db.Webhook) error
+	DeleteWebhook(recordID string) error
+	InsertWebhookDelivery(d db.WebhookDelivery) (string, error)
+	UpdateWebhookDelivery(d db.WebhookDelivery) error
+	GetDueWebhookDeliveries(now time.Time) ([]db.WebhookDelivery, error)
+}
+
+// webhookEvents are the events webhooks can subscribe to: the focus and
+// agent-lock state changes.
+var webhookEvents = []string{
+	FocusStarted{}.Name(), FocusExtended{}.Name(), FocusEnded{}.Name(),
+	LockReleased{}.Name(), LockEngaged{}.Name(), LockExpired{}.Name(),
+}
+
+const (
+	// webhookMaxAttempts is how many times a delivery is tried before it is marked failed.
+	webhookMaxAttempts = 6
+	// webhookRetryBase is the wait after the first failure; it doubles every attempt.
+	webhookRetryBase = 30 * time.Second
+	// webhookPollInterval is how often the queue is checked for due retries.
+	webhookPollInterval = 15 * time.Second
+	// webhookSendBacklog is how many claimed deliveries can wait for the send
+	// worker; past that, new ones are left pending for the retry poll.
+	webhookSendBacklog = 256
+)
+
+// Webhook is an outbound subscription: matching events are POSTed to URL as
+// JSON, signed with Secret.
+type Webhook struct {
+	ID        string   `json:"id"`
+	URL       string   `json:"url"`
+	Secret    string   `json:"secret,omitempty"`
+	HasSecret bool     `json:"has_secret"`
+	Events    []string `json:"events"` // empty means every webhook event
+	Enabled   bool     `json:"enabled"`
+}
+
+// Validate rejects webhooks the dispatcher can't deliver to.
+func (w Webhook) Validate() error {
+	u, err := url.Parse(w.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("url must be an http or https URL")
+	}
+	for _, e := range w.Events {
+		if !isWebhookEvent(e) {
+			return fmt.Errorf("unknown event %q (want one of %s)", e, strings.Join(webhookEvents, ", "))
+		}
+	}
+	return nil
+}
+
+// wants reports whether the webhook subscribes to the named event.
+func (w Webhook) wants(event string) bool {
+	if !w.Enabled {
+		return false
+	}
+	if len(w.Events) == 0 {
+		return true
+	}
+	for _, e := range w.Events {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
+
+func isWebhookEvent(name string) bool {
+	for _, e := range webhookEvents {
+		if e == name {
+			return true
+		}
+	}
+	return false
+}
+
+func webhookFromRecord(rec db.Webhook) Webhook {
+	events := []string{}
+	for _, e := range strings.Split(rec.Events, ",") {
+		if e = strings.TrimSpace(e); e != "" {
+			events = append(events, e)
+		}
+	}
+	return Webhook{ID: rec.RecordID, URL: rec.URL, Secret: rec.Secret, Events: events, Enabled: rec.Enabled}
+}
+
+func (w Webhook) toRecord() db.Webhook {
+	return db.Webhook{
+		RecordID: w.ID,
+		URL:      w.URL,
+		Secret:   w.Secret,
+		Events:   strings.Join(w.Events, ","),
+		Enabled:  w.Enabled,
+	}
+}
+
+// webhookBody is what a webhook receives.
+type webhookBody struct {
+	Event string `json:"event"`
+	Data  Event  `json:"data"`
+}
+
+// signWebhook returns the signature header value for body: the hex HMAC-SHA256
+// of the exact bytes sent, keyed by the webhook's secret.
+func signWebhook(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// webhookBackoff is the wait before the next attempt after attempts failures.
+func webhookBackoff(attempts int) time.Duration {
+	return webhookRetryBase << (attempts - 1)
+}
+
+// WebhookDispatcher sends focus and agent-lock events to the configured
+// webhooks. Every delivery is queued in PocketBase before the first attempt,
+// so failures are retried with backoff across restarts. Sends happen on a
+// worker goroutine, never on the event bus.
+type WebhookDispatcher struct {
+	store  webhookStore
+	client *http.Client
+	sends  chan db.WebhookDelivery // claimed deliveries waiting for the worker
+
+	mu       sync.Mutex
+	webhooks []Webhook
+	inflight map[string]bool // claimed delivery IDs, so a retry poll doesn't double-send
+}
+
+func NewWebhookDispatcher(store webhookStore) *WebhookDispatcher {
+	return &WebhookDispatcher{
+		store:    store,
+		client:   &http.Client{Timeout: 10 * time.Second},
+		sends:    make(chan db.WebhookDelivery, webhookSendBacklog),
+		inflight: make(map[string]bool),
+	}
+}
+
+// Load reads the webhooks from the store.
+func (d *WebhookDispatcher) Load() error {
+	records, err := d.store.GetWebhooks()
+	if err != nil {
+		return err
+	}
+	webhooks := make([]Webhook, 0, len(records))
+	for _, rec := range records {
+		webhooks = append(webhooks, webhookFromRecord(rec))
+	}
+	d.mu.Lock()
+	d.webhooks = webhooks
+	d.mu.Unlock()
+	log.Info("Loaded webhooks", "count", len(webhooks))
+	return nil
+}
+
+// Start subscribes to the bus and starts the send worker and the retry poll
+// in the background.
+func (d *WebhookDispatcher) Start(bus *EventBus) {
+	bus.Subscribe(d.handle)
+	go func() {
+		for delivery := range d.sends {
+			d.attempt(delivery)
+		}
+	}()
+	go func() {
+		for range time.Tick(webhookPollInterval) {
+			d.retryDue()
+		}
+	}()
+}
+
+// Webhooks returns the subscriptions in creation order, secrets withheld.
+func (d *WebhookDispatcher) Webhooks() []Webhook {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	out := make([]Webhook, 0, len(d.webhooks))
+	for _, w := range d.webhooks {
+		out = append(out, w.redacted())
+	}
+	return out
+}
+
+func (w Webhook) redacted() Webhook {
+	w.HasSecret = w.Secret != ""
+	w.Secret = ""
+	return w
+}
+
+// Save creates a webhook (empty ID) or replaces an existing one. Updating with
+// an empty secret keeps the stored one.
+func (d *WebhookDispatcher) Save(w Webhook) (Webhook, error) {
+	if err := w.Validate(); err != nil {
+		return Webhook{}, err
+	}
+
+	if w.ID == "" {
+		id, err := d.store.InsertWebhook(w.toRecord())
+		if err != nil {
+			return Webhook{}, err
+		}
+		w.ID = id
+		d.mu.Lock()
+		d.webhooks = append(d.webhooks, w)
+		d.mu.Unlock()
+	} else {
+		d.mu.Lock()
+		i := d.indexLocked(w.ID)
+		if i >= 0 && w.Secret == "" {
+			w.Secret = d.webhooks[i].Secret
+		}
+		d.mu.Unlock()
+		if i < 0 {
+			return Webhook{}, fmt.Errorf("no webhook with id %q", w.ID)
+		}
+		if err := d.store.UpdateWebhook(w.toRecord()); err != nil {
+			return Webhook{}, err
+		}
+		d.mu.Lock()
+		if i := d.indexLocked(w.ID); i >= 0 {
+			d.webhooks[i] = w
+		}
+		d.mu.Unlock()
+	}
+
+	log.Info("Webhook saved", "id", w.ID, "url", w.URL, "events", w.Events)
+	return w.redacted(), nil
+}
+
+// Delete removes a webhook. Returns false if no webhook has that ID.
+func (d *WebhookDispatcher) Delete(id string) (bool, error) {
+	d.mu.Lock()
+	found := d.indexLocked(id) >= 0
+	d.mu.Unlock()
+	if !found {
+		return false, nil
+	}
+	if err := d.store.DeleteWebhook(id); err != nil {
+		return true, err
+	}
+	d.mu.Lock()
+	if i := d.indexLocked(id); i >= 0 {
+		d.webhooks = append(d.webhooks[:i], d.webhooks[i+1:]...)
+	}
+	d.mu.Unlock()
+	log.Info("Webhook deleted", "id", id)
+	return true, nil
+}
+
+// indexLocked returns the position of the webhook with id, or -1. Must be called with d.mu held.
+func (d *WebhookDispatcher) indexLocked(id string) int {
+	for i, w := range d.webhooks {
+		if w.ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+// handle queues one delivery per subscribed webhook and hands each to the send
+// worker. The delivery is claimed before its row is written, so a retry poll
+// that reads the row first leaves it alone.
+func (d *WebhookDispatcher) handle(e Event) {
+	if !isWebhookEvent(e.Name()) {
+		return
+	}
+	body, err := json.Marshal(webhookBody{Event: e.Name(), Data: e})
+	if err != nil {
+		log.Error("Failed to encode webhook payload", "event", e.Name(), "error", err)
+		return
+	}
+
+	d.mu.Lock()
+	var targets []Webhook
+	for _, w := range d.webhooks {
+		if w.wants(e.Name()) {
+			targets = append(targets, w)
+		}
+	}
+	d.mu.Unlock()
+
+	for _, w := range targets {
+		delivery := db.WebhookDelivery{
+			RecordID:      db.NewRecordID(),
+			Webhook:       w.ID,
+			Event:         e.Name(),
+			Payload:       string(body),
+			Status:        "pending",
+			NextAttemptAt: time.Now().UTC().Format(time.RFC3339),
+		}
+		d.claim(delivery.RecordID)
+		id, err := d.store.InsertWebhookDelivery(delivery)
+		if err != nil {
+			d.release(delivery.RecordID)
+			log.Error("Failed to queue webhook delivery", "webhook", w.ID, "event", e.Name(), "error", err)
+			continue
+		}
+		delivery.RecordID = id
+		d.enqueue(delivery)
+	}
+}
+
+// retryDue attempts every pending delivery whose backoff has run out.
+func (d *WebhookDispatcher) retryDue() {
+	due, err := d.store.GetDueWebhookDeliveries(time.Now())
+	if err != nil {
+		log.Error("Failed to read webhook queue", "error", err)
+		return
+	}
+	for _, delivery := range due {
+		if d.claim(delivery.RecordID) {
+			d.enqueue(delivery)
+		}
+	}
+}
+
+// claim marks a delivery as being attempted. Returns false if it already was.
+func (d *WebhookDispatcher) claim(id string) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.inflight[id] {
+		return false
+	}
+	d.inflight[id] = true
+	return true
+}
+
+// release drops the claim on a delivery once its attempt is over.
+func (d *WebhookDispatcher) release(id string) {
+	d.mu.Lock()
+	delete(d.inflight, id)
+	d.mu.Unlock()
+}
+
+// enqueue hands a claimed delivery to the send worker. When the worker is that
+// far behind, the delivery stays pending in the store and the retry poll picks
+// it up again.
+func (d *WebhookDispatcher) enqueue(delivery db.WebhookDelivery) {
+	select {
+	case d.sends <- delivery:
+	default:
+		d.release(delivery.RecordID)
+		log.Warn("Webhook send backlog full, leaving delivery for the retry poll", "id", delivery.RecordID)
+	}
+}
+
+// attempt sends one claimed delivery, records the outcome and releases the
+// claim. The outcome is delivered, pending with the next attempt pushed out,
+// or failed after the last attempt. A delivery whose webhook was deleted
+// fails at once.
+func (d *WebhookDispatcher) attempt(delivery db.WebhookDelivery) {
+	defer d.release(delivery.RecordID)
+	d.mu.Lock()
+	i := d.indexLocked(delivery.Webhook)
+	var w Webhook
+	if i >= 0 {
+		w = d.webhooks[i]
+	}
+	d.mu.Unlock()
+
+	delivery.Attempts++
+	var status int
+	var err error
+	if i < 0 {
+		err = fmt.Errorf("webhook was deleted")
+		delivery.Attempts = webhookMaxAttempts
+	} else {
+		status, err = d.post(w, delivery)
+	}
+	delivery.ResponseStatus = status
+
+	switch {
+	case err == nil:
+		delivery.Status = "delivered"
+		delivery.LastError = ""
+	case delivery.Attempts >= webhookMaxAttempts:
+		delivery.Status = "failed"
+		delivery.LastError = err.Error()
+		log.Error("Webhook delivery failed for good", "webhook", delivery.Webhook, "event", delivery.Event, "error", err)
+	default:
+		delivery.Status = "pending"
+		delivery.LastError = err.Error()
+		delivery.NextAttemptAt = time.Now().Add(webhookBackoff(delivery.Attempts)).UTC().Format(time.RFC3339)
+		log.Warn("Webhook delivery failed, will retry", "webhook", delivery.Webhook, "event", delivery.Event,
+			"attempts", delivery.Attempts, "error", err)
+	}
+
+	if err := d.store.UpdateWebhookDelivery(delivery); err != nil {
+		log.Error("Failed to record webhook delivery", "id", delivery.RecordID, "error", err)
+	}
+}
+
+// post sends the stored payload. Any non-2xx answer is a failure.
+func (d *WebhookDispatcher) post(w Webhook, delivery db.WebhookDelivery) (int, error) {
+	body := []byte(delivery.Payload)
+	req, err := http.NewRequest("POST", w.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Coach-Event", delivery.Event)
+	req.Header.Set("X-Coach-Delivery", delivery.RecordID)
+	if w.Secret != "" {
+		req.Header.Set("X-Coach-Signature", signWebhook(w.Secret, body))
+	}
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("endpoint answered %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
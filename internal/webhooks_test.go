@@ -0,0 +1,217 @@
+package coach
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"coach/internal/db"
+)
+
+// fakeWebhookStore keeps webhooks and the delivery queue in memory instead of PocketBase.
+type fakeWebhookStore struct {
+	mu         sync.Mutex
+	webhooks   []db.Webhook
+	deliveries map[string]db.WebhookDelivery
+	nextID     int
+}
+
+func newFakeWebhookStore() *fakeWebhookStore {
+	return &fakeWebhookStore{deliveries: make(map[string]db.WebhookDelivery)}
+}
+
+func (f *fakeWebhookStore) GetWebhooks() ([]db.Webhook, error) { return f.webhooks, nil }
+
+func (f *fakeWebhookStore) InsertWebhook(w db.Webhook) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.nextID++
+	return fmt.Sprintf("wh%d", f.nextID), nil
+}
+
+func (f *fakeWebhookStore) UpdateWebhook(w db.Webhook) error { return nil }
+
+func (f *fakeWebhookStore) DeleteWebhook(recordID string) error { return nil }
+
+func (f *fakeWebhookStore) InsertWebhookDelivery(d db.WebhookDelivery) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if d.RecordID == "" {
+		f.nextID++
+		d.RecordID = fmt.Sprintf("del%d", f.nextID)
+	}
+	f.deliveries[d.RecordID] = d
+	return d.RecordID, nil
+}
+
+func (f *fakeWebhookStore) UpdateWebhookDelivery(d db.WebhookDelivery) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.deliveries[d.RecordID] = d
+	return nil
+}
+
+func (f *fakeWebhookStore) GetDueWebhookDeliveries(now time.Time) ([]db.WebhookDelivery, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var due []db.WebhookDelivery
+	for _, d := range f.deliveries {
+		if d.Status == "pending" {
+			due = append(due, d)
+		}
+	}
+	return due, nil
+}
+
+func (f *fakeWebhookStore) only(t *testing.T) db.WebhookDelivery {
+	t.Helper()
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if len(f.deliveries) != 1 {
+		t.Fatalf("Expected exactly one delivery, got %d", len(f.deliveries))
+	}
+	for _, d := range f.deliveries {
+		return d
+	}
+	return db.WebhookDelivery{}
+}
+
+// runSends attempts whatever is waiting for the send worker, in its place.
+func runSends(d *WebhookDispatcher) {
+	for {
+		select {
+		case delivery := <-d.sends:
+			d.attempt(delivery)
+		default:
+			return
+		}
+	}
+}
+
+func TestWebhookDeliversSignedEvent(t *testing.T) {
+	var gotBody []byte
+	var gotSig, gotEvent string
+	endpoint := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotBody, _ = io.ReadAll(r.Body)
+		gotSig = r.Header.Get("X-Coach-Signature")
+		gotEvent = r.Header.Get("X-Coach-Event")
+	}))
+	defer endpoint.Close()
+
+	store := newFakeWebhookStore()
+	dispatcher := NewWebhookDispatcher(store)
+	if _, err := dispatcher.Save(Webhook{URL: endpoint.URL, Secret: "s3cret", Events: []string{"lock_engaged"}, Enabled: true}); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+
+	dispatcher.handle(LockExpired{At: time.Now()}) // not subscribed
+	dispatcher.handle(LockEngaged{At: time.Now()})
+	runSends(dispatcher)
+
+	d := store.only(t)
+	if d.Status != "delivered" || d.Attempts != 1 || d.ResponseStatus != http.StatusOK {
+		t.Errorf("Expected one successful attempt, got %+v", d)
+	}
+	if gotEvent != "lock_engaged" || gotSig != signWebhook("s3cret", gotBody) {
+		t.Errorf("Bad headers: event=%q signature=%q", gotEvent, gotSig)
+	}
+}
+
+func TestWebhookRetriesThenGivesUp(t *testing.T) {
+	endpoint := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer endpoint.Close()
+
+	store := newFakeWebhookStore()
+	dispatcher := NewWebhookDispatcher(store)
+	if _, err := dispatcher.Save(Webhook{URL: endpoint.URL, Enabled: true}); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+
+	dispatcher.handle(FocusStarted{At: time.Now()})
+	runSends(dispatcher)
+	d := store.only(t)
+	if d.Status != "pending" || d.Attempts != 1 || d.ResponseStatus != http.StatusBadGateway {
+		t.Fatalf("Expected a pending retry after one failure, got %+v", d)
+	}
+	if next, err := time.Parse(time.RFC3339, d.NextAttemptAt); err != nil || time.Until(next) < webhookRetryBase-time.Second {
+		t.Errorf("Expected the next attempt about %v out, got %q", webhookRetryBase, d.NextAttemptAt)
+	}
+
+	for i := 1; i < webhookMaxAttempts; i++ {
+		dispatcher.retryDue()
+		runSends(dispatcher)
+	}
+	if d := store.only(t); d.Status != "failed" || d.Attempts != webhookMaxAttempts {
+		t.Errorf("Expected failed after %d attempts, got %+v", webhookMaxAttempts, d)
+	}
+}
+
+func TestWebhookRetryPollSkipsDeliveriesWaitingToSend(t *testing.T) {
+	var mu sync.Mutex
+	hits := 0
+	endpoint := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		hits++
+		mu.Unlock()
+	}))
+	defer endpoint.Close()
+
+	store := newFakeWebhookStore()
+	dispatcher := NewWebhookDispatcher(store)
+	if _, err := dispatcher.Save(Webhook{URL: endpoint.URL, Enabled: true}); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+
+	// The row is pending and due, but handle already claimed it
+	dispatcher.handle(FocusStarted{At: time.Now()})
+	dispatcher.retryDue()
+	runSends(dispatcher)
+
+	mu.Lock()
+	defer mu.Unlock()
+	if hits != 1 {
+		t.Errorf("Expected the delivery to be sent once, got %d", hits)
+	}
+	if d := store.only(t); d.Status != "delivered" || d.Attempts != 1 {
+		t.Errorf("Expected one successful attempt, got %+v", d)
+	}
+}
+
+func TestWebhookSaveKeepsSecretAndRedacts(t *testing.T) {
+	dispatcher := NewWebhookDispatcher(newFakeWebhookStore())
+	saved, err := dispatcher.Save(Webhook{URL: "https://example.com/hook", Secret: "keep-me", Enabled: true})
+	if err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	if saved.Secret != "" || !saved.HasSecret {
+		t.Errorf("Saved webhook should be redacted, got %+v", saved)
+	}
+
+	if _, err := dispatcher.Save(Webhook{ID: saved.ID, URL: "https://example.com/other", Enabled: true}); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	dispatcher.mu.Lock()
+	secret := dispatcher.webhooks[0].Secret
+	dispatcher.mu.Unlock()
+	if secret != "keep-me" {
+		t.Errorf("An update without a secret should keep the stored one, got %q", secret)
+	}
+}
+
+func TestWebhookValidate(t *testing.T) {
+	for name, w := range map[string]Webhook{
+		"no scheme":     {URL: "example.com/hook"},
+		"ftp":           {URL: "ftp://example.com"},
+		"unknown event": {URL: "https://example.com", Events: []string{"focus_exploded"}},
+	} {
+		if err := w.Validate(); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}